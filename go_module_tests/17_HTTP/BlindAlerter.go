@@ -21,7 +21,44 @@ func (a BlindAlerterFunc) ScheduledAlertAt(duration time.Duration, amount int, t
 
 //GenericAlerter writes a blind alert to the give io.Writer
 func GenericAlerter(duration time.Duration, amount int, to io.Writer) {
-	time.AfterFunc(duration, func() {
+	SystemClock.AfterFunc(duration, func() {
 		fmt.Fprintf(to, "Blind is now %d\n", amount)
 	})
 }
+
+//ClockAlerter is a BlindAlerter that can also announce levels, breaks and chip counts
+type ClockAlerter interface {
+	BlindAlerter
+	ScheduledAnnouncementAt(duration time.Duration, announcement Announcement, to io.Writer)
+}
+
+//TimedAlerter is a BlindAlerter that can time its alerts with the Clock of the game it belongs to
+type TimedAlerter interface {
+	BlindAlerter
+	WithClock(clock Clock) BlindAlerter
+}
+
+//GenericClockAlerter writes blind alerts and clock announcements to the given io.Writer.
+//Alerts are timed with the Clock of the game or the SystemClock when it has none
+type GenericClockAlerter struct {
+	clock Clock
+}
+
+//WithClock returns a GenericClockAlerter that times its alerts with clock
+func (g GenericClockAlerter) WithClock(clock Clock) BlindAlerter {
+	return GenericClockAlerter{clock}
+}
+
+//ScheduledAlertAt writes a blind alert after the given duration
+func (g GenericClockAlerter) ScheduledAlertAt(duration time.Duration, amount int, to io.Writer) {
+	clockOrSystem(g.clock).AfterFunc(duration, func() {
+		fmt.Fprintf(to, "Blind is now %d\n", amount)
+	})
+}
+
+//ScheduledAnnouncementAt writes an announcement after the given duration
+func (g GenericClockAlerter) ScheduledAnnouncementAt(duration time.Duration, announcement Announcement, to io.Writer) {
+	clockOrSystem(g.clock).AfterFunc(duration, func() {
+		fmt.Fprint(to, announcement.String())
+	})
+}
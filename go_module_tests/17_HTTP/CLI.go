@@ -21,6 +21,9 @@ func (d CLIError) Error() string {
 
 const InvalidInputError CLIError = CLIError("You entered a number but a string was expected")
 
+//NoWinnerError is returned when the input ends before a winner is declared
+const NoWinnerError CLIError = CLIError("The game ended without a winner")
+
 //CLI is a command line interface
 type CLI struct {
 	game   AbstractGame
@@ -37,7 +40,8 @@ func NewCLI(game AbstractGame, in io.Reader, out io.Writer) *CLI {
 	}
 }
 
-//PlayPoker reads user input and records a win if a user wins
+//PlayPoker reads user input and records a win if a user wins. Tournament games also
+//accept "{Name} is out" and "{Name} rebuys" before the winner is declared
 func (c *CLI) PlayPoker() error {
 	fmt.Fprint(c.output, PlayerPrompt)
 
@@ -50,10 +54,13 @@ func (c *CLI) PlayPoker() error {
 
 	c.game.Start(numberOfPlayers, c.output)
 
-	userInput := c.readLine()
-	c.game.Win(extractWinner(userInput))
+	for c.input.Scan() {
+		if HandleGameMessage(c.game, c.input.Text(), c.output) {
+			return nil
+		}
+	}
 
-	return nil
+	return NoWinnerError
 }
 
 func (c *CLI) readLine() string {
@@ -62,5 +69,5 @@ func (c *CLI) readLine() string {
 }
 
 func extractWinner(userInput string) string {
-	return strings.Replace(userInput, " wins", "", 1)
+	return strings.TrimSuffix(userInput, winSuffix)
 }
@@ -32,7 +32,7 @@ func TestCLI(t *testing.T) {
 			poker.AssertNoError(t, err)
 
 			assertMessagesSentToUser(t, stdout, poker.PlayerPrompt)
-			poker.AssertStartGameNumberOfPlayers(t, game.StartCalledWith, test.StartPlayersInt)
+			poker.AssertStartGameNumberOfPlayers(t, game.StartCalledWith(), test.StartPlayersInt)
 			poker.AssertGameWinCalled(t, game, test.Name)
 		})
 	}
@@ -54,10 +54,32 @@ func TestCLI(t *testing.T) {
 	})
 }
 
+func TestCLITournament(t *testing.T) {
+	t.Run("Players that are out are recorded before the winner", func(t *testing.T) {
+		in := strings.NewReader("3\nKiro is out\nJoro is out\nChris wins\n")
+		store := &SpyGameRecorder{}
+		game := poker.NewTournament(store, &SpyBlindAlerter{}, poker.TournamentOptions{BuyIn: 10})
+		cli := poker.NewCLI(game, in, &bytes.Buffer{})
+
+		poker.AssertNoError(t, cli.PlayPoker())
+		poker.AssertStringSlice(t, store.results[0].Positions, []string{"Chris", "Joro", "Kiro"})
+	})
+
+	t.Run("PlayPoker returns an error when the input ends without a winner", func(t *testing.T) {
+		in := strings.NewReader("3\nKiro is out\n")
+		game := poker.NewTournament(&SpyGameRecorder{}, &SpyBlindAlerter{}, poker.TournamentOptions{})
+		cli := poker.NewCLI(game, in, &bytes.Buffer{})
+
+		if err := cli.PlayPoker(); err != poker.NoWinnerError {
+			t.Errorf("Expected error %v but got %v", poker.NoWinnerError, err)
+		}
+	})
+}
+
 func assertGameNotStarted(t *testing.T, game *poker.SpyGame) {
 	t.Helper()
 
-	if game.StartCalledWith != 0 {
+	if game.StartCalledWith() != 0 {
 		t.Fatalf("Game started when an error should have been thrown")
 	}
 }
@@ -2,6 +2,7 @@ package poker
 
 import (
 	"io"
+	"sync"
 	"time"
 )
 
@@ -10,30 +11,157 @@ type AbstractGame interface {
 	Start(numberOfPlayers int, to io.Writer)
 }
 
+//StoppableGame is a game whose scheduled alerts can be cancelled when it ends without a winner
+type StoppableGame interface {
+	Stop()
+}
+
 type Game struct {
-	store   PlayerStore
-	alerter BlindAlerter
+	store           PlayerStore
+	alerter         BlindAlerter
+	clock           ClockOptions
+	numberOfPlayers int
+	stacks          *ChipStacks
+	timers          []Timer
+	stopped         bool
+	mx              sync.Mutex
 }
 
 //NewGame is a constructor for Game
 func NewGame(store PlayerStore, alerter BlindAlerter) AbstractGame {
-	return &Game{store, alerter}
+	return &Game{store: store, alerter: alerter}
 }
 
-//Win takes in user input and records a winner
+//NewGameWithClock is a constructor for a Game that tracks chips and takes breaks between levels
+func NewGameWithClock(store PlayerStore, alerter BlindAlerter, clock ClockOptions) AbstractGame {
+	return &Game{store: store, alerter: alerter, clock: clock}
+}
+
+//Win takes in user input and records a winner. Stores that keep game results get the whole result
 func (g *Game) Win(winner string) {
-	g.store.RecordWin(winner)
+	g.Stop()
+
+	recorder, ok := g.store.(GameRecorder)
+
+	if !ok {
+		g.store.RecordWin(winner)
+		return
+	}
+
+	recorder.RecordGame(GameResult{
+		PlayedAt:  clockOrSystem(g.clock.Time).Now(),
+		FieldSize: g.numberOfPlayers,
+		Positions: []string{winner},
+	})
 }
 
 //Start is the beggining of the game and it alerts the increase of the blind value after a set
-//amount of time. Tha follows the formula 5 + numberOfPlayers = time.Minutes until increment
+//amount of time. Tha follows the formula 5 + numberOfPlayers = time.Minutes until increment.
+//Alerters that implement ClockAlerter also get level numbers, chip counts and breaks
 func (g *Game) Start(numberOfPlayers int, to io.Writer) {
+	g.Stop()
+	g.mx.Lock()
+	g.stopped = false
+	g.mx.Unlock()
+
+	g.numberOfPlayers = numberOfPlayers
+	g.stacks = NewChipStacks(numberOfPlayers, g.clock.StartingStack)
 	blindIncrement := time.Duration(5+numberOfPlayers) * time.Minute
 
+	alerter := g.timedAlerter()
+	clockAlerter, announce := alerter.(ClockAlerter)
+
 	blinds := []int{100, 200, 300, 400, 500, 600, 800, 1000, 2000, 4000, 8000}
 	blindTime := 0 * time.Minute
-	for _, blind := range blinds {
-		g.alerter.ScheduledAlertAt(blindTime, blind, to)
+	breaks := 0
+	for i, blind := range blinds {
+		if !announce {
+			alerter.ScheduledAlertAt(blindTime, blind, to)
+			blindTime = blindTime + blindIncrement
+			continue
+		}
+
+		level := i + 1
+		clockAlerter.ScheduledAnnouncementAt(blindTime, Announcement{
+			Kind:      LevelAnnouncement,
+			Level:     level,
+			Blind:     blind,
+			Remaining: blindIncrement,
+			Stacks:    g.stacks,
+		}, to)
 		blindTime = blindTime + blindIncrement
+
+		if g.clock.BreakEvery < 1 || level%g.clock.BreakEvery != 0 || level == len(blinds) {
+			continue
+		}
+
+		breakAnnouncement := Announcement{
+			Kind:      BreakAnnouncement,
+			Level:     level,
+			Remaining: g.clock.BreakLength,
+			Stacks:    g.stacks,
+		}
+
+		if breaks < len(g.clock.ColorUps) {
+			breakAnnouncement.ColorUp = g.clock.ColorUps[breaks]
+		}
+
+		clockAlerter.ScheduledAnnouncementAt(blindTime, breakAnnouncement, to)
+		blindTime = blindTime + g.clock.BreakLength
+		breaks++
 	}
 }
+
+//Stop cancels the alerts that are still scheduled. Only alerters that implement TimedAlerter can
+//be stopped
+func (g *Game) Stop() {
+	g.mx.Lock()
+	defer g.mx.Unlock()
+
+	g.stopped = true
+
+	for _, timer := range g.timers {
+		timer.Stop()
+	}
+
+	g.timers = nil
+}
+
+//timedAlerter times the alerts with the Clock of the game when the alerter supports it. The timers
+//are kept so Stop can cancel them
+func (g *Game) timedAlerter() BlindAlerter {
+	timed, ok := g.alerter.(TimedAlerter)
+
+	if !ok {
+		return g.alerter
+	}
+
+	return timed.WithClock(gameClock{clockOrSystem(g.clock.Time), g})
+}
+
+//gameClock is the Clock of a game that keeps every timer scheduled on it
+type gameClock struct {
+	Clock
+	game *Game
+}
+
+func (c gameClock) AfterFunc(duration time.Duration, f func()) Timer {
+	timer := c.Clock.AfterFunc(duration, f)
+
+	c.game.mx.Lock()
+	defer c.game.mx.Unlock()
+
+	if c.game.stopped {
+		timer.Stop()
+		return timer
+	}
+
+	c.game.timers = append(c.game.timers, timer)
+
+	return timer
+}
+
+//Stacks returns the chips in play of the running game
+func (g *Game) Stacks() *ChipStacks {
+	return g.stacks
+}
@@ -48,6 +48,35 @@ func TestRecordWin(t *testing.T) {
 	}
 }
 
+type SpyGameRecorder struct {
+	poker.StubPlayerStore
+	results []poker.GameResult
+}
+
+func (s *SpyGameRecorder) RecordGame(result poker.GameResult) {
+	s.results = append(s.results, result)
+}
+
+func TestRecordGame(t *testing.T) {
+	t.Run("Stores that keep game results get the winner and field size", func(t *testing.T) {
+		store := &SpyGameRecorder{}
+		game := poker.NewGame(store, &SpyBlindAlerter{})
+
+		game.Start(5, ioutil.Discard)
+		game.Win("Chris")
+
+		if len(store.results) != 1 {
+			t.Fatalf("Expected 1 recorded game but got %d", len(store.results))
+		}
+
+		got := store.results[0]
+
+		if got.Winner() != "Chris" || got.FieldSize != 5 {
+			t.Errorf("Expected Chris to win a game of 5 but got %+v", got)
+		}
+	})
+}
+
 func TestStart(t *testing.T) {
 	cases := []struct {
 		numberOfPlayers int
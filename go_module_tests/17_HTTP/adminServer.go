@@ -0,0 +1,137 @@
+package poker
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+)
+
+const (
+	adminPlayersPath string = "/admin/players/"
+	adminGamesPath   string = "/admin/games"
+)
+
+//GameLister lists the games that are being played
+type GameLister interface {
+	RunningGames() []RunningGame
+}
+
+//AdminServer is the httpHandler for the operator requests to /admin/players/ and /admin/games.
+//It must only be served on the admin port
+type AdminServer struct {
+	store AdminStore
+	games GameLister
+	http.Handler
+}
+
+//renameRequest is the body of POST /admin/players/rename
+type renameRequest struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+//mergeRequest is the body of POST /admin/players/merge
+type mergeRequest struct {
+	From string `json:"from"`
+	Into string `json:"into"`
+}
+
+//revokeRequest is the body of POST /admin/players/revoke
+type revokeRequest struct {
+	Name string `json:"name"`
+	Wins int    `json:"wins"`
+}
+
+//NewAdminServer is a constructor for AdminServer that creates a router for it.
+//POST players/rename renames a player, POST players/merge merges one player into another,
+//POST players/revoke takes wins away and GET games dumps the running games
+func NewAdminServer(store AdminStore, games GameLister) *AdminServer {
+	a := &AdminServer{store: store, games: games}
+
+	router := http.NewServeMux()
+	router.Handle(adminPlayersPath+"rename", http.HandlerFunc(a.renameHandler))
+	router.Handle(adminPlayersPath+"merge", http.HandlerFunc(a.mergeHandler))
+	router.Handle(adminPlayersPath+"revoke", http.HandlerFunc(a.revokeHandler))
+	router.Handle(adminGamesPath, http.HandlerFunc(a.gamesHandler))
+
+	a.Handler = router
+
+	return a
+}
+
+func (a *AdminServer) renameHandler(resp http.ResponseWriter, req *http.Request) {
+	var body renameRequest
+
+	if !decodeAdminRequest(resp, req, &body) {
+		return
+	}
+
+	writeAdminResult(resp, a.store.RenamePlayer(body.From, body.To))
+}
+
+func (a *AdminServer) mergeHandler(resp http.ResponseWriter, req *http.Request) {
+	var body mergeRequest
+
+	if !decodeAdminRequest(resp, req, &body) {
+		return
+	}
+
+	writeAdminResult(resp, a.store.MergePlayers(body.From, body.Into))
+}
+
+func (a *AdminServer) revokeHandler(resp http.ResponseWriter, req *http.Request) {
+	var body revokeRequest
+
+	if !decodeAdminRequest(resp, req, &body) {
+		return
+	}
+
+	player, err := a.store.RevokeWins(body.Name, body.Wins)
+
+	if err != nil {
+		writeAdminResult(resp, err)
+		return
+	}
+
+	resp.Header().Set("content-type", jsonContentType)
+	json.NewEncoder(resp).Encode(player)
+}
+
+func (a *AdminServer) gamesHandler(resp http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		resp.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	resp.Header().Set("content-type", jsonContentType)
+	json.NewEncoder(resp).Encode(a.games.RunningGames())
+}
+
+//decodeAdminRequest reads the JSON body of a POST request. It writes the error response and
+//returns false when the request can not be handled
+func decodeAdminRequest(resp http.ResponseWriter, req *http.Request, body interface{}) bool {
+	if req.Method != http.MethodPost {
+		resp.WriteHeader(http.StatusMethodNotAllowed)
+		return false
+	}
+
+	if err := json.NewDecoder(req.Body).Decode(body); err != nil {
+		http.Error(resp, "Invalid request body "+err.Error(), http.StatusBadRequest)
+		return false
+	}
+
+	return true
+}
+
+func writeAdminResult(resp http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, ErrPlayerNotFound):
+		http.Error(resp, err.Error(), http.StatusNotFound)
+	case errors.Is(err, ErrPlayerExists):
+		http.Error(resp, err.Error(), http.StatusConflict)
+	case err != nil:
+		http.Error(resp, err.Error(), http.StatusBadRequest)
+	default:
+		resp.WriteHeader(http.StatusNoContent)
+	}
+}
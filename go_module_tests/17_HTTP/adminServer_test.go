@@ -0,0 +1,96 @@
+package poker
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+const adminTestLeague string = `{"version": 3, "players": [{"Name": "Cleo", "Wins": 3}, {"Name": "cleo", "Wins": 1},
+	{"Name": "Chris", "Wins": 2}], "games": [{"FieldSize": 2, "Positions": ["cleo", "Chris"]}]}`
+
+func TestAdminServer(t *testing.T) {
+	cases := []struct {
+		name   string
+		path   string
+		body   string
+		code   int
+		league League
+	}{
+		{"rename", "rename", `{"from": "Chris", "to": "Christopher"}`, http.StatusNoContent,
+			League{{"Cleo", 3}, {"Christopher", 2}, {"cleo", 1}}},
+		{"rename to a taken name", "rename", `{"from": "Chris", "to": "Cleo"}`, http.StatusConflict,
+			League{{"Cleo", 3}, {"Chris", 2}, {"cleo", 1}}},
+		{"rename a missing player", "rename", `{"from": "Missing", "to": "Found"}`, http.StatusNotFound,
+			League{{"Cleo", 3}, {"Chris", 2}, {"cleo", 1}}},
+		{"merge", "merge", `{"from": "cleo", "into": "Cleo"}`, http.StatusNoContent,
+			League{{"Cleo", 4}, {"Chris", 2}}},
+		{"merge into itself", "merge", `{"from": "Cleo", "into": "Cleo"}`, http.StatusBadRequest,
+			League{{"Cleo", 3}, {"Chris", 2}, {"cleo", 1}}},
+		{"revoke", "revoke", `{"name": "Cleo", "wins": 5}`, http.StatusOK,
+			League{{"Chris", 2}, {"cleo", 1}, {"Cleo", 0}}},
+		{"revoke no wins", "revoke", `{"name": "Cleo", "wins": 0}`, http.StatusBadRequest,
+			League{{"Cleo", 3}, {"Chris", 2}, {"cleo", 1}}},
+		{"invalid body", "revoke", `{`, http.StatusBadRequest,
+			League{{"Cleo", 3}, {"Chris", 2}, {"cleo", 1}}},
+	}
+
+	for _, test := range cases {
+		t.Run(test.name, func(t *testing.T) {
+			database, cleanDb := CreateTempFile(t, adminTestLeague, fileName)
+			defer cleanDb()
+
+			store, err := NewFileSystemPlayerStore(database)
+			AssertNoError(t, err)
+
+			server := NewAdminServer(store, NewGameRegistry())
+			response := httptest.NewRecorder()
+			request := httptest.NewRequest(http.MethodPost, adminPlayersPath+test.path, strings.NewReader(test.body))
+
+			server.ServeHTTP(response, request)
+
+			AssertStatusCode(t, response.Code, test.code)
+			AssertLeague(t, store.GetLeague(), test.league)
+		})
+	}
+
+	t.Run("Renamed and merged players keep their games", func(t *testing.T) {
+		database, cleanDb := CreateTempFile(t, adminTestLeague, fileName)
+		defer cleanDb()
+
+		store, err := NewFileSystemPlayerStore(database)
+		AssertNoError(t, err)
+
+		AssertNoError(t, store.MergePlayers("cleo", "Cleo"))
+		AssertNoError(t, store.RenamePlayer("Cleo", "Cleopatra"))
+
+		if winner := store.GetGames()[0].Winner(); winner != "Cleopatra" {
+			t.Errorf("Expected the game to be won by Cleopatra but got %s", winner)
+		}
+	})
+
+	t.Run("Running games are dumped", func(t *testing.T) {
+		games := NewGameRegistry()
+		_, finished := games.New()
+		finished.Finish("Cleo wins")
+		running, events := games.New()
+		events.Write([]byte("Blind is 100"))
+
+		server := NewAdminServer(&FileSystemPlayerStore{}, games)
+		response := httptest.NewRecorder()
+
+		server.ServeHTTP(response, httptest.NewRequest(http.MethodGet, adminGamesPath, nil))
+
+		AssertStatusCode(t, response.Code, http.StatusOK)
+		AssertJSONContentType(t, response)
+
+		var got []RunningGame
+		AssertNoError(t, json.NewDecoder(response.Body).Decode(&got))
+
+		if len(got) != 1 || got[0].ID != running || len(got[0].Events) != 1 {
+			t.Errorf("Expected only game %s with its event but got %+v", running, got)
+		}
+	})
+}
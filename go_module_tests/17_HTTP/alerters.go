@@ -0,0 +1,250 @@
+package poker
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"learning/17_HTTP/logging"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+//ANSI escape codes used by the TerminalSink
+const (
+	terminalBell  string = "\a"
+	terminalReset string = "\033[0m"
+)
+
+//TerminalColors maps the color names accepted by TerminalSink to their ANSI escape codes
+var TerminalColors = map[string]string{
+	"red":     "\033[1;41;97m",
+	"green":   "\033[1;42;97m",
+	"yellow":  "\033[1;43;30m",
+	"blue":    "\033[1;44;97m",
+	"magenta": "\033[1;45;97m",
+}
+
+//AlertSink delivers an announcement as soon as it is made. to is the writer the game was started with
+type AlertSink interface {
+	Send(announcement Announcement, to io.Writer) error
+}
+
+//ScheduledAlerter turns an AlertSink into a ClockAlerter that delivers announcements after a delay
+type ScheduledAlerter struct {
+	sink  AlertSink
+	clock Clock
+}
+
+//NewScheduledAlerter is a constructor for ScheduledAlerter. Alerts are timed with the SystemClock
+//until the game gives it its Clock
+func NewScheduledAlerter(sink AlertSink) *ScheduledAlerter {
+	return &ScheduledAlerter{sink, SystemClock}
+}
+
+//WithClock returns a ScheduledAlerter that delivers to the same sink and times its alerts with clock
+func (s *ScheduledAlerter) WithClock(clock Clock) BlindAlerter {
+	return &ScheduledAlerter{s.sink, clockOrSystem(clock)}
+}
+
+//ScheduledAlertAt delivers a blind alert after the given duration
+func (s *ScheduledAlerter) ScheduledAlertAt(duration time.Duration, amount int, to io.Writer) {
+	s.ScheduledAnnouncementAt(duration, Announcement{Kind: LevelAnnouncement, Blind: amount}, to)
+}
+
+//ScheduledAnnouncementAt delivers an announcement after the given duration. Delivery errors are logged
+func (s *ScheduledAlerter) ScheduledAnnouncementAt(duration time.Duration, announcement Announcement, to io.Writer) {
+	s.clock.AfterFunc(duration, func() {
+		if err := s.sink.Send(announcement, to); err != nil {
+			logging.Default().Error("Failed to deliver announcement", "announcement", announcement.String(), "error", err)
+		}
+	})
+}
+
+//WriterSink writes announcements to the writer the game was started with
+type WriterSink struct{}
+
+//Send writes the announcement to the given writer
+func (WriterSink) Send(announcement Announcement, to io.Writer) error {
+	_, err := fmt.Fprint(to, announcement.String())
+	return err
+}
+
+//TerminalSink rings the terminal bell and prints announcements as a colored banner
+type TerminalSink struct {
+	out   io.Writer
+	color string
+}
+
+//NewTerminalSink is a constructor for TerminalSink. Announcements are written to out or to the
+//writer of the game when out is nil. Unknown colors fall back to yellow
+func NewTerminalSink(out io.Writer, color string) *TerminalSink {
+	code, ok := TerminalColors[color]
+
+	if !ok {
+		code = TerminalColors["yellow"]
+	}
+
+	return &TerminalSink{out, code}
+}
+
+//Send writes the announcement framed in a colored banner preceded by a bell
+func (t *TerminalSink) Send(announcement Announcement, to io.Writer) error {
+	if t.out != nil {
+		to = t.out
+	}
+
+	text := " " + strings.TrimSuffix(announcement.String(), "\n") + " "
+	border := strings.Repeat("*", len(text))
+
+	_, err := fmt.Fprintf(to, "%s%s%s%s\n%s%s%s\n%s%s%s\n",
+		terminalBell,
+		t.color, border, terminalReset,
+		t.color, text, terminalReset,
+		t.color, border, terminalReset)
+
+	return err
+}
+
+//WebhookPayload is the JSON body posted by WebhookSink
+type WebhookPayload struct {
+	Kind             AnnouncementKind `json:"kind"`
+	Level            int              `json:"level"`
+	Blind            int              `json:"blind,omitempty"`
+	RemainingSeconds int              `json:"remainingSeconds"`
+	ColorUp          int              `json:"colorUp,omitempty"`
+	ChipsInPlay      int              `json:"chipsInPlay,omitempty"`
+	AverageStack     int              `json:"averageStack,omitempty"`
+	Message          string           `json:"message"`
+}
+
+//NewWebhookPayload converts an announcement into the JSON body of a webhook
+func NewWebhookPayload(announcement Announcement) WebhookPayload {
+	payload := WebhookPayload{
+		Kind:             announcement.Kind,
+		Level:            announcement.Level,
+		Blind:            announcement.Blind,
+		RemainingSeconds: int(announcement.Remaining / time.Second),
+		ColorUp:          announcement.ColorUp,
+		Message:          strings.TrimSuffix(announcement.String(), "\n"),
+	}
+
+	if announcement.Stacks != nil {
+		payload.ChipsInPlay = announcement.Stacks.InPlay()
+		payload.AverageStack = announcement.Stacks.AverageStack()
+	}
+
+	return payload
+}
+
+//WebhookSink posts announcements as JSON to a URL and retries failed deliveries
+type WebhookSink struct {
+	url     string
+	client  *http.Client
+	retries int
+	backoff time.Duration
+	clock   Clock
+}
+
+//NewWebhookSink is a constructor for WebhookSink. A delivery is attempted retries + 1 times
+//waiting backoff, 2 * backoff and so on between attempts on the given clock
+func NewWebhookSink(url string, client *http.Client, retries int, backoff time.Duration, clock Clock) *WebhookSink {
+	if client == nil {
+		client = &http.Client{Timeout: 5 * time.Second}
+	}
+
+	return &WebhookSink{url, client, retries, backoff, clockOrSystem(clock)}
+}
+
+//Send posts the announcement and returns the last error if every attempt failed
+func (w *WebhookSink) Send(announcement Announcement, to io.Writer) error {
+	body, err := json.Marshal(NewWebhookPayload(announcement))
+
+	if err != nil {
+		return fmt.Errorf("Could not encode webhook payload %v", err)
+	}
+
+	for attempt := 0; ; attempt++ {
+		err = w.post(body)
+
+		if err == nil || attempt >= w.retries {
+			break
+		}
+
+		w.clock.Sleep(time.Duration(attempt+1) * w.backoff)
+	}
+
+	if err != nil {
+		return fmt.Errorf("Webhook %s failed after %d attempts %v", w.host(), w.retries+1, err)
+	}
+
+	return nil
+}
+
+func (w *WebhookSink) post(body []byte) error {
+	resp, err := w.client.Post(w.url, jsonContentType, bytes.NewReader(body))
+
+	if urlErr, ok := err.(*url.Error); ok {
+		//The path and query of webhook URLs often hold tokens so only the cause is returned
+		return urlErr.Err
+	}
+
+	if err != nil {
+		return err
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status %s", resp.Status)
+	}
+
+	return nil
+}
+
+//host returns the scheme and host of the webhook to name it in errors without leaking its tokens
+func (w *WebhookSink) host() string {
+	parsed, err := url.Parse(w.url)
+
+	if err != nil {
+		return "with an invalid URL"
+	}
+
+	return parsed.Scheme + "://" + parsed.Host
+}
+
+//FanOutSink sends every announcement to all of its sinks
+type FanOutSink []AlertSink
+
+//Send delivers the announcement to every sink at the same time so a slow sink does not delay
+//the others. Every sink is tried even if some of them fail
+func (f FanOutSink) Send(announcement Announcement, to io.Writer) error {
+	var failures []string
+	var mx sync.Mutex
+	var wg sync.WaitGroup
+
+	for _, sink := range f {
+		wg.Add(1)
+
+		go func(sink AlertSink) {
+			defer wg.Done()
+
+			if err := sink.Send(announcement, to); err != nil {
+				mx.Lock()
+				failures = append(failures, err.Error())
+				mx.Unlock()
+			}
+		}(sink)
+	}
+
+	wg.Wait()
+
+	if len(failures) > 0 {
+		return fmt.Errorf("%d of %d alert sinks failed: %s", len(failures), len(f), strings.Join(failures, "; "))
+	}
+
+	return nil
+}
@@ -0,0 +1,207 @@
+package poker_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/ioutil"
+	poker "learning/17_HTTP"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+type SpyAlertSink struct {
+	sent []poker.Announcement
+	err  error
+	mx   sync.Mutex
+}
+
+func (s *SpyAlertSink) Send(announcement poker.Announcement, to io.Writer) error {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	s.sent = append(s.sent, announcement)
+	return s.err
+}
+
+func (s *SpyAlertSink) count() int {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	return len(s.sent)
+}
+
+var levelThree = poker.Announcement{
+	Kind:      poker.LevelAnnouncement,
+	Level:     3,
+	Blind:     300,
+	Remaining: 12 * time.Minute,
+	Stacks:    poker.NewChipStacks(4, 1000),
+}
+
+func TestWebhookSink(t *testing.T) {
+	t.Run("Posts the announcement as JSON and retries failures", func(t *testing.T) {
+		var attempts int
+		var got poker.WebhookPayload
+
+		server := httptest.NewServer(http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
+			attempts++
+
+			if attempts < 3 {
+				resp.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+
+			if req.Header.Get("content-type") != "application/json" {
+				t.Errorf("Expected a JSON body but got %q", req.Header.Get("content-type"))
+			}
+
+			json.NewDecoder(req.Body).Decode(&got)
+		}))
+		defer server.Close()
+
+		sink := poker.NewWebhookSink(server.URL, server.Client(), 2, time.Millisecond, nil)
+
+		poker.AssertNoError(t, sink.Send(levelThree, ioutil.Discard))
+
+		want := poker.WebhookPayload{
+			Kind:             poker.LevelAnnouncement,
+			Level:            3,
+			Blind:            300,
+			RemainingSeconds: 720,
+			ChipsInPlay:      4000,
+			AverageStack:     1000,
+			Message:          "Level 3 - Blind is now 300 - 12m0s remaining - 4000 chips in play, average stack 1000",
+		}
+
+		if got != want {
+			t.Errorf("got payload %+v want %+v", got, want)
+		}
+	})
+
+	t.Run("Gives up after the last retry", func(t *testing.T) {
+		var attempts int
+
+		server := httptest.NewServer(http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
+			attempts++
+			resp.WriteHeader(http.StatusInternalServerError)
+		}))
+		defer server.Close()
+
+		sink := poker.NewWebhookSink(server.URL, server.Client(), 1, time.Millisecond, nil)
+
+		poker.AssertError(t, sink.Send(levelThree, ioutil.Discard))
+
+		if attempts != 2 {
+			t.Errorf("Expected 2 attempts but got %d", attempts)
+		}
+	})
+
+	t.Run("Errors do not show the token in the URL", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {}))
+		server.Close()
+
+		sink := poker.NewWebhookSink(server.URL+"/hooks/s3cr3t?token=s3cr3t", nil, 0, 0, nil)
+		err := sink.Send(levelThree, ioutil.Discard)
+
+		poker.AssertError(t, err)
+
+		if strings.Contains(err.Error(), "s3cr3t") {
+			t.Errorf("Error %q shows the token", err)
+		}
+	})
+}
+
+func TestTerminalSink(t *testing.T) {
+	out := &bytes.Buffer{}
+	sink := poker.NewTerminalSink(out, "red")
+
+	poker.AssertNoError(t, sink.Send(poker.Announcement{Blind: 100}, ioutil.Discard))
+
+	got := out.String()
+
+	if !strings.HasPrefix(got, "\a"+poker.TerminalColors["red"]) {
+		t.Errorf("Expected a bell followed by a red banner but got %q", got)
+	}
+
+	if !strings.Contains(got, " Blind is now 100 ") {
+		t.Errorf("Expected the announcement in the banner but got %q", got)
+	}
+}
+
+func TestFanOutSink(t *testing.T) {
+	working, failing := &SpyAlertSink{}, &SpyAlertSink{err: errors.New("offline")}
+	fanOut := poker.FanOutSink{failing, working}
+
+	err := fanOut.Send(levelThree, ioutil.Discard)
+
+	poker.AssertError(t, err)
+
+	if len(working.sent) != 1 || len(failing.sent) != 1 {
+		t.Errorf("Expected every sink to get the announcement but got %d and %d", len(working.sent), len(failing.sent))
+	}
+}
+
+func TestScheduledAlerter(t *testing.T) {
+	out := &bytes.Buffer{}
+	clock := poker.NewFakeClock(time.Now())
+	alerter := poker.NewScheduledAlerter(poker.WriterSink{}).WithClock(clock)
+
+	alerter.ScheduledAlertAt(time.Minute, 100, out)
+
+	clock.Advance(59 * time.Second)
+	poker.AssertResponseBody(t, out.String(), "")
+
+	clock.Advance(time.Second)
+	poker.AssertResponseBody(t, out.String(), "Blind is now 100\n")
+}
+
+func TestAlertsStopWhenTheGameFinishes(t *testing.T) {
+	cases := []struct {
+		name   string
+		finish func(tournament *poker.Tournament, registry *poker.GameRegistry, events *poker.GameEvents)
+	}{
+		{"won", func(tournament *poker.Tournament, registry *poker.GameRegistry, events *poker.GameEvents) {
+			tournament.Win("Cleo")
+		}},
+		{"abandoned", func(tournament *poker.Tournament, registry *poker.GameRegistry, events *poker.GameEvents) {
+			events.Finish("Game abandoned")
+		}},
+		{"drained", func(tournament *poker.Tournament, registry *poker.GameRegistry, events *poker.GameEvents) {
+			registry.Interrupt("Server shutting down")
+		}},
+	}
+
+	for _, test := range cases {
+		t.Run(test.name, func(t *testing.T) {
+			clock := poker.NewFakeClock(time.Now())
+			sink := &SpyAlertSink{}
+			tournament := poker.NewTournament(&poker.StubPlayerStore{}, poker.NewScheduledAlerter(sink),
+				poker.TournamentOptions{Clock: poker.ClockOptions{Time: clock}})
+
+			registry := poker.NewGameRegistry()
+			_, events := registry.New()
+			events.MarkStarted(tournament)
+			tournament.Start(5, events)
+
+			clock.Advance(time.Minute)
+			sent := sink.count()
+
+			test.finish(tournament, registry, events)
+			clock.Advance(24 * time.Hour)
+
+			if sink.count() != sent {
+				t.Errorf("got %d announcements after the game finished want none", sink.count()-sent)
+			}
+
+			if clock.Pending() != 0 {
+				t.Errorf("got %d alerts still scheduled want none", clock.Pending())
+			}
+		})
+	}
+}
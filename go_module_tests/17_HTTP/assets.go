@@ -0,0 +1,165 @@
+package poker
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"embed"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"html/template"
+	"io/fs"
+	"net/http"
+	"os"
+	"path"
+	"sync"
+	"time"
+)
+
+//partialsTemplate defines the parts shared by every page
+const partialsTemplate string = "partials.html"
+
+//Cache-Control headers of static files. Files from an override directory change while
+//the server runs so browsers always revalidate them
+const (
+	embeddedCacheControl string = "public, max-age=300"
+	overrideCacheControl string = "no-cache"
+)
+
+//go:embed html
+var embeddedAssets embed.FS
+
+//Assets holds the templates and static files of the web pages. They are embedded in the binary
+//and files in an optional override directory take their place. Files from the override
+//directory are read again on every request so edits show up without a restart
+type Assets struct {
+	files     fs.FS
+	live      bool
+	templates map[string]*template.Template
+	etags     map[string]string
+	mx        sync.Mutex
+}
+
+//NewAssets creates Assets that prefer the files in overrideDir over the embedded ones.
+//An empty overrideDir only uses the embedded files. The override directory has the same
+//layout as the html directory
+func NewAssets(overrideDir string) (*Assets, error) {
+	embedded, err := fs.Sub(embeddedAssets, "html")
+
+	if err != nil {
+		return nil, fmt.Errorf("Error loading embedded assets %v", err)
+	}
+
+	assets := &Assets{
+		files:     embedded,
+		templates: map[string]*template.Template{},
+		etags:     map[string]string{},
+	}
+
+	if overrideDir == "" {
+		return assets, nil
+	}
+
+	info, err := os.Stat(overrideDir)
+
+	if err != nil {
+		return nil, fmt.Errorf("Error opening assets override directory %v", err)
+	}
+
+	if !info.IsDir() {
+		return nil, fmt.Errorf("Assets override %s is not a directory", overrideDir)
+	}
+
+	assets.files = overlayFS{os.DirFS(overrideDir), embedded}
+	assets.live = true
+
+	return assets, nil
+}
+
+//Template returns the parsed template with the given name together with the shared partials
+func (a *Assets) Template(name string) (*template.Template, error) {
+	if a.live {
+		return a.parse(name)
+	}
+
+	a.mx.Lock()
+	defer a.mx.Unlock()
+
+	if tmpl, ok := a.templates[name]; ok {
+		return tmpl, nil
+	}
+
+	tmpl, err := a.parse(name)
+
+	if err != nil {
+		return nil, err
+	}
+
+	a.templates[name] = tmpl
+
+	return tmpl, nil
+}
+
+func (a *Assets) parse(name string) (*template.Template, error) {
+	return template.New(name).Funcs(templateFuncs).ParseFS(a.files, name, partialsTemplate)
+}
+
+//ServeStatic serves the file of the static directory at the path of the request. Responses carry
+//an ETag so browsers get a 304 Not Modified for files they already have
+func (a *Assets) ServeStatic(resp http.ResponseWriter, req *http.Request) {
+	name := path.Join("static", path.Clean("/"+req.URL.Path))
+	data, err := fs.ReadFile(a.files, name)
+
+	if err != nil {
+		http.NotFound(resp, req)
+		return
+	}
+
+	resp.Header().Set("ETag", a.etag(name, data))
+
+	if a.live {
+		resp.Header().Set("Cache-Control", overrideCacheControl)
+	} else {
+		resp.Header().Set("Cache-Control", embeddedCacheControl)
+	}
+
+	http.ServeContent(resp, req, name, time.Time{}, bytes.NewReader(data))
+}
+
+func (a *Assets) etag(name string, data []byte) string {
+	if a.live {
+		return hashETag(data)
+	}
+
+	a.mx.Lock()
+	defer a.mx.Unlock()
+
+	if etag, ok := a.etags[name]; ok {
+		return etag
+	}
+
+	a.etags[name] = hashETag(data)
+
+	return a.etags[name]
+}
+
+func hashETag(data []byte) string {
+	sum := sha256.Sum256(data)
+	return `"` + hex.EncodeToString(sum[:16]) + `"`
+}
+
+//overlayFS opens files from top and falls back to bottom for files top does not have
+type overlayFS struct {
+	top    fs.FS
+	bottom fs.FS
+}
+
+func (o overlayFS) Open(name string) (fs.File, error) {
+	file, err := o.top.Open(name)
+
+	if errors.Is(err, fs.ErrNotExist) {
+		return o.bottom.Open(name)
+	}
+
+	return file, err
+}
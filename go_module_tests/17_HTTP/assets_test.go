@@ -0,0 +1,114 @@
+package poker_test
+
+import (
+	"io/ioutil"
+	poker "learning/17_HTTP"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestEmbeddedAssets(t *testing.T) {
+	server := poker.CreateNewPlayerServer(t, &poker.StubPlayerStore{}, &poker.SpyGame{})
+
+	t.Run("Static files are served with caching headers", func(t *testing.T) {
+		response := serveStatic(server, "/static/game.js", "")
+
+		poker.AssertStatusCode(t, response.Code, http.StatusOK)
+		assertHeader(t, response, "Cache-Control", "public, max-age=300")
+
+		if !strings.Contains(response.Header().Get("Content-Type"), "javascript") {
+			t.Errorf("Expected a javascript content type but got %s", response.Header().Get("Content-Type"))
+		}
+
+		if response.Header().Get("ETag") == "" {
+			t.Errorf("Expected an ETag")
+		}
+	})
+
+	t.Run("Files the browser already has are not sent again", func(t *testing.T) {
+		etag := serveStatic(server, "/static/style.css", "").Header().Get("ETag")
+		response := serveStatic(server, "/static/style.css", etag)
+
+		poker.AssertStatusCode(t, response.Code, http.StatusNotModified)
+	})
+
+	t.Run("Missing files and directories are not found", func(t *testing.T) {
+		for _, path := range []string{"/static/missing.js", "/static/"} {
+			poker.AssertStatusCode(t, serveStatic(server, path, "").Code, http.StatusNotFound)
+		}
+	})
+}
+
+func TestAssetsOverrideDir(t *testing.T) {
+	dir := t.TempDir()
+	writeAsset(t, dir, "game.html", "<p>Override</p>")
+	writeAsset(t, dir, "static/game.js", "let version = 1")
+
+	server := poker.CreateNewPlayerServer(t, &poker.StubPlayerStore{}, &poker.SpyGame{})
+	poker.AssertNoError(t, server.SetAssetsDir(dir))
+
+	t.Run("Files in the override directory replace the embedded ones", func(t *testing.T) {
+		response := httptest.NewRecorder()
+		server.ServeHTTP(response, httptest.NewRequest(http.MethodGet, "/game/", nil))
+
+		poker.AssertResponseBody(t, response.Body.String(), "<p>Override</p>")
+	})
+
+	t.Run("Edits show up without a restart", func(t *testing.T) {
+		before := serveStatic(server, "/static/game.js", "")
+		writeAsset(t, dir, "static/game.js", "let version = 2")
+		after := serveStatic(server, "/static/game.js", before.Header().Get("ETag"))
+
+		poker.AssertStatusCode(t, after.Code, http.StatusOK)
+		poker.AssertResponseBody(t, after.Body.String(), "let version = 2")
+		assertHeader(t, after, "Cache-Control", "no-cache")
+	})
+
+	t.Run("Files missing from the override directory fall back to the embedded ones", func(t *testing.T) {
+		poker.AssertStatusCode(t, serveStatic(server, "/static/style.css", "").Code, http.StatusOK)
+	})
+
+	t.Run("A missing override directory is rejected", func(t *testing.T) {
+		poker.AssertError(t, server.SetAssetsDir(filepath.Join(dir, "missing")))
+	})
+
+	t.Run("Broken templates fail the template check", func(t *testing.T) {
+		poker.AssertNoError(t, server.CheckTemplates())
+		writeAsset(t, dir, "league.html", "{{ template ")
+
+		poker.AssertError(t, server.CheckTemplates())
+	})
+}
+
+func serveStatic(server http.Handler, path, ifNoneMatch string) *httptest.ResponseRecorder {
+	request := httptest.NewRequest(http.MethodGet, path, nil)
+
+	if ifNoneMatch != "" {
+		request.Header.Set("If-None-Match", ifNoneMatch)
+	}
+
+	response := httptest.NewRecorder()
+	server.ServeHTTP(response, request)
+
+	return response
+}
+
+func writeAsset(t *testing.T, dir, name, content string) {
+	t.Helper()
+
+	path := filepath.Join(dir, name)
+	poker.AssertNoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+	poker.AssertNoError(t, ioutil.WriteFile(path, []byte(content), 0644))
+}
+
+func assertHeader(t *testing.T, response *httptest.ResponseRecorder, name, want string) {
+	t.Helper()
+
+	if got := response.Header().Get(name); got != want {
+		t.Errorf("Expected header %s to be %q but got %q", name, want, got)
+	}
+}
@@ -0,0 +1,164 @@
+package poker
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+//Clock is the source of time used by games and alerters so tests can control it
+type Clock interface {
+	Now() time.Time
+	AfterFunc(duration time.Duration, f func()) Timer
+	Sleep(duration time.Duration)
+}
+
+//Timer is a function scheduled on a Clock that can be cancelled
+type Timer interface {
+	Stop() bool
+}
+
+//SystemClock is the Clock that follows real time
+var SystemClock Clock = systemClock{}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+func (systemClock) AfterFunc(duration time.Duration, f func()) Timer {
+	return time.AfterFunc(duration, f)
+}
+
+func (systemClock) Sleep(duration time.Duration) {
+	time.Sleep(duration)
+}
+
+func clockOrSystem(clock Clock) Clock {
+	if clock == nil {
+		return SystemClock
+	}
+
+	return clock
+}
+
+//FakeClock is a Clock that only moves when it is advanced. Scheduled functions run synchronously
+//inside of Advance in the order they are due, which lets hours of play be simulated instantly
+type FakeClock struct {
+	now    time.Time
+	timers []*fakeTimer
+	seq    int
+	mx     sync.Mutex
+}
+
+type fakeTimer struct {
+	clock *FakeClock
+	at    time.Time
+	seq   int
+	f     func()
+}
+
+//NewFakeClock creates a FakeClock stopped at the given time
+func NewFakeClock(now time.Time) *FakeClock {
+	return &FakeClock{now: now}
+}
+
+//Now returns the time the clock has been advanced to
+func (c *FakeClock) Now() time.Time {
+	c.mx.Lock()
+	defer c.mx.Unlock()
+
+	return c.now
+}
+
+//AfterFunc schedules f to run when the clock is advanced past the given duration
+func (c *FakeClock) AfterFunc(duration time.Duration, f func()) Timer {
+	c.mx.Lock()
+	defer c.mx.Unlock()
+
+	c.seq++
+	timer := &fakeTimer{clock: c, at: c.now.Add(duration), seq: c.seq, f: f}
+	c.timers = append(c.timers, timer)
+
+	return timer
+}
+
+//Sleep blocks until another goroutine advances the clock by the given duration
+func (c *FakeClock) Sleep(duration time.Duration) {
+	done := make(chan struct{})
+	c.AfterFunc(duration, func() { close(done) })
+	<-done
+}
+
+//Advance moves the clock forward running every function that becomes due on the way
+func (c *FakeClock) Advance(duration time.Duration) {
+	c.mx.Lock()
+	target := c.now.Add(duration)
+	c.mx.Unlock()
+
+	for {
+		c.mx.Lock()
+		timer := c.nextDue(target)
+
+		if timer == nil {
+			c.now = target
+			c.mx.Unlock()
+			return
+		}
+
+		c.now = timer.at
+		c.mx.Unlock()
+
+		timer.f()
+	}
+}
+
+//Pending returns the number of scheduled functions that have not run yet
+func (c *FakeClock) Pending() int {
+	c.mx.Lock()
+	defer c.mx.Unlock()
+
+	return len(c.timers)
+}
+
+//BlockUntil waits until at least n functions are scheduled. It is used to wait for
+//goroutines that schedule work on the clock before advancing it
+func (c *FakeClock) BlockUntil(n int) {
+	for c.Pending() < n {
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func (c *FakeClock) nextDue(target time.Time) *fakeTimer {
+	sort.SliceStable(c.timers, func(i, j int) bool {
+		if c.timers[i].at.Equal(c.timers[j].at) {
+			return c.timers[i].seq < c.timers[j].seq
+		}
+
+		return c.timers[i].at.Before(c.timers[j].at)
+	})
+
+	if len(c.timers) == 0 || c.timers[0].at.After(target) {
+		return nil
+	}
+
+	timer := c.timers[0]
+	c.timers = c.timers[1:]
+
+	return timer
+}
+
+func (t *fakeTimer) Stop() bool {
+	t.clock.mx.Lock()
+	defer t.clock.mx.Unlock()
+
+	for i, timer := range t.clock.timers {
+		if timer == t {
+			t.clock.timers = append(t.clock.timers[:i], t.clock.timers[i+1:]...)
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,106 @@
+package poker_test
+
+import (
+	"bytes"
+	poker "learning/17_HTTP"
+	"strings"
+	"testing"
+	"time"
+)
+
+var startOfPlay = time.Date(2020, time.September, 1, 20, 0, 0, 0, time.UTC)
+
+func TestFakeClock(t *testing.T) {
+	t.Run("Functions run in the order they are due when the clock is advanced", func(t *testing.T) {
+		clock := poker.NewFakeClock(startOfPlay)
+		var got []string
+
+		clock.AfterFunc(2*time.Minute, func() { got = append(got, "second") })
+		clock.AfterFunc(time.Minute, func() {
+			got = append(got, "first")
+			clock.AfterFunc(30*time.Second, func() { got = append(got, "scheduled while running") })
+		})
+		stopped := clock.AfterFunc(90*time.Second, func() { got = append(got, "stopped") })
+		stopped.Stop()
+
+		clock.Advance(time.Minute)
+		poker.AssertStringSlice(t, got, []string{"first"})
+
+		clock.Advance(time.Hour)
+		poker.AssertStringSlice(t, got, []string{"first", "scheduled while running", "second"})
+
+		if !clock.Now().Equal(startOfPlay.Add(61 * time.Minute)) {
+			t.Errorf("Expected the clock to be at %v but got %v", startOfPlay.Add(61*time.Minute), clock.Now())
+		}
+	})
+
+	t.Run("Sleep returns once the clock is advanced", func(t *testing.T) {
+		clock := poker.NewFakeClock(startOfPlay)
+		done := make(chan struct{})
+
+		go func() {
+			clock.Sleep(time.Second)
+			close(done)
+		}()
+
+		clock.BlockUntil(1)
+		clock.Advance(time.Second)
+
+		select {
+		case <-done:
+		case <-time.After(time.Second):
+			t.Errorf("Expected Sleep to return after the clock was advanced")
+		}
+	})
+}
+
+func TestSimulatedTournament(t *testing.T) {
+	clock := poker.NewFakeClock(startOfPlay)
+	store := &SpyGameRecorder{}
+	out := &bytes.Buffer{}
+
+	tournament := poker.NewTournament(store, poker.GenericClockAlerter{}, poker.TournamentOptions{
+		BuyIn: 10,
+		Clock: poker.ClockOptions{
+			StartingStack: 1000,
+			BreakEvery:    4,
+			BreakLength:   10 * time.Minute,
+			Time:          clock,
+		},
+	})
+
+	tournament.Start(5, out)
+
+	clock.Advance(95 * time.Minute)
+	poker.AssertNoError(t, tournament.Eliminate("Kiro"))
+
+	clock.Advance(3 * time.Hour)
+	tournament.Win("Chris")
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+
+	want := []string{
+		"Level 1 - Blind is now 100 - 10m0s remaining - 5000 chips in play, average stack 1000",
+		"Level 2 - Blind is now 200 - 10m0s remaining - 5000 chips in play, average stack 1000",
+		"Level 3 - Blind is now 300 - 10m0s remaining - 5000 chips in play, average stack 1000",
+		"Level 4 - Blind is now 400 - 10m0s remaining - 5000 chips in play, average stack 1000",
+		"Break after level 4 - 10m0s remaining - 5000 chips in play, average stack 1000",
+		"Level 5 - Blind is now 500 - 10m0s remaining - 5000 chips in play, average stack 1000",
+		"Level 6 - Blind is now 600 - 10m0s remaining - 5000 chips in play, average stack 1000",
+		"Level 7 - Blind is now 800 - 10m0s remaining - 5000 chips in play, average stack 1000",
+		"Level 8 - Blind is now 1000 - 10m0s remaining - 5000 chips in play, average stack 1000",
+		"Break after level 8 - 10m0s remaining - 5000 chips in play, average stack 1000",
+		"Kiro finishes in position 5",
+		"Level 9 - Blind is now 2000 - 10m0s remaining - 5000 chips in play, average stack 1250",
+		"Level 10 - Blind is now 4000 - 10m0s remaining - 5000 chips in play, average stack 1250",
+		"Level 11 - Blind is now 8000 - 10m0s remaining - 5000 chips in play, average stack 1250",
+		"1. Chris wins 35",
+	}
+
+	poker.AssertStringSlice(t, lines, want)
+
+	playedAt := store.results[0].PlayedAt
+	if !playedAt.Equal(startOfPlay.Add(95*time.Minute + 3*time.Hour)) {
+		t.Errorf("Expected the game to be recorded at the simulated time but got %v", playedAt)
+	}
+}
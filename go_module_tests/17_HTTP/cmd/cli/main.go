@@ -10,18 +10,28 @@ import (
 var dbFileName string = "cli.db.json"
 
 func main() {
-	store, dbClose, err := poker.GenerateFileSystemPlayerStore(dbFileName)
+	if err := run(); err != nil {
+		log.Print(err)
+		os.Exit(1)
+	}
+}
 
-	defer dbClose()
+//run plays a game on the command line. It returns instead of exiting so the store is closed
+func run() error {
+	store, dbClose, err := poker.GenerateFileSystemPlayerStore(dbFileName)
 
 	if err != nil {
-		log.Fatalf("Could not generate FileSystem player store from file, %v", err)
+		return fmt.Errorf("Could not generate FileSystem player store from file, %v", err)
 	}
 
-	game := poker.NewGame(store, poker.BlindAlerterFunc(poker.GenericAlerter))
+	defer dbClose()
+
+	game := poker.NewTournament(store, poker.GenericClockAlerter{}, poker.TournamentOptions{})
 	gameCLI := poker.NewCLI(game, os.Stdin, os.Stdout)
 
 	fmt.Print("It's poker time\n")
+	fmt.Println("Type {Name} is out when a player is knocked out, {Name} rebuys to buy back in")
 	fmt.Println("Type {Name} wins to record a win")
-	gameCLI.PlayPoker()
+
+	return gameCLI.PlayPoker()
 }
@@ -1,15 +1,139 @@
 package main
 
 import (
+	"errors"
+	"fmt"
+	configuration "learning/17_HTTP/config"
 	server "learning/17_HTTP/server"
+	"log"
+	"os"
+
+	"github.com/spf13/pflag"
 )
 
+//defaultConfigFile is read when no --config flag is given. Without an extension it is looked up
+//with every supported one
+const defaultConfigFile string = "./config/viperConfig"
+
+//Exit codes of the server, following sysexits.h
 const (
-	configFileName string = "viperConfig"
-	configFilePath string = "./config"
+	exitFailure int = 1
+	exitUsage   int = 64
+	exitNoInput int = 66
+	exitUnavail int = 69
+	exitIOError int = 74
+	exitConfig  int = 78
 )
 
+const usage = `Usage:
+  webserver [flags]                        run the server
+  webserver validate-config [file] [flags] check the configuration without starting the server
+  webserver config dump [flags]            print every key of the configuration and where it comes from
+  webserver snapshot [flags]               snapshot the database into database.snapshotDir
+  webserver snapshots [flags]              list the snapshots of the database
+  webserver restore {name} [flags]         replace the database with a snapshot, use the admin API
+                                           when the server is running
+
+Values are taken from the flags, the VPR_ environment variables, the configuration file and the
+defaults in that order. VPR_SERVER_PORT sets server.port. The files in the conf.d directory next to
+the configuration file override it in lexical order. Files can be JSON, TOML or YAML. Values like
+file:///run/secrets/x or env:NAME are replaced by the secret they reference and are never shown.
+
+Flags:
+`
+
 func main() {
-	app := server.CreateDefaultApplication(configFileName, configFilePath)
-	app.Start()
+	command, args := splitCommand(os.Args[1:])
+
+	flags := configuration.NewFlagSet("webserver")
+	configFile := flags.String("config", defaultConfigFile, "configuration file")
+	flags.Usage = func() {
+		fmt.Fprint(os.Stderr, usage)
+		flags.PrintDefaults()
+	}
+
+	if err := flags.Parse(args); err != nil {
+		if errors.Is(err, pflag.ErrHelp) {
+			os.Exit(0)
+		}
+
+		os.Exit(exitUsage)
+	}
+
+	if command == "validate-config" && flags.NArg() > 0 {
+		*configFile = flags.Arg(0)
+	}
+
+	configFileName, configFilePath := server.SplitConfigPath(*configFile)
+
+	switch command {
+	case "validate-config":
+		os.Exit(exitCode(server.ValidateConfig(configFileName, configFilePath, flags, os.Stdout)))
+	case "config dump":
+		os.Exit(exitCode(server.DumpConfig(configFileName, configFilePath, flags, os.Stdout)))
+	case "snapshot", "snapshots", "restore":
+		err := server.SnapshotCommand(configFileName, configFilePath, flags, append([]string{command}, flags.Args()...),
+			os.Stdout)
+
+		if err != nil {
+			log.Printf("Snapshot command failed %v", err)
+		}
+
+		os.Exit(exitCode(err))
+	case "":
+		run(configFileName, configFilePath, flags)
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown command %q\n", command)
+		flags.Usage()
+		os.Exit(exitUsage)
+	}
+}
+
+//splitCommand separates the command like "config dump" from its flags
+func splitCommand(args []string) (string, []string) {
+	switch {
+	case len(args) == 0 || len(args[0]) > 0 && args[0][0] == '-':
+		return "", args
+	case args[0] == "config" && len(args) > 1:
+		return "config " + args[1], args[2:]
+	default:
+		return args[0], args[1:]
+	}
+}
+
+func run(configFileName, configFilePath string, flags *pflag.FlagSet) {
+	app, err := server.CreateApplicationWithFlags(configFileName, configFilePath, flags)
+
+	if err != nil {
+		log.Printf("Could not create the server %v", err)
+		os.Exit(exitCode(err))
+	}
+
+	if err := app.Start(); err != nil {
+		log.Printf("Server stopped with an error %v", err)
+		os.Exit(exitCode(err))
+	}
+}
+
+//exitCode tells scripts and supervisors why the server stopped
+func exitCode(err error) int {
+	var configErr *server.ConfigError
+	var storeErr *server.StoreError
+	var templateErr *server.TemplateError
+	var bindErr *server.BindError
+
+	switch {
+	case err == nil:
+		return 0
+	case errors.As(err, &configErr):
+		return exitConfig
+	case errors.As(err, &storeErr):
+		return exitIOError
+	case errors.As(err, &templateErr):
+		return exitNoInput
+	case errors.As(err, &bindErr):
+		return exitUnavail
+	default:
+		return exitFailure
+	}
 }
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	server "learning/17_HTTP/server"
+	"reflect"
+	"testing"
+)
+
+func TestExitCode(t *testing.T) {
+	cause := errors.New("cause")
+
+	cases := []struct {
+		name string
+		err  error
+		want int
+	}{
+		{"no error", nil, 0},
+		{"config error", &server.ConfigError{Section: "tls", Err: cause}, exitConfig},
+		{"store error", &server.StoreError{FileName: "game.db.json", Err: cause}, exitIOError},
+		{"template error", &server.TemplateError{Dir: "assets", Err: cause}, exitNoInput},
+		{"bind error", &server.BindError{Addr: ":8000", Err: cause}, exitUnavail},
+		{"wrapped bind error", fmt.Errorf("Error when serving %w", &server.BindError{Addr: ":8000", Err: cause}),
+			exitUnavail},
+		{"other error", cause, exitFailure},
+	}
+
+	for _, test := range cases {
+		t.Run(test.name, func(t *testing.T) {
+			if got := exitCode(test.err); got != test.want {
+				t.Errorf("got exit code %d want %d", got, test.want)
+			}
+		})
+	}
+}
+
+func TestSplitCommand(t *testing.T) {
+	cases := []struct {
+		name        string
+		args        []string
+		wantCommand string
+		wantArgs    []string
+	}{
+		{"no arguments", nil, "", nil},
+		{"only flags", []string{"--port", ":9000"}, "", []string{"--port", ":9000"}},
+		{"validate-config with a file", []string{"validate-config", "prod.yaml", "--port", ":9000"},
+			"validate-config", []string{"prod.yaml", "--port", ":9000"}},
+		{"config dump", []string{"config", "dump", "--config", "prod.yaml"}, "config dump",
+			[]string{"--config", "prod.yaml"}},
+		{"config without a subcommand", []string{"config"}, "config", []string{}},
+		{"restore a snapshot", []string{"restore", "snapshot-1.json", "--config", "prod.yaml"}, "restore",
+			[]string{"snapshot-1.json", "--config", "prod.yaml"}},
+		{"unknown command", []string{"serve"}, "serve", []string{}},
+		{"empty argument", []string{""}, "", []string{}},
+	}
+
+	for _, test := range cases {
+		t.Run(test.name, func(t *testing.T) {
+			command, args := splitCommand(test.args)
+
+			if command != test.wantCommand || !reflect.DeepEqual(args, test.wantArgs) {
+				t.Errorf("got %q %v want %q %v", command, args, test.wantCommand, test.wantArgs)
+			}
+		})
+	}
+}
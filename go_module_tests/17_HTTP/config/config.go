@@ -3,11 +3,15 @@ package configuration
 import (
 	"fmt"
 	"io"
-	"log"
+	"reflect"
+	"strings"
+	"time"
 
 	repo "learning/17_HTTP/config/viper"
+	"learning/17_HTTP/logging"
 
 	"github.com/pkg/errors"
+	"github.com/spf13/pflag"
 	"github.com/spf13/viper"
 )
 
@@ -16,25 +20,112 @@ type Configuration interface {
 	SetServerPort(newPort string)
 	SetDatabaseFileName(newFileName string)
 	GetServerPort() string
+	GetAdminPort() string
+	GetRateLimit() RateLimitConfiguration
+	GetAllowedOrigins() []string
+	GetTLSConfiguration() TLSConfiguration
+	GetAssetsDir() string
+	GetShutdownTimeout() time.Duration
+	GetDrainGracePeriod() time.Duration
 	GetDatabaseFileName() string
+	GetSnapshotDir() string
+	GetSnapshotRetention() int
+	GetInterruptedGamesFile() string
+	GetTournamentConfiguration() TournamentConfiguration
+	GetAlerters() []AlerterConfiguration
+	GetLoggingConfiguration() LoggingConfiguration
+	BindFlags(set *pflag.FlagSet) error
 	Read(configFileName, configFilePath string, defaultConfig repo.DefaultConfiguration) error
+	Validate() error
+	Dump() []Setting
+	Redact(text string) string
+	IsRedacted(key string) bool
 }
 
 //ConfigurationImpl is a type that holds the data required for the application to run
 type ConfigurationImpl struct {
-	reader   repo.Reader
-	Server   ServerConfiguration
-	Database DatabaseConfiguration
+	reader     repo.Reader
+	Server     ServerConfiguration
+	Database   DatabaseConfiguration
+	Tournament TournamentConfiguration
+	Alerters   []AlerterConfiguration
+	Logging    LoggingConfiguration
 }
 
 //ServerConfiguration is holds the configuration needed by the server like port, etc
 type ServerConfiguration struct {
-	Port string
+	Port             string
+	AllowedOrigins   []string
+	TLS              TLSConfiguration
+	AssetsDir        string
+	ShutdownTimeout  time.Duration
+	DrainGracePeriod time.Duration
+	AdminPort        string
+	RateLimit        RateLimitConfiguration
 }
 
-//DatabaseConfiguration stores the name of our file which we are using as a database
+//RateLimitConfiguration holds how many requests per second every client may make on average and
+//how many at once. Requests are not limited when RequestsPerSecond is zero
+type RateLimitConfiguration struct {
+	RequestsPerSecond float64
+	Burst             int
+}
+
+//TLSConfiguration holds the certificate and key used to serve HTTPS. TLS is off unless both are set.
+//When RedirectPort is set plain HTTP requests on it are redirected to HTTPS
+type TLSConfiguration struct {
+	CertFile     string
+	KeyFile      string
+	RedirectPort string
+}
+
+//Enabled tells if the server should serve HTTPS
+func (t TLSConfiguration) Enabled() bool {
+	return t.CertFile != "" && t.KeyFile != ""
+}
+
+//DatabaseConfiguration stores the name of our file which we are using as a database.
+//Games still running when the server stops are saved to InterruptedGamesFile
 type DatabaseConfiguration struct {
-	FileName string
+	FileName             string
+	SnapshotDir          string
+	SnapshotRetention    int
+	InterruptedGamesFile string
+}
+
+//TournamentConfiguration holds the buy-in and rebuy prices, the payout table and the clock of tournaments
+type TournamentConfiguration struct {
+	BuyIn         int
+	Rebuy         int
+	Payouts       []PayoutConfiguration
+	StartingStack int
+	BreakEvery    int
+	BreakLength   time.Duration
+	ColorUps      []int
+}
+
+//PayoutConfiguration holds the percentages of the prize pool paid to each finishing position
+//when a tournament has at least MinEntries entries
+type PayoutConfiguration struct {
+	MinEntries int
+	Shares     []int
+}
+
+//AlerterConfiguration describes a sink that blind alerts are sent to. Type is one of
+//writer, terminal or webhook. URL, Retries and Backoff are used by webhooks and Color by terminals
+type AlerterConfiguration struct {
+	Type    string
+	URL     string
+	Retries int
+	Backoff time.Duration
+	Color   string
+}
+
+//LoggingConfiguration holds the lowest level of the entries that are logged and where trace spans
+//are exported. TraceOutput is "stdout" or the path of a file and tracing is off when it is empty
+type LoggingConfiguration struct {
+	Level       string
+	TraceOutput string
 }
 
 //NewConfiguration creates a configuration with an empty viper
@@ -43,14 +134,72 @@ func NewConfiguration(vpr repo.Reader) Configuration {
 		vpr,
 		ServerConfiguration{},
 		DatabaseConfiguration{},
+		TournamentConfiguration{},
+		nil,
+		LoggingConfiguration{},
 	}
 }
 
+//GetAllowedOrigins returns the origins other than the server itself that may open websockets
+func (c *ConfigurationImpl) GetAllowedOrigins() []string {
+	return c.Server.AllowedOrigins
+}
+
+//GetTLSConfiguration returns the certificate configuration of the server
+func (c *ConfigurationImpl) GetTLSConfiguration() TLSConfiguration {
+	return c.Server.TLS
+}
+
+//GetAssetsDir returns the directory whose templates and static files replace the embedded ones
+func (c *ConfigurationImpl) GetAssetsDir() string {
+	return c.Server.AssetsDir
+}
+
+//GetShutdownTimeout returns how long running games and requests are waited for when the server stops
+func (c *ConfigurationImpl) GetShutdownTimeout() time.Duration {
+	return c.Server.ShutdownTimeout
+}
+
+//GetDrainGracePeriod returns how long the server keeps serving while it is unready before it stops
+func (c *ConfigurationImpl) GetDrainGracePeriod() time.Duration {
+	return c.Server.DrainGracePeriod
+}
+
 //GetDatabaseFileName returns the database file name
 func (c *ConfigurationImpl) GetDatabaseFileName() string {
 	return c.Database.FileName
 }
 
+//GetSnapshotDir returns the directory where database snapshots are stored
+func (c *ConfigurationImpl) GetSnapshotDir() string {
+	return c.Database.SnapshotDir
+}
+
+//GetSnapshotRetention returns the number of database snapshots that are kept
+func (c *ConfigurationImpl) GetSnapshotRetention() int {
+	return c.Database.SnapshotRetention
+}
+
+//GetInterruptedGamesFile returns the file the games running when the server stops are saved to
+func (c *ConfigurationImpl) GetInterruptedGamesFile() string {
+	return c.Database.InterruptedGamesFile
+}
+
+//GetTournamentConfiguration returns the buy-in, rebuy and payout configuration of tournaments
+func (c *ConfigurationImpl) GetTournamentConfiguration() TournamentConfiguration {
+	return c.Tournament
+}
+
+//GetAlerters returns the sinks that blind alerts are sent to
+func (c *ConfigurationImpl) GetAlerters() []AlerterConfiguration {
+	return c.Alerters
+}
+
+//GetLoggingConfiguration returns the log level and the trace output of the server
+func (c *ConfigurationImpl) GetLoggingConfiguration() LoggingConfiguration {
+	return c.Logging
+}
+
 //SetDatabaseFileName returns the database file name
 func (c *ConfigurationImpl) SetDatabaseFileName(newFileName string) {
 	c.Database.FileName = newFileName
@@ -66,17 +215,37 @@ func (c *ConfigurationImpl) GetServerPort() string {
 	return c.Server.Port
 }
 
+//GetAdminPort returns the port of the admin API. The admin API is off when it is empty
+func (c *ConfigurationImpl) GetAdminPort() string {
+	return c.Server.AdminPort
+}
+
+//GetRateLimit returns the limit of the requests of every client
+func (c *ConfigurationImpl) GetRateLimit() RateLimitConfiguration {
+	return c.Server.RateLimit
+}
+
 //Read generates the server viper configuration. You can give a default configuration not loaded
-//from a file by giving an empty string for a fileName or filePath.
+//from a file by giving an empty string for a fileName or filePath. A file that can not be read
+//is an error.
 func (c *ConfigurationImpl) Read(configFileName, configFilePath string,
 	defaultConfig repo.DefaultConfiguration) error {
 
-	log.Printf("Loading default configuration")
+	logging.Default().Info("Loading default configuration")
 	c.reader.LoadDefaultConfiguration(defaultConfig)
 
+	for _, key := range keys(reflect.TypeOf(*c), "") {
+		if err := c.reader.BindEnv(key); err != nil {
+			return errors.Wrap(err, "Reader failed to bind the environment")
+		}
+	}
+
 	if configFileName != "" && configFilePath != "" {
-		log.Printf("Loading configuration from file")
-		c.reader.LoadFromFile(configFileName, configFilePath)
+		logging.Default().Info("Loading configuration from file", "file", configFileName, "path", configFilePath)
+
+		if err := c.reader.LoadFromFile(configFileName, configFilePath); err != nil {
+			return err
+		}
 	}
 
 	err := c.reader.Unmarshal(c)
@@ -87,6 +256,30 @@ func (c *ConfigurationImpl) Read(configFileName, configFilePath string,
 	return nil
 }
 
+//keys returns the keys of the fields of a configuration structure like "server.tls.certfile".
+//Lists are a single key
+func keys(t reflect.Type, prefix string) []string {
+	var result []string
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+
+		if field.PkgPath != "" {
+			continue
+		}
+
+		key := prefix + strings.ToLower(field.Name)
+
+		if field.Type.Kind() == reflect.Struct {
+			result = append(result, keys(field.Type, key+".")...)
+		} else {
+			result = append(result, key)
+		}
+	}
+
+	return result
+}
+
 //Read generates the server viper configuration. You can give a default configuration not loaded
 //from a file by giving an empty string for a fileName or filePath.
 func Read(configFileName, configFilePath string, defaultConfig repo.DefaultConfiguration) (*viper.Viper, error) {
@@ -95,7 +288,7 @@ func Read(configFileName, configFilePath string, defaultConfig repo.DefaultConfi
 	loadDefaultConfiguration(vCfg, defaultConfig)
 
 	if configFileName == "" || configFilePath == "" {
-		log.Printf("Loading default configuration")
+		logging.Default().Info("Loading default configuration")
 		return vCfg, nil
 	}
 
@@ -131,24 +324,30 @@ func loadFromFile(vCfg *viper.Viper, configFileName, configFilePath string) erro
 	return nil
 }
 
-//ReadV2 takes in an io.Reader and reads it
-func ReadV2(reader io.Reader, defaultConfig repo.DefaultConfiguration) (*viper.Viper, error) {
+//ReadV2 takes in an io.Reader and reads it. configType is one of repo.ConfigTypes as a reader has
+//no extension to tell its format
+func ReadV2(reader io.Reader, configType string, defaultConfig repo.DefaultConfiguration) (*viper.Viper, error) {
 	vCfg := viper.New()
 	loadDefaultConfiguration(vCfg, defaultConfig)
 
 	if reader == nil {
-		log.Println("Loading default configuration")
+		logging.Default().Info("Loading default configuration")
 		return vCfg, nil
 	}
 
-	return readFromFile(vCfg, reader)
+	if !repo.IsConfigType(configType) {
+		return nil, fmt.Errorf("Unsupported configuration type %q, use one of %s", configType,
+			strings.Join(repo.ConfigTypes, ", "))
+	}
+
+	return readFromFile(vCfg, reader, configType)
 }
 
-func readFromFile(vCfg *viper.Viper, reader io.Reader) (*viper.Viper, error) {
+func readFromFile(vCfg *viper.Viper, reader io.Reader, configType string) (*viper.Viper, error) {
 	vCfg.SetEnvPrefix("vpr")
 	vCfg.AutomaticEnv()
 	//Needed or ReadConfig does not work
-	vCfg.SetConfigType("yaml")
+	vCfg.SetConfigType(configType)
 	err := vCfg.ReadConfig(reader)
 
 	if err != nil {
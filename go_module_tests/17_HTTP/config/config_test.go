@@ -8,6 +8,7 @@ import (
 	"reflect"
 	"testing"
 
+	"github.com/spf13/pflag"
 	"github.com/spf13/viper"
 )
 
@@ -37,13 +38,35 @@ type SpyReader struct {
 	unmarshalProperlyCalled bool
 	defaultConfig           repo.DefaultConfiguration
 	fileName, filePath      string
+	loadErr                 error
+	file                    map[string]interface{}
+	fileLoaded              bool
+	envKeys                 []string
+	dbName, serverPort      string
+}
+
+//value returns a value of the loaded file over the default one like viper does
+func (s *SpyReader) value(key string) interface{} {
+	if value, ok := s.file[key]; ok && s.fileLoaded {
+		return value
+	}
+
+	return s.defaultConfig[key]
 }
 
 func (s *SpyReader) Unmarshal(rawConf interface{}) error {
-	if _, ok := rawConf.(configuration.Configuration); ok {
-		s.unmarshalProperlyCalled = true
+	conf, ok := rawConf.(configuration.Configuration)
+	if !ok {
+		return nil
 	}
 
+	s.unmarshalProperlyCalled = true
+	s.dbName = fmt.Sprint(s.value("database.name"))
+	s.serverPort = fmt.Sprint(s.value("server.port"))
+
+	conf.SetDatabaseFileName(fmt.Sprint(s.value("database.fileName")))
+	conf.SetServerPort(s.serverPort)
+
 	return nil
 }
 
@@ -51,13 +74,47 @@ func (s *SpyReader) LoadDefaultConfiguration(defaultConfig repo.DefaultConfigura
 	s.defaultConfig = defaultConfig
 }
 
+func (s *SpyReader) SetConfigType(configType string) error {
+	return nil
+}
+
 func (s *SpyReader) LoadFromFile(fileName, filePath string) error {
 	s.fileName = fileName
 	s.filePath = filePath
+	s.fileLoaded = s.loadErr == nil
+
+	return s.loadErr
+}
+
+func (s *SpyReader) BindFlag(key string, flag *pflag.Flag) error {
+	return nil
+}
 
+func (s *SpyReader) BindEnv(key string) error {
+	s.envKeys = append(s.envKeys, key)
 	return nil
 }
 
+func (s *SpyReader) Keys() []string {
+	return nil
+}
+
+func (s *SpyReader) Get(key string) interface{} {
+	return nil
+}
+
+func (s *SpyReader) Source(key string) string {
+	return "default"
+}
+
+func (s *SpyReader) Redact(text string) string {
+	return text
+}
+
+func (s *SpyReader) ShortSecret(key string) bool {
+	return false
+}
+
 func TestConfigurationRead(t *testing.T) {
 	t.Run("Reads default config and config file when given nonempty string unit", func(t *testing.T) {
 		reader := &SpyReader{}
@@ -85,21 +142,40 @@ func TestConfigurationRead(t *testing.T) {
 	})
 
 	t.Run("Reads only default config when given empty string unit", func(t *testing.T) {
-		vpr := &SpyReader{}
+		vpr := &SpyReader{file: map[string]interface{}{
+			"database.fileName": testConfigDbFileName,
+			"server.port":       testConfigServerPort,
+		}}
 		conf := configuration.NewConfiguration(vpr)
 		wantedFilePath := "."
 
 		err := conf.Read(fileName, wantedFilePath, defaultConfig)
 
 		poker.AssertNoError(t, err)
+
+		if !vpr.unmarshalProperlyCalled {
+			t.Fatalf("Unmarshal was not called properly!")
+		}
+
 		assertDbName(t, vpr.dbName, defaultConfig["database.name"].(string))
 		assertDbFileName(t, conf.GetDatabaseFileName(), testConfigDbFileName)
 
-		assertAutomaticEnvCalled(t, vpr)
+		assertEnvBound(t, vpr, "database.filename")
 		assertConfigFileName(t, vpr, fileName, wantedFilePath)
 		assertPort(t, vpr.serverPort, testConfigServerPort)
 	})
 
+	t.Run("Returns the error of a config file that can not be loaded", func(t *testing.T) {
+		vpr := &SpyReader{loadErr: fmt.Errorf("missing file")}
+		conf := configuration.NewConfiguration(vpr)
+
+		poker.AssertError(t, conf.Read(fileName, ".", defaultConfig))
+
+		if vpr.unmarshalProperlyCalled {
+			t.Errorf("Unmarshal was called after the file failed to load")
+		}
+	})
+
 	//t.Run("Reads default config when given empty string", func(t *testing.T) {
 	//_, clean := poker.CreateTempFileOsOpenFile(t, testConfig, fullFileName)
 	//defer clean()
@@ -199,24 +275,29 @@ func assertPort(t *testing.T, got, want string) {
 		fmt.Sprintf("Did not load configuration properly. Port mismatch: Wanted %s but got %s", want, got))
 }
 
-//func assertAutomaticEnvCalled(t *testing.T, vpr *SpyViper) {
-//t.Helper()
-//if !vpr.automaticEnvCalled {
-//t.Fatalf("Invalid config file read: got %s but wanted %s", vpr.configFile, fileName)
-//}
-//}
+func assertEnvBound(t *testing.T, vpr *SpyReader, key string) {
+	t.Helper()
 
-//func assertConfigFileName(t *testing.T, vpr *SpyViper, wantedName, wantedFilePath string) {
-//t.Helper()
+	for _, bound := range vpr.envKeys {
+		if bound == key {
+			return
+		}
+	}
 
-//if vpr.configFile != fileName {
-//t.Fatalf("Invalid config file read: got %s but wanted %s", vpr.configFile, fileName)
-//}
+	t.Fatalf("The environment was not bound to %s, bound keys: %v", key, vpr.envKeys)
+}
 
-//if vpr.configPath != wantedFilePath {
-//t.Fatalf("Invalid config file read: got %s but wanted %s", vpr.configFile, fileName)
-//}
-//}
+func assertConfigFileName(t *testing.T, vpr *SpyReader, wantedName, wantedFilePath string) {
+	t.Helper()
+
+	if vpr.fileName != wantedName {
+		t.Fatalf("Invalid config file read: got %s but wanted %s", vpr.fileName, wantedName)
+	}
+
+	if vpr.filePath != wantedFilePath {
+		t.Fatalf("Invalid config file path read: got %s but wanted %s", vpr.filePath, wantedFilePath)
+	}
+}
 
 func asserStrings(t *testing.T, got, want, errMsg string) {
 	t.Helper()
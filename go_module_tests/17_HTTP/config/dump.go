@@ -0,0 +1,105 @@
+package configuration
+
+import (
+	"fmt"
+	repo "learning/17_HTTP/config/viper"
+	"strings"
+)
+
+//Redacted replaces the values of secrets in dumps
+const Redacted = repo.Redacted
+
+//secretNames are parts of key names whose values are never shown
+var secretNames = []string{"password", "secret", "token", "apikey", "api_key", "credential"}
+
+//Setting is the effective value of a key and where it comes from
+type Setting struct {
+	Key    string
+	Value  string
+	Source string
+}
+
+//Dump returns every key of the configuration sorted by key. Secrets and the values of keys named
+//like secrets are redacted
+func (c *ConfigurationImpl) Dump() []Setting {
+	keys := c.reader.Keys()
+	settings := make([]Setting, len(keys))
+
+	for i, key := range keys {
+		settings[i] = Setting{Key: key, Source: c.reader.Source(key)}
+
+		value := c.reader.Get(key)
+
+		switch {
+		case value == nil:
+		case c.reader.ShortSecret(key):
+			settings[i].Value = Redacted
+		default:
+			settings[i].Value = c.Redact(fmt.Sprint(redact(key, value)))
+		}
+	}
+
+	return settings
+}
+
+//Redact replaces the secrets the configuration references in text. Secrets that are too short to
+//be found in text are not replaced, IsRedacted tells which keys hold them
+func (c *ConfigurationImpl) Redact(text string) string {
+	return c.reader.Redact(text)
+}
+
+//IsRedacted tells if the value of a key must never be shown because it is named like a secret or
+//holds a secret too short for Redact
+func (c *ConfigurationImpl) IsRedacted(key string) bool {
+	return IsSecret(key) || c.reader.ShortSecret(key)
+}
+
+//IsSecret tells if the value of a key must not be shown. Only the last part of the key is checked
+func IsSecret(key string) bool {
+	name := strings.ToLower(key[strings.LastIndex(key, ".")+1:])
+
+	for _, secret := range secretNames {
+		if strings.Contains(name, secret) {
+			return true
+		}
+	}
+
+	return false
+}
+
+//redact replaces the value of a secret key and the secrets nested in lists and maps, like the
+//tokens of alerters
+func redact(key string, value interface{}) interface{} {
+	if IsSecret(key) {
+		return Redacted
+	}
+
+	switch v := value.(type) {
+	case []interface{}:
+		redacted := make([]interface{}, len(v))
+
+		for i, item := range v {
+			redacted[i] = redact("", item)
+		}
+
+		return redacted
+	case map[string]interface{}:
+		redacted := make(map[string]interface{}, len(v))
+
+		for name, item := range v {
+			redacted[name] = redact(name, item)
+		}
+
+		return redacted
+	case map[interface{}]interface{}:
+		redacted := make(map[interface{}]interface{}, len(v))
+
+		for name, item := range v {
+			redacted[name] = redact(fmt.Sprint(name), item)
+		}
+
+		return redacted
+	}
+
+	return value
+}
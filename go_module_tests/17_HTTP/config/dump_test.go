@@ -0,0 +1,105 @@
+package configuration_test
+
+import (
+	"io/ioutil"
+	poker "learning/17_HTTP"
+	configuration "learning/17_HTTP/config"
+	repo "learning/17_HTTP/config/viper"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestDump(t *testing.T) {
+	t.Run("Flags override the environment, the file and the defaults in that order", func(t *testing.T) {
+		dir := t.TempDir()
+		poker.AssertNoError(t, writeFile(dir, "layers.yaml", `
+server:
+   port: ":8000"
+   adminPort: ":8001"
+   assetsDir: "assets"
+`))
+
+		os.Setenv("VPR_SERVER_ADMINPORT", ":9001")
+		os.Setenv("VPR_SERVER_PORT", ":9000")
+		defer os.Unsetenv("VPR_SERVER_ADMINPORT")
+		defer os.Unsetenv("VPR_SERVER_PORT")
+
+		flags := configuration.NewFlagSet("test")
+		poker.AssertNoError(t, flags.Parse([]string{"--port", ":7000"}))
+
+		conf := configuration.NewConfiguration(repo.NewViperReader())
+		poker.AssertNoError(t, conf.BindFlags(flags))
+		poker.AssertNoError(t, conf.Read("layers", dir, repo.DefaultConfiguration{"logging.level": "info"}))
+
+		file := "file " + filepath.Join(dir, "layers.yaml")
+		assertSettings(t, conf.Dump(), map[string]configuration.Setting{
+			"server.port":      {Key: "server.port", Value: ":7000", Source: "flag --port"},
+			"server.adminport": {Key: "server.adminport", Value: ":9001", Source: "env VPR_SERVER_ADMINPORT"},
+			"server.assetsdir": {Key: "server.assetsdir", Value: "assets", Source: file},
+			"logging.level":    {Key: "logging.level", Value: "info", Source: "default"},
+		})
+
+		if got := conf.GetServerPort(); got != ":7000" {
+			t.Errorf("got port %s want the flag :7000", got)
+		}
+	})
+
+	t.Run("Secrets are redacted", func(t *testing.T) {
+		dir := t.TempDir()
+		poker.AssertNoError(t, writeFile(dir, "secrets.yaml", `
+database:
+   password: "hunter2"
+alerters:
+   - type: webhook
+     token: "abc"
+`))
+
+		conf := configuration.NewConfiguration(repo.NewViperReader())
+		poker.AssertNoError(t, conf.Read("secrets", dir, repo.DefaultConfiguration{}))
+
+		file := "file " + filepath.Join(dir, "secrets.yaml")
+		assertSettings(t, conf.Dump(), map[string]configuration.Setting{
+			"database.password": {Key: "database.password", Value: configuration.Redacted, Source: file},
+			"alerters": {Key: "alerters", Value: "[map[token:" + configuration.Redacted + " type:webhook]]",
+				Source: file},
+		})
+	})
+}
+
+func TestIsSecret(t *testing.T) {
+	for key, want := range map[string]bool{
+		"database.password":   true,
+		"alerters.authToken":  true,
+		"saml.apiKey":         true,
+		"server.tls.keyFile":  false,
+		"server.port":         false,
+		"database.secretless": true,
+	} {
+		if got := configuration.IsSecret(key); got != want {
+			t.Errorf("IsSecret(%q) got %v want %v", key, got, want)
+		}
+	}
+}
+
+func writeFile(dir, name, content string) error {
+	return ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0600)
+}
+
+//assertSettings checks the settings of the keys in want. Other keys are ignored
+func assertSettings(t *testing.T, settings []configuration.Setting, want map[string]configuration.Setting) {
+	t.Helper()
+
+	got := map[string]configuration.Setting{}
+
+	for _, setting := range settings {
+		if _, ok := want[setting.Key]; ok {
+			got[setting.Key] = setting
+		}
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got settings %v want %v", got, want)
+	}
+}
@@ -0,0 +1,81 @@
+package configuration
+
+import (
+	repo "learning/17_HTTP/config/viper"
+	"strings"
+	"time"
+
+	"github.com/spf13/pflag"
+)
+
+//flag is a command line flag that overrides a configuration key
+type flag struct {
+	name  string
+	key   string
+	value interface{}
+	usage string
+}
+
+//flags are the keys that can be given on the command line. Their zero values are never used as
+//the flags only take effect when they are given
+var flags = []flag{
+	{"port", "server.port", "", "address the server listens on, like :8000"},
+	{"admin-port", "server.adminPort", "", "address of the admin API"},
+	{"assets-dir", "server.assetsDir", "", "directory whose templates and static files replace the embedded ones"},
+	{"shutdown-timeout", "server.shutdownTimeout", time.Duration(0), "how long running games are waited for on shutdown"},
+	{"drain-grace-period", "server.drainGracePeriod", time.Duration(0), "how long /readyz fails before the server stops"},
+	{"tls-cert", "server.tls.certFile", "", "certificate file used to serve HTTPS"},
+	{"tls-key", "server.tls.keyFile", "", "key file used to serve HTTPS"},
+	{"tls-redirect-port", "server.tls.redirectPort", "", "address that redirects plain HTTP to HTTPS"},
+	{"db-file", "database.fileName", "", "JSON file the players are stored in"},
+	{"snapshot-dir", "database.snapshotDir", "", "directory database snapshots are stored in"},
+	{"snapshot-retention", "database.snapshotRetention", 0, "number of database snapshots that are kept"},
+	{"interrupted-games-file", "database.interruptedGamesFile", "", "file running games are saved to on shutdown"},
+	{"log-level", "logging.level", "", "lowest level that is logged: debug, info, warn or error"},
+	{"trace-output", "logging.traceOutput", "", "stdout or the file trace spans are exported to"},
+}
+
+//configTypeFlag sets the format of a configuration file whose extension does not tell it
+const configTypeFlag = "config-type"
+
+//NewFlagSet returns the command line flags that override the configuration file and environment
+func NewFlagSet(name string) *pflag.FlagSet {
+	set := pflag.NewFlagSet(name, pflag.ContinueOnError)
+	set.String(configTypeFlag, "", "format of the configuration file when its extension does not tell it: "+
+		strings.Join(repo.ConfigTypes, ", "))
+
+	for _, f := range flags {
+		switch value := f.value.(type) {
+		case string:
+			set.String(f.name, value, f.usage+" ("+f.key+")")
+		case int:
+			set.Int(f.name, value, f.usage+" ("+f.key+")")
+		case time.Duration:
+			set.Duration(f.name, value, f.usage+" ("+f.key+")")
+		}
+	}
+
+	return set
+}
+
+//BindFlags makes the flags of a set created by NewFlagSet override their keys and sets the format
+//of the configuration file. Flags the set does not have are skipped so commands can leave some out
+func (c *ConfigurationImpl) BindFlags(set *pflag.FlagSet) error {
+	if configType := set.Lookup(configTypeFlag); configType != nil && configType.Value.String() != "" {
+		if err := c.reader.SetConfigType(configType.Value.String()); err != nil {
+			return err
+		}
+	}
+
+	for _, f := range flags {
+		if set.Lookup(f.name) == nil {
+			continue
+		}
+
+		if err := c.reader.BindFlag(f.key, set.Lookup(f.name)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,110 @@
+package configuration_test
+
+import (
+	poker "learning/17_HTTP"
+	configuration "learning/17_HTTP/config"
+	repo "learning/17_HTTP/config/viper"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var formats = map[string]string{
+	"json": `{"server": {"port": ":8000"}}`,
+	"toml": "[server]\nport = \":8000\"\n",
+	"yaml": "server:\n   port: \":8000\"\n",
+}
+
+func TestFormats(t *testing.T) {
+	for configType, content := range formats {
+		t.Run("Detects "+configType+" by the extension", func(t *testing.T) {
+			dir := t.TempDir()
+			poker.AssertNoError(t, writeFile(dir, "app."+configType, content))
+
+			for _, name := range []string{"app." + configType, "app"} {
+				conf := configuration.NewConfiguration(repo.NewViperReader())
+				poker.AssertNoError(t, conf.Read(name, dir, repo.DefaultConfiguration{}))
+
+				assertPort(t, conf.GetServerPort(), ":8000")
+			}
+		})
+
+		t.Run("Reads "+configType+" without an extension when the type is given", func(t *testing.T) {
+			dir := t.TempDir()
+			poker.AssertNoError(t, writeFile(dir, "app", content))
+
+			flags := configuration.NewFlagSet("test")
+			poker.AssertNoError(t, flags.Parse([]string{"--config-type", configType}))
+
+			conf := configuration.NewConfiguration(repo.NewViperReader())
+			poker.AssertNoError(t, conf.BindFlags(flags))
+			poker.AssertNoError(t, conf.Read("app", dir, repo.DefaultConfiguration{}))
+
+			assertPort(t, conf.GetServerPort(), ":8000")
+		})
+
+		t.Run("ReadV2 reads "+configType+" when the type is given", func(t *testing.T) {
+			vpr, err := configuration.ReadV2(strings.NewReader(content), configType, defaultConfig)
+
+			poker.AssertNoError(t, err)
+			assertPort(t, vpr.GetString("server.port"), ":8000")
+		})
+	}
+
+	t.Run("Unsupported types are rejected", func(t *testing.T) {
+		flags := configuration.NewFlagSet("test")
+		poker.AssertNoError(t, flags.Parse([]string{"--config-type", "ini"}))
+
+		poker.AssertError(t, configuration.NewConfiguration(repo.NewViperReader()).BindFlags(flags))
+
+		_, err := configuration.ReadV2(strings.NewReader(""), "ini", defaultConfig)
+		poker.AssertError(t, err)
+	})
+}
+
+func TestConfigDir(t *testing.T) {
+	dir := t.TempDir()
+	fragments := filepath.Join(dir, repo.ConfigDir)
+	poker.AssertNoError(t, os.Mkdir(fragments, 0700))
+
+	poker.AssertNoError(t, writeFile(dir, "app.yaml", `
+server:
+   port: ":8000"
+   assetsDir: "assets"
+logging:
+   level: "info"
+`))
+	poker.AssertNoError(t, writeFile(fragments, "20-local.toml", "[server]\nport = \":9100\"\n"))
+	poker.AssertNoError(t, writeFile(fragments, "10-prod.json", `{"server": {"port": ":9000", "adminPort": ":9001"}}`))
+	poker.AssertNoError(t, writeFile(fragments, "README.md", "Fragments are merged in lexical order"))
+
+	conf := configuration.NewConfiguration(repo.NewViperReader())
+	poker.AssertNoError(t, conf.Read("app", dir, repo.DefaultConfiguration{}))
+
+	t.Run("Fragments override the file and each other in lexical order", func(t *testing.T) {
+		assertPort(t, conf.GetServerPort(), ":9100")
+		assertPort(t, conf.GetAdminPort(), ":9001")
+
+		if got := conf.GetAssetsDir(); got != "assets" {
+			t.Errorf("got assets dir %q want the one of the file", got)
+		}
+	})
+
+	t.Run("Sources name the fragment that sets a key", func(t *testing.T) {
+		assertSettings(t, conf.Dump(), map[string]configuration.Setting{
+			"server.port": {Key: "server.port", Value: ":9100",
+				Source: "file " + filepath.Join(fragments, "20-local.toml")},
+			"server.adminport": {Key: "server.adminport", Value: ":9001",
+				Source: "file " + filepath.Join(fragments, "10-prod.json")},
+			"logging.level": {Key: "logging.level", Value: "info", Source: "file " + filepath.Join(dir, "app.yaml")},
+		})
+	})
+
+	t.Run("A broken fragment is an error", func(t *testing.T) {
+		poker.AssertNoError(t, writeFile(fragments, "30-broken.json", "{"))
+
+		err := configuration.NewConfiguration(repo.NewViperReader()).Read("app", dir, repo.DefaultConfiguration{})
+		poker.AssertError(t, err)
+	})
+}
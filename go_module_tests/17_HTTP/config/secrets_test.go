@@ -0,0 +1,138 @@
+package configuration_test
+
+import (
+	poker "learning/17_HTTP"
+	configuration "learning/17_HTTP/config"
+	repo "learning/17_HTTP/config/viper"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSecrets(t *testing.T) {
+	dir := t.TempDir()
+	secretFile := filepath.Join(dir, "hook")
+	poker.AssertNoError(t, writeFile(dir, "hook", "https://hooks.example.com/T0K3N\n"))
+
+	os.Setenv("TEST_TRACE_OUTPUT", "/run/traces/s3cr3t.json")
+	os.Setenv("TEST_PORT", ":8000")
+	defer os.Unsetenv("TEST_TRACE_OUTPUT")
+	defer os.Unsetenv("TEST_PORT")
+
+	t.Run("References are replaced by the secrets when the configuration is read", func(t *testing.T) {
+		conf := readSecrets(t, dir, secretFile)
+
+		alerters := conf.GetAlerters()
+
+		if len(alerters) != 1 || alerters[0].URL != "https://hooks.example.com/T0K3N" {
+			t.Errorf("got alerters %v want the webhook URL from %s", alerters, secretFile)
+		}
+
+		if got := conf.GetLoggingConfiguration().TraceOutput; got != "/run/traces/s3cr3t.json" {
+			t.Errorf("got trace output %q want the value of TEST_TRACE_OUTPUT", got)
+		}
+	})
+
+	t.Run("Secrets are redacted in dumps and name their reference", func(t *testing.T) {
+		conf := readSecrets(t, dir, secretFile)
+
+		for _, setting := range conf.Dump() {
+			if strings.Contains(setting.Value, "T0K3N") || strings.Contains(setting.Value, "s3cr3t") {
+				t.Errorf("Dump shows the secret of %s %q", setting.Key, setting.Value)
+			}
+
+			if setting.Key == "logging.traceoutput" && !strings.HasSuffix(setting.Source, " secret env:TEST_TRACE_OUTPUT") {
+				t.Errorf("got source %q want it to name the secret", setting.Source)
+			}
+		}
+	})
+
+	t.Run("Secrets are redacted in validation errors", func(t *testing.T) {
+		os.Setenv("TEST_PORT", "s3cr3t")
+		defer os.Setenv("TEST_PORT", ":8000")
+
+		conf := readSecrets(t, dir, secretFile)
+		err := conf.Validate()
+
+		poker.AssertError(t, err)
+
+		if strings.Contains(err.Error(), "s3cr3t") {
+			t.Errorf("Validation error %q shows the secret", err)
+		}
+	})
+
+	t.Run("Short secrets are hidden by key without changing other text", func(t *testing.T) {
+		os.Setenv("TEST_PORT", ":80")
+		defer os.Setenv("TEST_PORT", ":8000")
+
+		conf := readSecrets(t, dir, secretFile)
+
+		if got := conf.Redact("listening on 127.0.0.1:8080"); got != "listening on 127.0.0.1:8080" {
+			t.Errorf("got %q want the text unchanged", got)
+		}
+
+		if !conf.IsRedacted("server.port") {
+			t.Errorf("Expected server.port to be redacted")
+		}
+
+		for _, setting := range conf.Dump() {
+			if setting.Key == "server.port" && setting.Value != configuration.Redacted {
+				t.Errorf("got server.port %q want it redacted", setting.Value)
+			}
+		}
+	})
+
+	t.Run("Short secrets are not shown in validation errors", func(t *testing.T) {
+		os.Setenv("TEST_PORT", "x1y")
+		defer os.Setenv("TEST_PORT", ":8000")
+
+		conf := readSecrets(t, dir, secretFile)
+		err := conf.Validate()
+
+		poker.AssertError(t, err)
+
+		if strings.Contains(err.Error(), "x1y") {
+			t.Errorf("Validation error %q shows the secret", err)
+		}
+	})
+
+	t.Run("A missing secret is an error that does not load the configuration", func(t *testing.T) {
+		poker.AssertNoError(t, writeFile(dir, "missing.yaml", `
+database:
+   fileName: "env:TEST_MISSING_SECRET"
+`))
+
+		conf := configuration.NewConfiguration(repo.NewViperReader())
+		err := conf.Read("missing", dir, repo.DefaultConfiguration{})
+
+		poker.AssertError(t, err)
+
+		if !strings.Contains(err.Error(), "database.filename") {
+			t.Errorf("Error %q does not name the key", err)
+		}
+	})
+}
+
+//readSecrets reads a configuration whose webhook URL is in secretFile and whose port and trace
+//output are in environment variables
+func readSecrets(t *testing.T, dir, secretFile string) configuration.Configuration {
+	t.Helper()
+
+	poker.AssertNoError(t, writeFile(dir, "secrets.yaml", `
+server:
+   port: "env:TEST_PORT"
+database:
+   fileName: "`+filepath.Join(dir, "game.db.json")+`"
+logging:
+   traceOutput: "env:TEST_TRACE_OUTPUT"
+alerters:
+   - type: webhook
+     url: "file://`+secretFile+`"
+`))
+
+	conf := configuration.NewConfiguration(repo.NewViperReader())
+	poker.AssertNoError(t, conf.Read("secrets", dir, repo.DefaultConfiguration{}))
+
+	return conf
+}
@@ -0,0 +1,329 @@
+package configuration
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//Problem is an invalid configuration value. Source tells where the value came from
+type Problem struct {
+	Key     string
+	Source  string
+	Message string
+}
+
+func (p Problem) String() string {
+	return fmt.Sprintf("%s (%s): %s", p.Key, p.Source, p.Message)
+}
+
+//ValidationError lists every invalid value of a configuration
+type ValidationError struct {
+	Problems []Problem
+}
+
+func (v *ValidationError) Error() string {
+	lines := make([]string, len(v.Problems))
+
+	for i, problem := range v.Problems {
+		lines[i] = problem.String()
+	}
+
+	return fmt.Sprintf("found %d problems:\n  %s", len(lines), strings.Join(lines, "\n  "))
+}
+
+//check returns an error describing what is wrong with a value
+type check func(value interface{}) error
+
+//rule checks the value of a key. Its checks run in order until one fails
+type rule struct {
+	key    string
+	value  func(c *ConfigurationImpl) interface{}
+	checks []check
+}
+
+//relation checks a key against other keys
+type relation struct {
+	key   string
+	check func(c *ConfigurationImpl) error
+}
+
+//serverRules and databaseRules are checked by Validate
+var serverRules = []rule{
+	{"server.port", func(c *ConfigurationImpl) interface{} { return c.Server.Port }, []check{required, address}},
+	{"server.adminPort", func(c *ConfigurationImpl) interface{} { return c.Server.AdminPort }, []check{address}},
+	{"server.assetsDir", func(c *ConfigurationImpl) interface{} { return c.Server.AssetsDir }, []check{existingDir}},
+	{"server.shutdownTimeout", func(c *ConfigurationImpl) interface{} { return c.Server.ShutdownTimeout },
+		[]check{notNegative}},
+	{"server.drainGracePeriod", func(c *ConfigurationImpl) interface{} { return c.Server.DrainGracePeriod },
+		[]check{notNegative}},
+	{"server.rateLimit.requestsPerSecond", func(c *ConfigurationImpl) interface{} {
+		return c.Server.RateLimit.RequestsPerSecond
+	}, []check{notNegative}},
+	{"server.rateLimit.burst", func(c *ConfigurationImpl) interface{} { return c.Server.RateLimit.Burst },
+		[]check{notNegative}},
+	{"server.tls.certFile", func(c *ConfigurationImpl) interface{} { return c.Server.TLS.CertFile }, []check{readableFile}},
+	{"server.tls.keyFile", func(c *ConfigurationImpl) interface{} { return c.Server.TLS.KeyFile }, []check{readableFile}},
+	{"server.tls.redirectPort", func(c *ConfigurationImpl) interface{} { return c.Server.TLS.RedirectPort },
+		[]check{address}},
+}
+
+var databaseRules = []rule{
+	{"database.fileName", func(c *ConfigurationImpl) interface{} { return c.Database.FileName },
+		[]check{required, writableFile}},
+	{"database.snapshotDir", func(c *ConfigurationImpl) interface{} { return c.Database.SnapshotDir },
+		[]check{writableDir}},
+	{"database.snapshotRetention", func(c *ConfigurationImpl) interface{} { return c.Database.SnapshotRetention },
+		[]check{notNegative}},
+	{"database.interruptedGamesFile", func(c *ConfigurationImpl) interface{} { return c.Database.InterruptedGamesFile },
+		[]check{writableFile}},
+}
+
+var relations = []relation{
+	{"server.adminPort", func(c *ConfigurationImpl) error {
+		if sameListener(c.Server.AdminPort, c.Server.Port) || sameListener(c.Server.AdminPort, c.Server.TLS.RedirectPort) {
+			return fmt.Errorf("must not be a public port")
+		}
+
+		return nil
+	}},
+	{"server.tls.keyFile", func(c *ConfigurationImpl) error {
+		if (c.Server.TLS.CertFile == "") != (c.Server.TLS.KeyFile == "") {
+			return fmt.Errorf("server.tls.certFile and server.tls.keyFile must be set together")
+		}
+
+		return nil
+	}},
+}
+
+//Validate checks the server and database configuration. It returns a ValidationError with every
+//problem it finds so they can all be fixed at once
+func (c *ConfigurationImpl) Validate() error {
+	var problems []Problem
+
+	for _, rules := range [][]rule{serverRules, databaseRules} {
+		for _, r := range rules {
+			value := r.value(c)
+
+			for _, check := range r.checks {
+				if err := check(value); err != nil {
+					problems = append(problems, c.problem(r.key, err))
+					break
+				}
+			}
+		}
+	}
+
+	for _, r := range relations {
+		if err := r.check(c); err != nil {
+			problems = append(problems, c.problem(r.key, err))
+		}
+	}
+
+	if problems != nil {
+		return &ValidationError{problems}
+	}
+
+	return nil
+}
+
+//problem describes the error of a key. The error of a key holding a secret too short for Redact is
+//not shown since it may quote the secret
+func (c *ConfigurationImpl) problem(key string, err error) Problem {
+	message := c.Redact(err.Error())
+
+	if c.reader.ShortSecret(key) {
+		message = "is invalid, the secret it references is not shown"
+	}
+
+	return Problem{Key: key, Source: c.reader.Source(key), Message: message}
+}
+
+//required fails for empty strings. The other checks accept empty strings as not configured
+func required(value interface{}) error {
+	if value == "" {
+		return fmt.Errorf("is required")
+	}
+
+	return nil
+}
+
+//address checks a host and port like ":8000" or "127.0.0.1:8001". Port 0 picks a free port
+func address(value interface{}) error {
+	addr, _ := value.(string)
+
+	if addr == "" {
+		return nil
+	}
+
+	_, port, err := net.SplitHostPort(addr)
+
+	if err != nil {
+		return fmt.Errorf("must be a host and port like \":8000\" but is %q", addr)
+	}
+
+	number, err := strconv.Atoi(port)
+
+	if err != nil || number < 0 || number > 65535 {
+		return fmt.Errorf("must have a port between 0 and 65535 but is %q", addr)
+	}
+
+	return nil
+}
+
+//sameListener tells if two addresses would listen on the same port. A wildcard host like "",
+//"0.0.0.0" or "::" listens on every host. Port 0 picks a free port so it never clashes
+func sameListener(a, b string) bool {
+	hostA, portA, errA := net.SplitHostPort(a)
+	hostB, portB, errB := net.SplitHostPort(b)
+
+	if errA != nil || errB != nil {
+		return a != "" && a == b
+	}
+
+	numberA, _ := strconv.Atoi(portA)
+	numberB, _ := strconv.Atoi(portB)
+
+	if numberA != numberB || numberA == 0 {
+		return false
+	}
+
+	return wildcardHost(hostA) || wildcardHost(hostB) || hostA == hostB ||
+		net.ParseIP(hostA) != nil && net.ParseIP(hostA).Equal(net.ParseIP(hostB))
+}
+
+//wildcardHost tells if a host listens on every address
+func wildcardHost(host string) bool {
+	ip := net.ParseIP(host)
+	return host == "" || ip != nil && ip.IsUnspecified()
+}
+
+func notNegative(value interface{}) error {
+	var negative bool
+
+	switch v := value.(type) {
+	case int:
+		negative = v < 0
+	case float64:
+		negative = v < 0
+	case time.Duration:
+		negative = v < 0
+	}
+
+	if negative {
+		return fmt.Errorf("must not be negative but is %v", value)
+	}
+
+	return nil
+}
+
+func existingDir(value interface{}) error {
+	dir, _ := value.(string)
+
+	if dir == "" {
+		return nil
+	}
+
+	info, err := os.Stat(dir)
+
+	if err != nil {
+		return fmt.Errorf("must be an existing directory %v", err)
+	}
+
+	if !info.IsDir() {
+		return fmt.Errorf("must be a directory but %s is a file", dir)
+	}
+
+	return nil
+}
+
+func readableFile(value interface{}) error {
+	fileName, _ := value.(string)
+
+	if fileName == "" {
+		return nil
+	}
+
+	file, err := os.Open(fileName)
+
+	if err != nil {
+		return fmt.Errorf("must be a readable file %v", err)
+	}
+
+	return file.Close()
+}
+
+//writableFile checks that a file can be written or created without creating it
+func writableFile(value interface{}) error {
+	fileName, _ := value.(string)
+
+	if fileName == "" {
+		return nil
+	}
+
+	info, err := os.Stat(fileName)
+
+	if os.IsNotExist(err) {
+		return writableDirectory(filepath.Dir(fileName), false)
+	}
+
+	if err != nil {
+		return fmt.Errorf("must be a writable file %v", err)
+	}
+
+	if info.IsDir() {
+		return fmt.Errorf("must be a file but %s is a directory", fileName)
+	}
+
+	file, err := os.OpenFile(fileName, os.O_WRONLY, 0)
+
+	if err != nil {
+		return fmt.Errorf("must be a writable file %v", err)
+	}
+
+	return file.Close()
+}
+
+//writableDir checks that a directory can be written to or created with its parents
+func writableDir(value interface{}) error {
+	dir, _ := value.(string)
+
+	if dir == "" {
+		return nil
+	}
+
+	return writableDirectory(dir, true)
+}
+
+//writableDirectory tries to create a file in dir. When createMissing is set the closest existing
+//parent of dir has to be writable instead
+func writableDirectory(dir string, createMissing bool) error {
+	info, err := os.Stat(dir)
+
+	if os.IsNotExist(err) && createMissing && filepath.Dir(dir) != dir {
+		return writableDirectory(filepath.Dir(dir), true)
+	}
+
+	if err != nil {
+		return fmt.Errorf("must be in an existing directory %v", err)
+	}
+
+	if !info.IsDir() {
+		return fmt.Errorf("must be in a directory but %s is a file", dir)
+	}
+
+	probe, err := ioutil.TempFile(dir, ".validate-")
+
+	if err != nil {
+		return fmt.Errorf("must be in a writable directory but %s is not", dir)
+	}
+
+	probe.Close()
+
+	return os.Remove(probe.Name())
+}
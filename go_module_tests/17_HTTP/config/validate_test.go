@@ -0,0 +1,143 @@
+package configuration_test
+
+import (
+	"errors"
+	"io/ioutil"
+	poker "learning/17_HTTP"
+	configuration "learning/17_HTTP/config"
+	repo "learning/17_HTTP/config/viper"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	t.Run("A valid configuration has no problems", func(t *testing.T) {
+		dir := t.TempDir()
+		conf := readConfig(t, dir, `
+server:
+   port: ":8000"
+   adminPort: "127.0.0.1:8001"
+database:
+   fileName: `+filepath.Join(dir, "game.db.json")+`
+   snapshotDir: `+filepath.Join(dir, "snapshots", "daily"))
+
+		poker.AssertNoError(t, conf.Validate())
+	})
+
+	t.Run("Every problem is reported at once with its key and source", func(t *testing.T) {
+		dir := t.TempDir()
+		conf := readConfig(t, dir, `
+server:
+   port: ":99999"
+   adminPort: ":99999"
+   shutdownTimeout: -1s
+   tls:
+      certFile: `+filepath.Join(dir, "missing.pem")+`
+database:
+   snapshotRetention: -1
+   interruptedGamesFile: `+filepath.Join(dir, "missing", "games.json"))
+
+		configFile := "file " + filepath.Join(dir, "validate.yaml")
+		want := []configuration.Problem{
+			{Key: "server.port", Source: configFile},
+			{Key: "server.adminPort", Source: configFile},
+			{Key: "server.shutdownTimeout", Source: configFile},
+			{Key: "server.tls.certFile", Source: configFile},
+			{Key: "database.fileName", Source: "default"},
+			{Key: "database.snapshotRetention", Source: configFile},
+			{Key: "database.interruptedGamesFile", Source: configFile},
+			{Key: "server.adminPort", Source: configFile},
+			{Key: "server.tls.keyFile", Source: "default"},
+		}
+
+		assertProblems(t, conf.Validate(), want)
+	})
+
+	t.Run("The admin port must not share a public port", func(t *testing.T) {
+		cases := []struct {
+			port      string
+			adminPort string
+			clash     bool
+		}{
+			{":8000", "0.0.0.0:8000", true},
+			{":8000", "127.0.0.1:8000", true},
+			{"0.0.0.0:8000", "127.0.0.1:8000", true},
+			{"[::]:8000", "127.0.0.1:8000", true},
+			{"127.0.0.1:8000", "127.0.0.1:08000", true},
+			{"[::1]:8000", "[0:0:0:0:0:0:0:1]:8000", true},
+			{"127.0.0.1:8000", "127.0.0.2:8000", false},
+			{":8000", ":8001", false},
+			{":0", ":0", false},
+		}
+
+		for _, test := range cases {
+			dir := t.TempDir()
+			conf := readConfig(t, dir, `
+server:
+   port: "`+test.port+`"
+   adminPort: "`+test.adminPort+`"
+database:
+   fileName: `+filepath.Join(dir, "game.db.json"))
+
+			err := conf.Validate()
+
+			if clash := err != nil; clash != test.clash {
+				t.Errorf("Expected a clash between %s and %s to be %v but got %v", test.port, test.adminPort,
+					test.clash, err)
+			}
+		}
+	})
+
+	t.Run("Values from the environment name their variable", func(t *testing.T) {
+		dir := t.TempDir()
+		os.Setenv("VPR_SERVER_PORT", "8000")
+		defer os.Unsetenv("VPR_SERVER_PORT")
+
+		conf := readConfig(t, dir, `
+database:
+   fileName: `+filepath.Join(dir, "game.db.json"))
+
+		assertProblems(t, conf.Validate(), []configuration.Problem{
+			{Key: "server.port", Source: "env VPR_SERVER_PORT"},
+		})
+	})
+}
+
+//readConfig writes content to validate.yaml in dir and reads it
+func readConfig(t *testing.T, dir, content string) configuration.Configuration {
+	t.Helper()
+
+	poker.AssertNoError(t, ioutil.WriteFile(filepath.Join(dir, "validate.yaml"), []byte(content), 0600))
+
+	conf := configuration.NewConfiguration(repo.NewViperReader())
+	poker.AssertNoError(t, conf.Read("validate", dir, repo.DefaultConfiguration{}))
+
+	return conf
+}
+
+//assertProblems compares the keys and sources of the problems. Messages are only checked to be set
+func assertProblems(t *testing.T, err error, want []configuration.Problem) {
+	t.Helper()
+
+	var invalid *configuration.ValidationError
+
+	if !errors.As(err, &invalid) {
+		t.Fatalf("Expected a validation error but got %v", err)
+	}
+
+	got := make([]configuration.Problem, len(invalid.Problems))
+
+	for i, problem := range invalid.Problems {
+		if problem.Message == "" {
+			t.Errorf("Problem %s has no message", problem.Key)
+		}
+
+		got[i] = configuration.Problem{Key: problem.Key, Source: problem.Source}
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got problems %v want %v", got, want)
+	}
+}
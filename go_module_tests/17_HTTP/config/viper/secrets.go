@@ -0,0 +1,163 @@
+package configurationrepo
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+//Redacted replaces secrets in dumps and messages
+const Redacted = "<redacted>"
+
+//Values starting with these prefixes reference a secret instead of holding it. file:///run/secrets/x
+//is replaced by the content of /run/secrets/x and env:NAME by the environment variable NAME
+const (
+	secretFilePrefix = "file://"
+	secretEnvPrefix  = "env:"
+)
+
+//minRedactedLength is the length of the shortest secret Redact replaces in text. Shorter ones like
+//a port would also match unrelated words and numbers so the keys holding them are hidden instead
+const minRedactedLength = 6
+
+//resolveSecrets replaces the values that reference secrets, including the ones in lists and maps,
+//by the secrets. The secrets are remembered so Redact can hide them
+func (v *ViperReader) resolveSecrets() error {
+	for _, key := range v.vpr.AllKeys() {
+		value := v.vpr.Get(key)
+		resolved := len(v.secrets)
+		resolvedValue, changed, err := v.resolve(value)
+
+		if err != nil {
+			return errors.Wrap(err, fmt.Sprintf("Error resolving the secret of %s", key))
+		}
+
+		if !changed {
+			continue
+		}
+
+		for _, secret := range v.secrets[resolved:] {
+			if len(secret) < minRedactedLength {
+				v.shortSecrets[key] = true
+			}
+		}
+
+		if reference, ok := value.(string); ok {
+			v.references[key] = reference
+		}
+
+		v.vpr.Set(key, resolvedValue)
+	}
+
+	//Longer secrets go first so a secret containing another one is replaced whole
+	sort.Slice(v.secrets, func(i, j int) bool { return len(v.secrets[i]) > len(v.secrets[j]) })
+
+	return nil
+}
+
+//resolve returns the value with its secret references replaced and if there were any
+func (v *ViperReader) resolve(value interface{}) (interface{}, bool, error) {
+	switch value := value.(type) {
+	case string:
+		secret, ok, err := readSecret(value)
+
+		if ok && err == nil {
+			v.secrets = append(v.secrets, secret)
+		}
+
+		return secret, ok, err
+	case []interface{}:
+		resolved := make([]interface{}, len(value))
+		var changed bool
+
+		for i, item := range value {
+			var itemChanged bool
+			var err error
+
+			if resolved[i], itemChanged, err = v.resolve(item); err != nil {
+				return nil, false, errors.Wrap(err, fmt.Sprintf("[%d]", i))
+			}
+
+			changed = changed || itemChanged
+		}
+
+		return resolved, changed, nil
+	case map[string]interface{}:
+		resolved := make(map[string]interface{}, len(value))
+		var changed bool
+
+		for name, item := range value {
+			var itemChanged bool
+			var err error
+
+			if resolved[name], itemChanged, err = v.resolve(item); err != nil {
+				return nil, false, errors.Wrap(err, name)
+			}
+
+			changed = changed || itemChanged
+		}
+
+		return resolved, changed, nil
+	case map[interface{}]interface{}:
+		//YAML files decode maps in lists with keys of any type
+		converted := make(map[string]interface{}, len(value))
+
+		for name, item := range value {
+			converted[fmt.Sprint(name)] = item
+		}
+
+		if resolved, changed, err := v.resolve(converted); changed || err != nil {
+			return resolved, changed, err
+		}
+	}
+
+	return value, false, nil
+}
+
+//readSecret returns the secret a value references. The errors name the reference but never the secret
+func readSecret(value string) (string, bool, error) {
+	switch {
+	case strings.HasPrefix(value, secretFilePrefix):
+		content, err := ioutil.ReadFile(strings.TrimPrefix(value, secretFilePrefix))
+
+		if err != nil {
+			return "", true, fmt.Errorf("Could not read secret %s %v", value, err)
+		}
+
+		//Secret files usually end with a newline that is not part of the secret
+		return strings.TrimRight(string(content), "\r\n"), true, nil
+	case strings.HasPrefix(value, secretEnvPrefix):
+		name := strings.TrimPrefix(value, secretEnvPrefix)
+		secret, ok := os.LookupEnv(name)
+
+		if !ok {
+			return "", true, fmt.Errorf("Could not read secret %s, the variable is not set", value)
+		}
+
+		return secret, true, nil
+	}
+
+	return value, false, nil
+}
+
+//Redact replaces every secret that was resolved in text. Secrets shorter than minRedactedLength
+//are left alone, their keys are reported by ShortSecret instead
+func (v *ViperReader) Redact(text string) string {
+	for _, secret := range v.secrets {
+		if len(secret) >= minRedactedLength {
+			text = strings.ReplaceAll(text, secret, Redacted)
+		}
+	}
+
+	return text
+}
+
+//ShortSecret tells if the value of a key holds a secret too short for Redact to replace. The whole
+//value of such a key must be hidden
+func (v *ViperReader) ShortSecret(key string) bool {
+	return v.shortSecrets[strings.ToLower(key)]
+}
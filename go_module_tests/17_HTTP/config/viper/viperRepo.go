@@ -2,8 +2,14 @@ package configurationrepo
 
 import (
 	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
 
 	"github.com/pkg/errors"
+	"github.com/spf13/pflag"
 	"github.com/spf13/viper"
 )
 
@@ -31,18 +37,49 @@ type DefaultConfiguration map[string]interface{}
 //Reader represents a configuration reader that does all the reading from a file
 type Reader interface {
 	LoadDefaultConfiguration(defaultConfig DefaultConfiguration)
+	SetConfigType(configType string) error
 	LoadFromFile(configFileName, configFilePath string) error
+	BindFlag(key string, flag *pflag.Flag) error
+	BindEnv(key string) error
 	Unmarshal(rawValue interface{}) error
+	Keys() []string
+	Get(key string) interface{}
+	Source(key string) string
+	Redact(text string) string
+	ShortSecret(key string) bool
 }
 
-//ViperReader uses the viper library to implement the viper class
+//EnvPrefix starts the names of the environment variables that override the configuration.
+//The dots of a key become underscores so server.port is set by VPR_SERVER_PORT
+const EnvPrefix = "vpr"
+
+//ConfigDir is the directory next to the configuration file whose fragments override it
+const ConfigDir = "conf.d"
+
+//ConfigTypes are the supported formats of configuration files
+var ConfigTypes = []string{"json", "toml", "yaml", "yml"}
+
+//ViperReader uses the viper library to implement the viper class. Values are looked up in the
+//flags, the environment, the configuration files and the defaults in that order
 type ViperReader struct {
-	vpr *viper.Viper
+	vpr          *viper.Viper
+	configType   string
+	files        []*viper.Viper
+	flags        map[string]*pflag.Flag
+	references   map[string]string
+	secrets      []string
+	shortSecrets map[string]bool
 }
 
 //NewViperReader is a default constructor for ViperReader
 func NewViperReader() Reader {
-	return &ViperReader{viper.New()}
+	vpr := viper.New()
+	vpr.SetEnvPrefix(EnvPrefix)
+	vpr.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
+	vpr.AutomaticEnv()
+
+	return &ViperReader{vpr: vpr, flags: map[string]*pflag.Flag{}, references: map[string]string{},
+		shortSecrets: map[string]bool{}}
 }
 
 //LoadDefaultConfiguration loads a DefaultConfiguration inside of a viper.Viper
@@ -52,8 +89,13 @@ func (v *ViperReader) LoadDefaultConfiguration(defaultConfig DefaultConfiguratio
 	}
 }
 
-//Unmarshal uses the viper library to unmarshal the configuration loaded into it into a structure
+//Unmarshal resolves the secrets the values reference and uses the viper library to unmarshal the
+//configuration loaded into it into a structure
 func (v *ViperReader) Unmarshal(rawValue interface{}) error {
+	if err := v.resolveSecrets(); err != nil {
+		return err
+	}
+
 	err := v.vpr.Unmarshal(rawValue)
 
 	if err != nil {
@@ -63,12 +105,41 @@ func (v *ViperReader) Unmarshal(rawValue interface{}) error {
 	return nil
 }
 
-//LoadFromFile reads a file into the viperConfiguration
+//SetConfigType sets the format of the configuration file when its extension does not tell it
+func (v *ViperReader) SetConfigType(configType string) error {
+	if !IsConfigType(configType) {
+		return fmt.Errorf("Unsupported configuration type %q, use one of %s", configType,
+			strings.Join(ConfigTypes, ", "))
+	}
+
+	v.configType = configType
+	v.vpr.SetConfigType(configType)
+
+	return nil
+}
+
+//IsConfigType tells if configType is one of the ConfigTypes
+func IsConfigType(configType string) bool {
+	for _, supported := range ConfigTypes {
+		if configType == supported {
+			return true
+		}
+	}
+
+	return false
+}
+
+//LoadFromFile reads a file into the viperConfiguration and merges the fragments in the ConfigDir
+//next to it in lexical order. A file name without an extension is looked up with every supported
+//extension. The format is taken from the extension unless SetConfigType was called
 func (v *ViperReader) LoadFromFile(configFileName, configFilePath string) error {
-	v.vpr.SetEnvPrefix("vpr")
-	v.vpr.SetConfigName(configFileName)
-	v.vpr.AddConfigPath(configFilePath)
-	v.vpr.AutomaticEnv()
+	if ext := filepath.Ext(configFileName); ext != "" && IsConfigType(ext[1:]) {
+		v.vpr.SetConfigFile(filepath.Join(configFilePath, configFileName))
+	} else {
+		v.vpr.SetConfigName(configFileName)
+		v.vpr.AddConfigPath(configFilePath)
+	}
+
 	err := v.vpr.ReadInConfig()
 
 	if err != nil {
@@ -78,5 +149,126 @@ func (v *ViperReader) LoadFromFile(configFileName, configFilePath string) error
 		return errors.Wrap(err, errText)
 	}
 
+	file, err := readFile(v.vpr.ConfigFileUsed(), v.configType)
+
+	if err != nil {
+		return err
+	}
+
+	v.files = []*viper.Viper{file}
+
+	return v.mergeFragments(filepath.Join(configFilePath, ConfigDir))
+}
+
+//mergeFragments merges the files of a supported type in dir in lexical order. Later fragments
+//override earlier ones. A missing dir has no fragments
+func (v *ViperReader) mergeFragments(dir string) error {
+	entries, err := ioutil.ReadDir(dir)
+
+	if os.IsNotExist(err) {
+		return nil
+	}
+
+	if err != nil {
+		return errors.Wrap(err, "Error reading configuration fragments")
+	}
+
+	for _, entry := range entries {
+		ext := filepath.Ext(entry.Name())
+
+		if entry.IsDir() || strings.HasPrefix(entry.Name(), ".") || ext == "" || !IsConfigType(ext[1:]) {
+			continue
+		}
+
+		fragment, err := readFile(filepath.Join(dir, entry.Name()), "")
+
+		if err != nil {
+			return err
+		}
+
+		if err := v.vpr.MergeConfigMap(fragment.AllSettings()); err != nil {
+			return errors.Wrap(err, fmt.Sprintf("Error merging configuration fragment %s", entry.Name()))
+		}
+
+		v.files = append(v.files, fragment)
+	}
+
+	return nil
+}
+
+//readFile reads a single configuration file into its own viper, which tells the keys the file sets.
+//The format is taken from the extension when configType is empty
+func readFile(fileName, configType string) (*viper.Viper, error) {
+	file := viper.New()
+	file.SetConfigFile(fileName)
+
+	if configType != "" {
+		file.SetConfigType(configType)
+	}
+
+	if err := file.ReadInConfig(); err != nil {
+		return nil, errors.Wrap(err, fmt.Sprintf("Error reading configuration file: %s", fileName))
+	}
+
+	return file, nil
+}
+
+//BindFlag makes a flag override the key when it is given on the command line
+func (v *ViperReader) BindFlag(key string, flag *pflag.Flag) error {
+	if err := v.vpr.BindPFlag(key, flag); err != nil {
+		return errors.Wrap(err, fmt.Sprintf("Error binding flag %s to %s", flag.Name, key))
+	}
+
+	v.flags[strings.ToLower(key)] = flag
+
 	return nil
 }
+
+//BindEnv makes the environment variable of a key override it even when the key is neither in the
+//file nor in the defaults
+func (v *ViperReader) BindEnv(key string) error {
+	return v.vpr.BindEnv(key)
+}
+
+//Keys returns every key that has a value, sorted
+func (v *ViperReader) Keys() []string {
+	keys := v.vpr.AllKeys()
+	sort.Strings(keys)
+
+	return keys
+}
+
+//Get returns the effective value of a key
+func (v *ViperReader) Get(key string) interface{} {
+	return v.vpr.Get(key)
+}
+
+//Source tells where the value of a key comes from. It is the flag, the environment variable or
+//the file that sets it or "default", followed by the secret it references
+func (v *ViperReader) Source(key string) string {
+	if reference, ok := v.references[strings.ToLower(key)]; ok {
+		return v.source(key) + " secret " + reference
+	}
+
+	return v.source(key)
+}
+
+func (v *ViperReader) source(key string) string {
+	if flag, ok := v.flags[strings.ToLower(key)]; ok && flag.Changed {
+		return "flag --" + flag.Name
+	}
+
+	name := strings.ToUpper(EnvPrefix + "_" + strings.ReplaceAll(key, ".", "_"))
+
+	if _, ok := os.LookupEnv(name); ok {
+		return "env " + name
+	}
+
+	for i := len(v.files) - 1; i >= 0; i-- {
+		if v.files[i].IsSet(key) {
+			return "file " + v.files[i].ConfigFileUsed()
+		}
+	}
+
+	return "default"
+}
@@ -0,0 +1,431 @@
+package poker
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"sync"
+)
+
+//Types of the events sent to the players of a game
+const (
+	MessageEvent string = "message"
+	ResultEvent  string = "result"
+)
+
+const (
+	subscriberBuffer int = 64
+	maxFinishedGames int = 50
+	gameTokenBytes   int = 16
+)
+
+//GameEvent is a single message sent to the players of a game
+type GameEvent struct {
+	ID   int
+	Type string
+	Data string
+}
+
+//GameEvents records every message written to a running game and broadcasts it to subscribers.
+//Both the websocket and the server-sent events transports read from it so they show the same data.
+//It also holds the game that is played so every connection of the game applies its messages to it.
+//Players are the websocket clients controlling the game. Unlike subscribers they leave out viewers
+type GameEvents struct {
+	events      []GameEvent
+	subscribers map[chan GameEvent]struct{}
+	game        AbstractGame
+	token       string
+	players     int
+	leaves      int
+	started     bool
+	finished    bool
+	mx          sync.Mutex
+}
+
+//NewGameEvents is a constructor for GameEvents
+func NewGameEvents() *GameEvents {
+	return &GameEvents{subscribers: map[chan GameEvent]struct{}{}}
+}
+
+//Write records a message event. It lets GameEvents be the io.Writer a game is started with
+func (g *GameEvents) Write(msg []byte) (int, error) {
+	g.publish(MessageEvent, string(msg))
+	return len(msg), nil
+}
+
+//Finish records the result of the game, stops the alerts of its game and disconnects every subscriber
+func (g *GameEvents) Finish(result string) {
+	g.publish(ResultEvent, result)
+
+	g.mx.Lock()
+	defer g.mx.Unlock()
+
+	g.finished = true
+
+	if game, ok := g.game.(StoppableGame); ok {
+		game.Stop()
+	}
+
+	for subscriber := range g.subscribers {
+		close(subscriber)
+		delete(g.subscribers, subscriber)
+	}
+}
+
+//Events returns every event of the game so far
+func (g *GameEvents) Events() []GameEvent {
+	g.mx.Lock()
+	defer g.mx.Unlock()
+
+	return append([]GameEvent{}, g.events...)
+}
+
+//Finished tells if the result of the game is known
+func (g *GameEvents) Finished() bool {
+	g.mx.Lock()
+	defer g.mx.Unlock()
+
+	return g.finished
+}
+
+//MarkStarted records that game was started. It returns false if another game already was so a
+//game resumed from another connection is not started twice
+func (g *GameEvents) MarkStarted(game AbstractGame) bool {
+	g.mx.Lock()
+	defer g.mx.Unlock()
+
+	if g.started {
+		return false
+	}
+
+	g.started = true
+	g.game = game
+	return true
+}
+
+//Game returns the game that was started or nil if none was
+func (g *GameEvents) Game() AbstractGame {
+	g.mx.Lock()
+	defer g.mx.Unlock()
+
+	return g.game
+}
+
+//Started tells if the game was started
+func (g *GameEvents) Started() bool {
+	g.mx.Lock()
+	defer g.mx.Unlock()
+
+	return g.started
+}
+
+//Subscribers returns the number of clients following the game
+func (g *GameEvents) Subscribers() int {
+	g.mx.Lock()
+	defer g.mx.Unlock()
+
+	return len(g.subscribers)
+}
+
+//Join records that a websocket client took control of the game
+func (g *GameEvents) Join() {
+	g.mx.Lock()
+	defer g.mx.Unlock()
+
+	g.players++
+}
+
+//Leave records that a websocket client stopped controlling the game. It returns how many times
+//clients left so far which tells Abandoned if anybody left again since
+func (g *GameEvents) Leave() int {
+	g.mx.Lock()
+	defer g.mx.Unlock()
+
+	g.players--
+	g.leaves++
+
+	return g.leaves
+}
+
+//Abandoned tells if the game is still running and nobody took it back since Leave returned leave
+func (g *GameEvents) Abandoned(leave int) bool {
+	g.mx.Lock()
+	defer g.mx.Unlock()
+
+	return !g.finished && g.players == 0 && g.leaves == leave
+}
+
+//Subscribe returns the events after lastID that already happened and a channel with the ones
+//that will follow. The channel is closed when the game finishes, when the subscriber falls too far
+//behind or when cancel is called
+func (g *GameEvents) Subscribe(lastID int) (backlog []GameEvent, live <-chan GameEvent, cancel func()) {
+	g.mx.Lock()
+	defer g.mx.Unlock()
+
+	for _, event := range g.events {
+		if event.ID > lastID {
+			backlog = append(backlog, event)
+		}
+	}
+
+	subscriber := make(chan GameEvent, subscriberBuffer)
+
+	if g.finished {
+		close(subscriber)
+		return backlog, subscriber, func() {}
+	}
+
+	g.subscribers[subscriber] = struct{}{}
+
+	cancel = func() {
+		g.mx.Lock()
+		defer g.mx.Unlock()
+
+		if _, ok := g.subscribers[subscriber]; ok {
+			close(subscriber)
+			delete(g.subscribers, subscriber)
+		}
+	}
+
+	return backlog, subscriber, cancel
+}
+
+func (g *GameEvents) publish(eventType, data string) {
+	g.mx.Lock()
+	defer g.mx.Unlock()
+
+	if g.finished {
+		return
+	}
+
+	event := GameEvent{ID: len(g.events) + 1, Type: eventType, Data: data}
+	g.events = append(g.events, event)
+
+	for subscriber := range g.subscribers {
+		select {
+		case subscriber <- event:
+		default:
+			close(subscriber)
+			delete(g.subscribers, subscriber)
+		}
+	}
+}
+
+//GameRegistry keeps the events of running games by their id. Only the most recent finished
+//games are kept so late subscribers can still see their results.
+//Every game also gets a secret token. The id is public while the token lets a client take over
+//a game after its connection drops
+type GameRegistry struct {
+	games  map[string]*GameEvents
+	tokens map[string]string
+	order  []string
+	lastID int
+	mx     sync.Mutex
+}
+
+//NewGameRegistry is a constructor for GameRegistry
+func NewGameRegistry() *GameRegistry {
+	return &GameRegistry{games: map[string]*GameEvents{}, tokens: map[string]string{}}
+}
+
+//New registers a new game and returns its id and events
+func (r *GameRegistry) New() (string, *GameEvents) {
+	r.mx.Lock()
+	defer r.mx.Unlock()
+
+	r.prune()
+
+	r.lastID++
+	id := strconv.Itoa(r.lastID)
+	events := NewGameEvents()
+	events.token = newGameToken()
+
+	r.games[id] = events
+	r.tokens[events.token] = id
+	r.order = append(r.order, id)
+
+	return id, events
+}
+
+//Token returns the token of the game with the given id or an empty string if there is no such game
+func (r *GameRegistry) Token(id string) string {
+	r.mx.Lock()
+	defer r.mx.Unlock()
+
+	events, ok := r.games[id]
+
+	if !ok {
+		return ""
+	}
+
+	return events.token
+}
+
+//Lookup returns the id and events of the game with the given token. The events are nil
+//if the token is unknown
+func (r *GameRegistry) Lookup(token string) (string, *GameEvents) {
+	r.mx.Lock()
+	defer r.mx.Unlock()
+
+	id, ok := r.tokens[token]
+
+	if !ok {
+		return "", nil
+	}
+
+	return id, r.games[id]
+}
+
+//Get returns the events of the game with the given id or nil if there is no such game
+func (r *GameRegistry) Get(id string) *GameEvents {
+	r.mx.Lock()
+	defer r.mx.Unlock()
+
+	return r.games[id]
+}
+
+//Running returns the number of games that have not finished
+func (r *GameRegistry) Running() int {
+	r.mx.Lock()
+	defer r.mx.Unlock()
+
+	running := 0
+
+	for _, events := range r.games {
+		if !events.Finished() {
+			running++
+		}
+	}
+
+	return running
+}
+
+//RunningGame is the state of a game that has not finished
+type RunningGame struct {
+	ID          string
+	Started     bool
+	Subscribers int
+	Events      []GameEvent
+}
+
+//RunningGames returns the games that have not finished from the oldest to the newest
+func (r *GameRegistry) RunningGames() []RunningGame {
+	r.mx.Lock()
+	defer r.mx.Unlock()
+
+	running := []RunningGame{}
+
+	for _, id := range r.order {
+		events := r.games[id]
+
+		if events.Finished() {
+			continue
+		}
+
+		running = append(running, RunningGame{id, events.Started(), events.Subscribers(), events.Events()})
+	}
+
+	return running
+}
+
+//SavedGame is a game that was interrupted before it finished, kept so its log survives a restart.
+//Tournament is the standing of the game when it was a tournament and nil otherwise
+type SavedGame struct {
+	ID         string
+	Events     []GameEvent
+	Tournament *TournamentState
+}
+
+//Interrupt finishes every running game with the given result and returns them
+func (r *GameRegistry) Interrupt(result string) []SavedGame {
+	r.mx.Lock()
+	defer r.mx.Unlock()
+
+	var interrupted []SavedGame
+
+	for _, id := range r.order {
+		events := r.games[id]
+
+		if events.Finished() {
+			continue
+		}
+
+		saved := SavedGame{ID: id}
+
+		if tournament, ok := events.Game().(*Tournament); ok {
+			state := tournament.State()
+			saved.Tournament = &state
+		}
+
+		events.Finish(result)
+		saved.Events = events.Events()
+		interrupted = append(interrupted, saved)
+	}
+
+	return interrupted
+}
+
+//Restore adds finished games saved by Interrupt. New games get ids after the restored ones
+func (r *GameRegistry) Restore(games []SavedGame) {
+	r.mx.Lock()
+	defer r.mx.Unlock()
+
+	for _, game := range games {
+		if _, exists := r.games[game.ID]; exists {
+			continue
+		}
+
+		events := NewGameEvents()
+		events.events = game.Events
+		events.started = true
+		events.finished = true
+
+		r.games[game.ID] = events
+		r.order = append(r.order, game.ID)
+
+		if id, err := strconv.Atoi(game.ID); err == nil && id > r.lastID {
+			r.lastID = id
+		}
+	}
+
+	r.prune()
+}
+
+//IDs returns the ids of every known game from the oldest to the newest
+func (r *GameRegistry) IDs() []string {
+	r.mx.Lock()
+	defer r.mx.Unlock()
+
+	return append([]string{}, r.order...)
+}
+
+func (r *GameRegistry) prune() {
+	finished := 0
+
+	for i := len(r.order) - 1; i >= 0; i-- {
+		id := r.order[i]
+
+		if !r.games[id].Finished() {
+			continue
+		}
+
+		finished++
+
+		if finished > maxFinishedGames {
+			delete(r.tokens, r.games[id].token)
+			delete(r.games, id)
+			r.order = append(r.order[:i], r.order[i+1:]...)
+		}
+	}
+}
+
+func newGameToken() string {
+	token := make([]byte, gameTokenBytes)
+
+	if _, err := rand.Read(token); err != nil {
+		panic(fmt.Sprintf("Could not generate a game token %v", err))
+	}
+
+	return hex.EncodeToString(token)
+}
@@ -0,0 +1,267 @@
+package poker
+
+import (
+	"bufio"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestGameEvents(t *testing.T) {
+	t.Run("Subscribers get missed events and then the live ones", func(t *testing.T) {
+		events := NewGameEvents()
+		events.Write([]byte("Blind is now 100\n"))
+		events.Write([]byte("Blind is now 200\n"))
+
+		backlog, live, cancel := events.Subscribe(1)
+		defer cancel()
+
+		assertGameEvents(t, backlog, []GameEvent{{2, MessageEvent, "Blind is now 200\n"}})
+
+		events.Finish("Chris wins")
+
+		assertGameEvents(t, drain(live), []GameEvent{{3, ResultEvent, "Chris wins"}})
+
+		if !events.Finished() {
+			t.Errorf("Expected the game to be finished")
+		}
+	})
+
+	t.Run("Subscribing to a finished game returns a closed channel", func(t *testing.T) {
+		events := NewGameEvents()
+		events.Finish("Chris wins")
+
+		backlog, live, _ := events.Subscribe(0)
+
+		assertGameEvents(t, backlog, []GameEvent{{1, ResultEvent, "Chris wins"}})
+		assertGameEvents(t, drain(live), nil)
+	})
+
+	t.Run("Subscribers that fall behind are disconnected", func(t *testing.T) {
+		events := NewGameEvents()
+		_, live, _ := events.Subscribe(0)
+
+		for i := 0; i <= subscriberBuffer; i++ {
+			events.Write([]byte("Blind is now 100\n"))
+		}
+
+		if got := len(drain(live)); got != subscriberBuffer {
+			t.Errorf("Expected %d buffered events before disconnecting but got %d", subscriberBuffer, got)
+		}
+	})
+}
+
+func TestGameRegistry(t *testing.T) {
+	registry := NewGameRegistry()
+	var oldestToken string
+
+	for i := 0; i < maxFinishedGames+2; i++ {
+		id, events := registry.New()
+		events.Finish("")
+
+		if i == 0 {
+			oldestToken = registry.Token(id)
+		}
+	}
+
+	running, _ := registry.New()
+
+	ids := registry.IDs()
+
+	if len(ids) != maxFinishedGames+1 || ids[len(ids)-1] != running {
+		t.Errorf("Expected only the last %d finished games and the running one but got %v", maxFinishedGames, ids)
+	}
+
+	if registry.Get("1") != nil {
+		t.Errorf("Expected the oldest finished game to be removed")
+	}
+
+	token := registry.Token(running)
+	id, events := registry.Lookup(token)
+
+	if token == "" || id != running || events != registry.Get(running) {
+		t.Errorf("Expected token %q to find game %s but got %s", token, running, id)
+	}
+
+	if _, events := registry.Lookup(oldestToken); events != nil || len(registry.tokens) != len(ids) {
+		t.Errorf("Expected the token of a removed game to be forgotten but got %d tokens", len(registry.tokens))
+	}
+}
+
+func TestGameRegistryInterrupt(t *testing.T) {
+	registry := NewGameRegistry()
+
+	_, finished := registry.New()
+	finished.Finish("Chris wins")
+
+	running, events := registry.New()
+	events.Write([]byte("Blind is 100"))
+
+	interrupted := registry.Interrupt("Server shutting down")
+
+	if len(interrupted) != 1 || interrupted[0].ID != running {
+		t.Fatalf("Expected only game %s to be interrupted but got %+v", running, interrupted)
+	}
+
+	assertGameEvents(t, interrupted[0].Events, []GameEvent{
+		{1, MessageEvent, "Blind is 100"},
+		{2, ResultEvent, "Server shutting down"},
+	})
+
+	restored := NewGameRegistry()
+	restored.Restore(interrupted)
+
+	if events := restored.Get(running); events == nil || !events.Finished() {
+		t.Fatalf("Expected game %s to be restored as finished", running)
+	}
+
+	if id, _ := restored.New(); id != "3" {
+		t.Errorf("Expected new games to get ids after the restored ones but got %s", id)
+	}
+}
+
+func TestGameRegistryInterruptTournament(t *testing.T) {
+	registry := NewGameRegistry()
+	tournament := NewTournament(&StubPlayerStore{}, BlindAlerterFunc(func(time.Duration, int, io.Writer) {}),
+		TournamentOptions{BuyIn: 10, Rebuy: 5})
+
+	_, events := registry.New()
+	events.MarkStarted(tournament)
+	tournament.Start(4, events)
+	AssertNoError(t, tournament.Eliminate("Kiro"))
+	AssertNoError(t, tournament.Eliminate("Ruth"))
+	AssertNoError(t, tournament.Rebuy("Kiro"))
+
+	interrupted := registry.Interrupt("Server shutting down")
+	want := &TournamentState{Players: 4, Rebuys: 1, PrizePool: 45, Eliminated: []string{"Ruth"}}
+
+	if len(interrupted) != 1 || !reflect.DeepEqual(interrupted[0].Tournament, want) {
+		t.Errorf("Expected the standing %+v to be saved but got %+v", want, interrupted)
+	}
+}
+
+func TestGameEventsStream(t *testing.T) {
+	game := &SpyGame{BlindAlert: []byte("Blind is now 100\n")}
+	playerServer := CreateNewPlayerServer(t, &StubPlayerStore{}, game)
+	server := httptest.NewServer(playerServer)
+	defer server.Close()
+
+	ws, wsResp, err := websocketDialer(server.URL)
+	if err != nil {
+		t.Fatalf("could not open a ws connection %v", err)
+	}
+	defer ws.Close()
+
+	id := wsResp.Header.Get(gameIDHeader)
+	sendWebSocketMessage(t, ws, "5")
+	within(t, tenMS, func() { assertWebsocketGotMsg(t, ws, "Blind is now 100\n") })
+
+	t.Run("Events of the game are streamed", func(t *testing.T) {
+		stream := openEventStream(t, server.URL+"/games/"+id+"/events", "")
+		defer stream.Body.Close()
+
+		if stream.Header.Get("content-type") != eventStreamContentType {
+			t.Errorf("Expected content-type %s but got %s", eventStreamContentType, stream.Header.Get("content-type"))
+		}
+
+		reader := bufio.NewReader(stream.Body)
+		assertServerSentEvent(t, reader, "id: 1\nevent: message\ndata: Blind is now 100\n\n")
+
+		sendWebSocketMessage(t, ws, "Chris")
+		assertServerSentEvent(t, reader, "id: 2\nevent: result\ndata: Chris wins\n\n")
+	})
+
+	t.Run("Clients resume after the Last-Event-ID", func(t *testing.T) {
+		stream := openEventStream(t, server.URL+"/games/"+id+"/events", "1")
+		defer stream.Body.Close()
+
+		assertServerSentEvent(t, bufio.NewReader(stream.Body), "id: 2\nevent: result\ndata: Chris wins\n\n")
+	})
+
+	t.Run("Unknown games are not found", func(t *testing.T) {
+		response := httptest.NewRecorder()
+		playerServer.ServeHTTP(response, httptest.NewRequest(http.MethodGet, "/games/404/events", nil))
+
+		AssertStatusCode(t, response.Code, http.StatusNotFound)
+	})
+
+	t.Run("Games are listed", func(t *testing.T) {
+		response := httptest.NewRecorder()
+		playerServer.ServeHTTP(response, httptest.NewRequest(http.MethodGet, "/games/", nil))
+
+		AssertJSONContentType(t, response)
+		AssertResponseBody(t, response.Body.String(), `[{"ID":"`+id+`","Finished":true}]`+"\n")
+	})
+}
+
+func websocketDialer(serverURL string) (*websocket.Conn, *http.Response, error) {
+	return websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(serverURL, "http")+"/ws/", nil)
+}
+
+func openEventStream(t *testing.T, url, lastEventID string) *http.Response {
+	t.Helper()
+
+	request, _ := http.NewRequest(http.MethodGet, url, nil)
+
+	if lastEventID != "" {
+		request.Header.Set("Last-Event-ID", lastEventID)
+	}
+
+	resp, err := http.DefaultClient.Do(request)
+
+	if err != nil {
+		t.Fatalf("could not open event stream %v", err)
+	}
+
+	return resp
+}
+
+func assertServerSentEvent(t *testing.T, reader *bufio.Reader, want string) {
+	t.Helper()
+
+	got := ""
+
+	within(t, time.Second, func() {
+		for !strings.HasSuffix(got, "\n\n") {
+			line, err := reader.ReadString('\n')
+
+			if err != nil {
+				return
+			}
+
+			got += line
+		}
+	})
+
+	AssertResponseBody(t, got, want)
+}
+
+func assertGameEvents(t *testing.T, got, want []GameEvent) {
+	t.Helper()
+
+	if len(got) != len(want) {
+		t.Fatalf("got events %v want %v", got, want)
+	}
+
+	for i := range got {
+		if got[i] != want[i] {
+			t.Errorf("got event %v want %v", got[i], want[i])
+		}
+	}
+}
+
+func drain(live <-chan GameEvent) []GameEvent {
+	var got []GameEvent
+
+	for event := range live {
+		got = append(got, event)
+	}
+
+	return got
+}
@@ -16,7 +16,8 @@ func GenerateFileSystemPlayerStore(dbFileName string) (*FileSystemPlayerStore, f
 	store, err := NewFileSystemPlayerStore(file)
 
 	if err != nil {
-		return nil, nil, fmt.Errorf("Could not create File System player store %v", err)
+		file.Close()
+		return nil, nil, fmt.Errorf("Could not create File System player store %w", err)
 	}
 
 	closeFunc := func() {
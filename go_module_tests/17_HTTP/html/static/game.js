@@ -0,0 +1,142 @@
+const startGame = document.getElementById('game-start')
+const gameConsole = document.getElementById('console')
+
+const playerInput = document.getElementById('winner')
+const submitWinnerButton = document.getElementById('winner-button')
+const eliminateButton = document.getElementById('eliminate-button')
+const rebuyButton = document.getElementById('rebuy-button')
+
+const levelContainer = document.getElementById('level')
+const blindContainer = document.getElementById('blind-value')
+const countdownContainer = document.getElementById('countdown')
+const gameLog = document.getElementById('game-log')
+
+const gameContainer = document.getElementById('game')
+const gameEndContainer = document.getElementById('game-end')
+const resultContainer = document.getElementById('result')
+
+//Close code sent by the server when it shuts down
+const serviceRestart = 1012
+
+const remainingPattern = /(?:(\d+)h)?(?:(\d+)m)?(?:([\d.]+)s)? remaining/
+
+let levelEndsAt = null
+
+gameConsole.hidden = true
+gameEndContainer.hidden = true
+
+//showAnnouncement updates the blind clock from messages like
+//"Level 3 - Blind is now 300 - 12m0s remaining - 4000 chips in play, average stack 1000"
+const showAnnouncement = message => {
+    const parts = message.trim().split(' - ')
+    const level = parts.find(part => part.startsWith('Level ') || part.startsWith('Break '))
+    const blind = parts.find(part => part.startsWith('Blind is now '))
+    const remaining = message.match(remainingPattern)
+
+    if (level) {
+        levelContainer.innerText = level
+    }
+
+    if (blind) {
+        blindContainer.innerText = blind
+    }
+
+    if (remaining && remaining[0] !== ' remaining') {
+        const seconds = (Number(remaining[1] || 0) * 60 + Number(remaining[2] || 0)) * 60 + Number(remaining[3] || 0)
+        levelEndsAt = Date.now() + seconds * 1000
+    }
+}
+
+const showCountdown = () => {
+    if (levelEndsAt === null) {
+        return
+    }
+
+    const seconds = Math.max(0, Math.round((levelEndsAt - Date.now()) / 1000))
+    const minutes = Math.floor(seconds / 60)
+
+    countdownContainer.innerText = minutes + ':' + String(seconds % 60).padStart(2, '0')
+}
+
+const logMessage = message => {
+    const entry = document.createElement('li')
+    entry.innerText = message
+    gameLog.prepend(entry)
+}
+
+setInterval(showCountdown, 1000)
+
+document.getElementById('start-game').addEventListener('click', event => {
+    startGame.hidden = true
+    gameConsole.hidden = false
+
+    const numberOfPlayers = document.getElementById('player-count').value
+
+    if (window['WebSocket']) {
+        let received = 0
+        let finished = false
+        let conn
+
+        const gameToken = () => {
+            const cookie = document.cookie.split('; ').find(c => c.startsWith('poker-game-token='))
+            return cookie ? cookie.split('=')[1] : ''
+        }
+
+        const connect = resume => {
+            const query = resume ? '?token=' + gameToken() + '&last=' + received : ''
+            const scheme = document.location.protocol === 'https:' ? 'wss://' : 'ws://'
+            conn = new WebSocket(scheme + document.location.host + '/ws/' + query)
+
+            conn.onclose = evt => {
+                if (finished) {
+                    return
+                }
+
+                if (evt.code === serviceRestart) {
+                    finished = true
+                    logMessage(evt.reason)
+                    return
+                }
+
+                logMessage('Connection lost, reconnecting...')
+                setTimeout(() => connect(true), 2000)
+            }
+
+            conn.onmessage = evt => {
+                received++
+                logMessage(evt.data)
+                showAnnouncement(evt.data)
+
+                if (evt.data.endsWith(' wins') && finished) {
+                    resultContainer.innerText = evt.data
+                }
+            }
+
+            conn.onopen = function () {
+                if (!resume) {
+                    conn.send(numberOfPlayers)
+                }
+            }
+        }
+
+        const sendAction = suffix => {
+            if (playerInput.value !== '') {
+                conn.send(playerInput.value + suffix)
+                playerInput.value = ''
+            }
+        }
+
+        eliminateButton.onclick = event => sendAction(' is out')
+        rebuyButton.onclick = event => sendAction(' rebuys')
+
+        submitWinnerButton.onclick = event => {
+            finished = true
+            resultContainer.innerText = playerInput.value + ' wins'
+            sendAction(' wins')
+            gameEndContainer.hidden = false
+            gameContainer.hidden = true
+        }
+
+        connect(false)
+    }
+})
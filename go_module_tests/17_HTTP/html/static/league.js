@@ -0,0 +1,17 @@
+//Refreshes the rows of the league table so results show up without reloading the page
+const league = document.getElementById('league')
+
+const refreshLeague = () => {
+    fetch(document.location.href, {headers: {'Accept': 'text/html'}})
+        .then(response => response.text())
+        .then(page => {
+            const fresh = new DOMParser().parseFromString(page, 'text/html').querySelector('#league tbody')
+
+            if (fresh) {
+                league.querySelector('tbody').replaceWith(fresh)
+            }
+        })
+        .catch(() => {})
+}
+
+setInterval(refreshLeague, Number(league.dataset.refresh))
@@ -1,24 +1,136 @@
 package poker
 
 import (
+	"bufio"
+	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 )
 
+//LeagueSchemaVersion is the version of the league file format written by this binary.
+//Version 1 is the legacy format where the file holds a bare []Player, version 2 wraps the players
+//in an envelope and version 3 adds the history of played games
+const LeagueSchemaVersion int = 3
+
+//ErrUnsupportedLeagueVersion is returned when a league file is newer than this binary understands
+var ErrUnsupportedLeagueVersion = errors.New("League file version is not supported")
+
 //League is an assortment of players
 type League []Player
 
+//LeagueData is everything stored in a league file
+type LeagueData struct {
+	Players League
+	Games   []GameResult
+}
+
+//leagueFile is the versioned envelope the league is stored in
+type leagueFile struct {
+	Version int             `json:"version"`
+	Players json.RawMessage `json:"players"`
+	Games   []GameResult    `json:"games,omitempty"`
+}
+
+//leagueMigrations upgrade the players of a league file from the version they are keyed by
+//to the next one. Versions without an entry need no changes to the players
+var leagueMigrations = map[int]func(players json.RawMessage) (json.RawMessage, error){}
+
 //NewLeague parses the read league into a []Player object
 func NewLeague(read io.Reader) ([]Player, error) {
-	var got []Player
-	err := json.NewDecoder(read).Decode(&got)
+	got, _, err := DecodeLeague(read)
+
+	return got, err
+}
+
+//DecodeLeague parses the players of a league in any known format.
+//It returns the version the league was stored in
+func DecodeLeague(read io.Reader) (League, int, error) {
+	data, version, err := DecodeLeagueData(read)
+
+	return data.Players, version, err
+}
+
+//DecodeLeagueData parses a league file in any known format and upgrades it to the current one.
+//It returns the version the league was stored in
+func DecodeLeagueData(read io.Reader) (LeagueData, int, error) {
+	file, err := decodeLeagueFile(read)
 
 	if err != nil {
-		err = fmt.Errorf("Unable to parse response from server %q into slice of Player, '%v'", read, err)
+		return LeagueData{}, 0, fmt.Errorf("Unable to parse response from server %q into slice of Player, '%v'", read, err)
 	}
 
-	return got, err
+	if file.Version > LeagueSchemaVersion {
+		return LeagueData{}, file.Version, fmt.Errorf("%w: got version %d but the newest known is %d",
+			ErrUnsupportedLeagueVersion, file.Version, LeagueSchemaVersion)
+	}
+
+	players := file.Players
+
+	for version := file.Version; version < LeagueSchemaVersion; version++ {
+		migrate, ok := leagueMigrations[version]
+
+		if !ok {
+			continue
+		}
+
+		players, err = migrate(players)
+
+		if err != nil {
+			return LeagueData{}, file.Version, fmt.Errorf("Unable to migrate league from version %d %v", version, err)
+		}
+	}
+
+	var got League
+	err = json.Unmarshal(players, &got)
+
+	if err != nil {
+		err = fmt.Errorf("Unable to parse players of league version %d into slice of Player, '%v'", file.Version, err)
+	}
+
+	return LeagueData{got, file.Games}, file.Version, err
+}
+
+func decodeLeagueFile(read io.Reader) (leagueFile, error) {
+	var raw json.RawMessage
+	err := json.NewDecoder(bufio.NewReader(read)).Decode(&raw)
+
+	if err != nil {
+		return leagueFile{}, err
+	}
+
+	if trimmed := bytes.TrimSpace(raw); len(trimmed) > 0 && trimmed[0] == '[' {
+		return leagueFile{Version: 1, Players: raw}, nil
+	}
+
+	var file leagueFile
+	err = json.Unmarshal(raw, &file)
+
+	if err == nil && file.Version < 1 {
+		err = fmt.Errorf("league file is missing a version")
+	}
+
+	if err == nil && file.Players == nil {
+		file.Players = json.RawMessage("[]")
+	}
+
+	return file, err
+}
+
+//EncodeLeagueData writes the league in the current versioned format
+func EncodeLeagueData(to io.Writer, data LeagueData) error {
+	if data.Players == nil {
+		data.Players = League{}
+	}
+
+	players, err := json.Marshal(data.Players)
+
+	if err != nil {
+		return err
+	}
+
+	return json.NewEncoder(to).Encode(leagueFile{LeagueSchemaVersion, players, data.Games})
 }
 
 //Find is used to find the player entry throug a name
@@ -31,3 +143,14 @@ func (l League) Find(name string) *Player {
 
 	return nil
 }
+
+//remove returns the league without the player with the given name
+func (l League) remove(name string) League {
+	for index, player := range l {
+		if player.Name == name {
+			return append(l[:index], l[index+1:]...)
+		}
+	}
+
+	return l
+}
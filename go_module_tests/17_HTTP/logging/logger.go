@@ -0,0 +1,236 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+//Level is the severity of a log entry
+type Level int
+
+//Levels from the most to the least verbose
+const (
+	DebugLevel Level = iota
+	InfoLevel
+	WarnLevel
+	ErrorLevel
+)
+
+var levelNames = map[Level]string{
+	DebugLevel: "debug",
+	InfoLevel:  "info",
+	WarnLevel:  "warn",
+	ErrorLevel: "error",
+}
+
+func (l Level) String() string {
+	return levelNames[l]
+}
+
+//ParseLevel converts the name of a level like "info" into a Level. An empty name is InfoLevel
+func ParseLevel(name string) (Level, error) {
+	if name == "" {
+		return InfoLevel, nil
+	}
+
+	for level, levelName := range levelNames {
+		if strings.EqualFold(name, levelName) {
+			return level, nil
+		}
+	}
+
+	return InfoLevel, fmt.Errorf("Unknown log level %q", name)
+}
+
+type field struct {
+	key   string
+	value interface{}
+}
+
+//Logger writes entries as JSON lines. Entries below its level are dropped. Loggers created by With
+//share the level of their parent
+type Logger struct {
+	out    io.Writer
+	level  *int32
+	fields []field
+	now    func() time.Time
+	mx     *sync.Mutex
+}
+
+//New creates a Logger that writes entries of at least the given level to out
+func New(out io.Writer, level Level) *Logger {
+	current := int32(level)
+
+	return &Logger{out: out, level: &current, now: time.Now, mx: &sync.Mutex{}}
+}
+
+var (
+	defaultLogger   = New(os.Stderr, InfoLevel)
+	defaultLoggerMx sync.RWMutex
+)
+
+//Default returns the logger used by code that has no logger of its own
+func Default() *Logger {
+	defaultLoggerMx.RLock()
+	defer defaultLoggerMx.RUnlock()
+
+	return defaultLogger
+}
+
+//SetDefault replaces the logger returned by Default
+func SetDefault(logger *Logger) {
+	defaultLoggerMx.Lock()
+	defer defaultLoggerMx.Unlock()
+
+	defaultLogger = logger
+}
+
+//With returns a logger that adds the given key and value to every entry
+func (l *Logger) With(key string, value interface{}) *Logger {
+	child := *l
+	child.fields = append(append([]field{}, l.fields...), field{key, value})
+
+	return &child
+}
+
+//Enabled tells if entries of the given level are written
+func (l *Logger) Enabled(level Level) bool {
+	return level >= l.Level()
+}
+
+//Level returns the lowest level of the entries that are written
+func (l *Logger) Level() Level {
+	return Level(atomic.LoadInt32(l.level))
+}
+
+//SetLevel changes the lowest level of the entries that are written while the logger is in use
+func (l *Logger) SetLevel(level Level) {
+	atomic.StoreInt32(l.level, int32(level))
+}
+
+//Debug writes an entry useful when looking into a problem. keyvals are pairs of keys and values
+func (l *Logger) Debug(msg string, keyvals ...interface{}) {
+	l.log(DebugLevel, msg, keyvals)
+}
+
+//Info writes an entry about normal operation
+func (l *Logger) Info(msg string, keyvals ...interface{}) {
+	l.log(InfoLevel, msg, keyvals)
+}
+
+//Warn writes an entry about something unexpected that the server recovered from
+func (l *Logger) Warn(msg string, keyvals ...interface{}) {
+	l.log(WarnLevel, msg, keyvals)
+}
+
+//Error writes an entry about a failure
+func (l *Logger) Error(msg string, keyvals ...interface{}) {
+	l.log(ErrorLevel, msg, keyvals)
+}
+
+func (l *Logger) log(level Level, msg string, keyvals []interface{}) {
+	if !l.Enabled(level) {
+		return
+	}
+
+	fields := append([]field{
+		{"time", l.now().UTC().Format(time.RFC3339Nano)},
+		{"level", level.String()},
+		{"msg", msg},
+	}, l.fields...)
+
+	for i := 0; i < len(keyvals); i += 2 {
+		key := fmt.Sprint(keyvals[i])
+		var value interface{} = "MISSING"
+
+		if i+1 < len(keyvals) {
+			value = keyvals[i+1]
+		}
+
+		fields = append(fields, field{key, value})
+	}
+
+	line := encodeFields(fields)
+
+	l.mx.Lock()
+	defer l.mx.Unlock()
+
+	l.out.Write(line)
+}
+
+//encodeFields writes the fields as a JSON object keeping their order
+func encodeFields(fields []field) []byte {
+	buffer := &bytes.Buffer{}
+	buffer.WriteByte('{')
+
+	for i, f := range fields {
+		if i > 0 {
+			buffer.WriteByte(',')
+		}
+
+		key, _ := json.Marshal(f.key)
+		buffer.Write(key)
+		buffer.WriteByte(':')
+		buffer.Write(encodeValue(f.value))
+	}
+
+	buffer.WriteString("}\n")
+
+	return buffer.Bytes()
+}
+
+func encodeValue(value interface{}) []byte {
+	switch v := value.(type) {
+	case error:
+		value = v.Error()
+	case time.Duration:
+		value = v.String()
+	case fmt.Stringer:
+		value = v.String()
+	}
+
+	encoded, err := json.Marshal(value)
+
+	if err != nil {
+		encoded, _ = json.Marshal(fmt.Sprint(value))
+	}
+
+	return encoded
+}
+
+type loggerKey struct{}
+
+//NewContext returns a context that carries the logger
+func NewContext(ctx context.Context, logger *Logger) context.Context {
+	return context.WithValue(ctx, loggerKey{}, logger)
+}
+
+//FromContext returns the logger of the context or the default logger if it has none
+func FromContext(ctx context.Context) *Logger {
+	if logger, ok := ctx.Value(loggerKey{}).(*Logger); ok {
+		return logger
+	}
+
+	return Default()
+}
+
+type requestIDKey struct{}
+
+//WithRequestID returns a context that carries the id of the request being served
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+//RequestID returns the id of the request carried by ctx or an empty string
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
@@ -0,0 +1,195 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+var testTime = time.Date(2021, time.March, 14, 12, 0, 0, 0, time.UTC)
+
+func TestLogger(t *testing.T) {
+	t.Run("Entries are JSON lines with the time, level and message first", func(t *testing.T) {
+		buffer := &bytes.Buffer{}
+		logger := newTestLogger(buffer, DebugLevel).With("requestId", "abc")
+
+		logger.Info("Request served", "status", 200, "duration", 1500*time.Millisecond, "error", errors.New("boom"))
+
+		want := `{"time":"2021-03-14T12:00:00Z","level":"info","msg":"Request served","requestId":"abc",` +
+			`"status":200,"duration":"1.5s","error":"boom"}` + "\n"
+
+		if buffer.String() != want {
+			t.Errorf("got %s want %s", buffer.String(), want)
+		}
+	})
+
+	t.Run("Entries below the level are dropped", func(t *testing.T) {
+		buffer := &bytes.Buffer{}
+		logger := newTestLogger(buffer, WarnLevel)
+
+		logger.Debug("debug")
+		logger.Info("info")
+		logger.Warn("warn")
+		logger.Error("error")
+
+		lines := strings.Split(strings.TrimSpace(buffer.String()), "\n")
+
+		if len(lines) != 2 || !strings.Contains(lines[0], `"warn"`) || !strings.Contains(lines[1], `"error"`) {
+			t.Errorf("Expected only the warning and the error but got %v", lines)
+		}
+	})
+
+	t.Run("With does not change the parent logger", func(t *testing.T) {
+		buffer := &bytes.Buffer{}
+		logger := newTestLogger(buffer, InfoLevel)
+		logger.With("gameId", "1")
+
+		logger.Info("Game started")
+
+		if strings.Contains(buffer.String(), "gameId") {
+			t.Errorf("Unexpected field in %s", buffer.String())
+		}
+	})
+
+	t.Run("Changing the level applies to the loggers created by With", func(t *testing.T) {
+		buffer := &bytes.Buffer{}
+		logger := newTestLogger(buffer, InfoLevel)
+		child := logger.With("gameId", "1")
+
+		logger.SetLevel(DebugLevel)
+		child.Debug("Game started")
+
+		if !strings.Contains(buffer.String(), "Game started") {
+			t.Errorf("Expected the debug entry of the child logger but got %q", buffer.String())
+		}
+	})
+
+	t.Run("Levels are parsed by name", func(t *testing.T) {
+		cases := map[string]Level{"": InfoLevel, "debug": DebugLevel, "WARN": WarnLevel, "error": ErrorLevel}
+
+		for name, want := range cases {
+			got, err := ParseLevel(name)
+
+			if err != nil || got != want {
+				t.Errorf("ParseLevel(%q) got %v %v want %v", name, got, err, want)
+			}
+		}
+
+		if _, err := ParseLevel("verbose"); err == nil {
+			t.Errorf("Expected an error for an unknown level")
+		}
+	})
+
+	t.Run("Contexts carry the logger and the request id", func(t *testing.T) {
+		logger := New(&bytes.Buffer{}, InfoLevel)
+		ctx := WithRequestID(NewContext(context.Background(), logger), "abc")
+
+		if FromContext(ctx) != logger {
+			t.Errorf("Expected the logger of the context")
+		}
+
+		if FromContext(context.Background()) != Default() {
+			t.Errorf("Expected the default logger without one in the context")
+		}
+
+		if RequestID(ctx) != "abc" {
+			t.Errorf("got request id %q want %q", RequestID(ctx), "abc")
+		}
+	})
+}
+
+func TestTracer(t *testing.T) {
+	t.Run("Child spans share the trace of their parent", func(t *testing.T) {
+		buffer := &bytes.Buffer{}
+		tracer := NewTracer(buffer, "poker")
+
+		ctx, parent := tracer.Start(context.Background(), "HTTP GET")
+		_, child := StartSpan(ctx, "store.GetLeague")
+		child.SetAttribute("players", 3)
+		child.End()
+		parent.SetError(errors.New("boom"))
+		parent.End()
+		parent.End()
+
+		spans := readSpans(t, buffer)
+
+		if len(spans) != 2 {
+			t.Fatalf("Expected 2 exported spans but got %d", len(spans))
+		}
+
+		if spans[0].Name != "store.GetLeague" || spans[0].TraceID != spans[1].TraceID ||
+			spans[0].ParentSpanID != spans[1].SpanID {
+			t.Errorf("Expected the store span to be a child of the request span %+v", spans)
+		}
+
+		if spans[0].Attributes["players"] != float64(3) || spans[0].Resource["service.name"] != "poker" {
+			t.Errorf("Unexpected attributes or resource %+v", spans[0])
+		}
+
+		if spans[1].Status.Code != "ERROR" || spans[1].Status.Message != "boom" {
+			t.Errorf("Expected the error status but got %+v", spans[1].Status)
+		}
+	})
+
+	t.Run("Traces continue from a traceparent header", func(t *testing.T) {
+		buffer := &bytes.Buffer{}
+		header := "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+
+		ctx := ContextWithTraceParent(context.Background(), header)
+		_, span := NewTracer(buffer, "poker").Start(ctx, "HTTP GET")
+
+		if span.TraceID != "4bf92f3577b34da6a3ce929d0e0e4736" || span.ParentSpanID != "00f067aa0ba902b7" {
+			t.Errorf("Expected the span to continue the trace of %s but got %+v", header, span)
+		}
+
+		if !strings.HasPrefix(span.TraceParent(), "00-4bf92f3577b34da6a3ce929d0e0e4736-"+span.SpanID) {
+			t.Errorf("Unexpected traceparent %s", span.TraceParent())
+		}
+
+		if _, remote := StartSpan(ctx, "store.GetLeague"); remote != nil {
+			t.Errorf("Expected no span without a local tracer")
+		}
+	})
+
+	t.Run("A nil tracer records nothing", func(t *testing.T) {
+		var tracer *Tracer
+
+		_, span := tracer.Start(context.Background(), "HTTP GET")
+		span.SetAttribute("http.method", "GET")
+		span.End()
+
+		if span != nil {
+			t.Errorf("Expected no span")
+		}
+	})
+}
+
+func newTestLogger(buffer *bytes.Buffer, level Level) *Logger {
+	logger := New(buffer, level)
+	logger.now = func() time.Time { return testTime }
+
+	return logger
+}
+
+func readSpans(t *testing.T, buffer *bytes.Buffer) []exportedSpan {
+	t.Helper()
+
+	var spans []exportedSpan
+	decoder := json.NewDecoder(buffer)
+
+	for decoder.More() {
+		var span exportedSpan
+
+		if err := decoder.Decode(&span); err != nil {
+			t.Fatalf("Could not decode span %v", err)
+		}
+
+		spans = append(spans, span)
+	}
+
+	return spans
+}
@@ -0,0 +1,217 @@
+package logging
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"strings"
+	"sync"
+	"time"
+)
+
+//TraceParentHeader is the W3C trace context header that links spans across services
+const TraceParentHeader string = "traceparent"
+
+//Tracer records spans and writes them as JSON lines when they end. The fields follow the
+//OpenTelemetry span model so the output can be loaded by tools that read OTLP JSON.
+//A nil *Tracer records nothing
+type Tracer struct {
+	out     io.Writer
+	service string
+	now     func() time.Time
+	mx      sync.Mutex
+}
+
+//NewTracer creates a Tracer that exports the spans of the given service to out
+func NewTracer(out io.Writer, service string) *Tracer {
+	return &Tracer{out: out, service: service, now: time.Now}
+}
+
+//Span is a timed operation that is part of a trace
+type Span struct {
+	TraceID      string
+	SpanID       string
+	ParentSpanID string
+	Name         string
+
+	tracer     *Tracer
+	start      time.Time
+	attributes map[string]interface{}
+	err        error
+	ended      bool
+	mx         sync.Mutex
+}
+
+//exportedSpan is the JSON form of a finished span
+type exportedSpan struct {
+	TraceID           string                 `json:"traceId"`
+	SpanID            string                 `json:"spanId"`
+	ParentSpanID      string                 `json:"parentSpanId,omitempty"`
+	Name              string                 `json:"name"`
+	StartTimeUnixNano int64                  `json:"startTimeUnixNano"`
+	EndTimeUnixNano   int64                  `json:"endTimeUnixNano"`
+	Attributes        map[string]interface{} `json:"attributes,omitempty"`
+	Status            exportedStatus         `json:"status"`
+	Resource          map[string]string      `json:"resource"`
+}
+
+type exportedStatus struct {
+	Code    string `json:"code"`
+	Message string `json:"message,omitempty"`
+}
+
+type spanKey struct{}
+
+//Start begins a span that is a child of the span in ctx and returns a context that carries it
+func (t *Tracer) Start(ctx context.Context, name string) (context.Context, *Span) {
+	if t == nil {
+		return ctx, nil
+	}
+
+	span := &Span{
+		SpanID:     newID(8),
+		Name:       name,
+		tracer:     t,
+		start:      t.now(),
+		attributes: map[string]interface{}{},
+	}
+
+	if parent := SpanFromContext(ctx); parent != nil {
+		span.TraceID, span.ParentSpanID = parent.TraceID, parent.SpanID
+	} else {
+		span.TraceID = newID(16)
+	}
+
+	return context.WithValue(ctx, spanKey{}, span), span
+}
+
+//StartSpan begins a child of the span in ctx using the tracer that started it.
+//Nothing is recorded when ctx has no span
+func StartSpan(ctx context.Context, name string) (context.Context, *Span) {
+	parent := SpanFromContext(ctx)
+
+	if parent == nil || parent.tracer == nil {
+		return ctx, nil
+	}
+
+	return parent.tracer.Start(ctx, name)
+}
+
+//SpanFromContext returns the span carried by ctx or nil
+func SpanFromContext(ctx context.Context) *Span {
+	span, _ := ctx.Value(spanKey{}).(*Span)
+	return span
+}
+
+//ContextWithTraceParent makes spans started from the returned context children of the span
+//described by a W3C traceparent header. Invalid headers are ignored
+func ContextWithTraceParent(ctx context.Context, header string) context.Context {
+	parts := strings.Split(header, "-")
+
+	if len(parts) != 4 || !isHex(parts[1], 32) || !isHex(parts[2], 16) {
+		return ctx
+	}
+
+	return context.WithValue(ctx, spanKey{}, &Span{TraceID: parts[1], SpanID: parts[2], ended: true})
+}
+
+//TraceParent returns the W3C traceparent header that makes other services continue the trace
+func (s *Span) TraceParent() string {
+	if s == nil {
+		return ""
+	}
+
+	return "00-" + s.TraceID + "-" + s.SpanID + "-01"
+}
+
+//SetAttribute records a key and value describing the operation
+func (s *Span) SetAttribute(key string, value interface{}) {
+	if s == nil {
+		return
+	}
+
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	s.attributes[key] = value
+}
+
+//SetError marks the operation as failed
+func (s *Span) SetError(err error) {
+	if s == nil || err == nil {
+		return
+	}
+
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	s.err = err
+}
+
+//End finishes the span and exports it. Only the first call has an effect
+func (s *Span) End() {
+	if s == nil {
+		return
+	}
+
+	s.mx.Lock()
+
+	if s.ended {
+		s.mx.Unlock()
+		return
+	}
+
+	s.ended = true
+
+	exported := exportedSpan{
+		TraceID:           s.TraceID,
+		SpanID:            s.SpanID,
+		ParentSpanID:      s.ParentSpanID,
+		Name:              s.Name,
+		StartTimeUnixNano: s.start.UnixNano(),
+		EndTimeUnixNano:   s.tracer.now().UnixNano(),
+		Attributes:        s.attributes,
+		Status:            exportedStatus{Code: "OK"},
+		Resource:          map[string]string{"service.name": s.tracer.service},
+	}
+
+	if s.err != nil {
+		exported.Status = exportedStatus{Code: "ERROR", Message: s.err.Error()}
+	}
+
+	line, err := json.Marshal(exported)
+	s.mx.Unlock()
+
+	if err != nil {
+		Default().Error("Could not export span", "span", s.Name, "error", err)
+		return
+	}
+
+	s.tracer.mx.Lock()
+	defer s.tracer.mx.Unlock()
+
+	s.tracer.out.Write(append(line, '\n'))
+}
+
+//NewRequestID returns a random id for a request
+func NewRequestID() string {
+	return newID(8)
+}
+
+func newID(bytes int) string {
+	id := make([]byte, bytes)
+	rand.Read(id)
+
+	return hex.EncodeToString(id)
+}
+
+func isHex(s string, length int) bool {
+	if len(s) != length {
+		return false
+	}
+
+	_, err := hex.DecodeString(s)
+	return err == nil
+}
@@ -0,0 +1,415 @@
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+//ContentType is the content type of the Prometheus text format
+const ContentType string = "text/plain; version=0.0.4; charset=utf-8"
+
+//DefaultBuckets are the upper bounds in seconds used for latency histograms
+var DefaultBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+type metric interface {
+	name() string
+	write(w io.Writer) error
+}
+
+//Registry holds metrics and writes them in the Prometheus text format
+type Registry struct {
+	metrics []metric
+	mx      sync.Mutex
+}
+
+//NewRegistry is a constructor for Registry
+func NewRegistry() *Registry {
+	return &Registry{}
+}
+
+func (r *Registry) register(m metric) {
+	r.mx.Lock()
+	defer r.mx.Unlock()
+
+	for _, registered := range r.metrics {
+		if registered.name() == m.name() {
+			panic(fmt.Sprintf("Metric %s is already registered", m.name()))
+		}
+	}
+
+	r.metrics = append(r.metrics, m)
+}
+
+//Expose writes every metric sorted by name
+func (r *Registry) Expose(w io.Writer) error {
+	r.mx.Lock()
+	metrics := append([]metric{}, r.metrics...)
+	r.mx.Unlock()
+
+	sort.Slice(metrics, func(i, j int) bool { return metrics[i].name() < metrics[j].name() })
+
+	for _, m := range metrics {
+		if err := m.write(w); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+//ServeHTTP exposes the metrics to Prometheus
+func (r *Registry) ServeHTTP(resp http.ResponseWriter, req *http.Request) {
+	resp.Header().Set("content-type", ContentType)
+	r.Expose(resp)
+}
+
+//desc holds what every kind of metric has in common
+type desc struct {
+	metricName string
+	help       string
+	kind       string
+	labels     []string
+}
+
+func (d desc) name() string {
+	return d.metricName
+}
+
+func (d desc) writeHeader(w io.Writer) error {
+	_, err := fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", d.metricName, escapeHelp(d.help), d.metricName, d.kind)
+	return err
+}
+
+//key joins label values so they can be used as a map key. It panics when the number of values
+//does not match the labels of the metric
+func (d desc) key(values []string) string {
+	if len(values) != len(d.labels) {
+		panic(fmt.Sprintf("Metric %s has labels %v but got values %v", d.metricName, d.labels, values))
+	}
+
+	return strings.Join(values, "\xff")
+}
+
+func (d desc) formatLabels(values []string, extra ...string) string {
+	var pairs []string
+
+	for i, label := range d.labels {
+		pairs = append(pairs, fmt.Sprintf(`%s="%s"`, label, escapeLabel(values[i])))
+	}
+
+	for i := 0; i+1 < len(extra); i += 2 {
+		pairs = append(pairs, fmt.Sprintf(`%s="%s"`, extra[i], escapeLabel(extra[i+1])))
+	}
+
+	if len(pairs) == 0 {
+		return ""
+	}
+
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+type sample struct {
+	labels []string
+	value  float64
+}
+
+//vector holds the values of a counter or gauge for every combination of label values
+type vector struct {
+	desc
+	samples map[string]*sample
+	mx      sync.Mutex
+}
+
+func newVector(name, help, kind string, labels []string) *vector {
+	return &vector{desc: desc{name, help, kind, labels}, samples: map[string]*sample{}}
+}
+
+func (v *vector) add(delta float64, values []string) {
+	key := v.key(values)
+
+	v.mx.Lock()
+	defer v.mx.Unlock()
+
+	s, ok := v.samples[key]
+
+	if !ok {
+		s = &sample{labels: append([]string{}, values...)}
+		v.samples[key] = s
+	}
+
+	s.value += delta
+}
+
+func (v *vector) set(value float64, values []string) {
+	key := v.key(values)
+
+	v.mx.Lock()
+	defer v.mx.Unlock()
+
+	v.samples[key] = &sample{labels: append([]string{}, values...), value: value}
+}
+
+func (v *vector) get(values []string) float64 {
+	key := v.key(values)
+
+	v.mx.Lock()
+	defer v.mx.Unlock()
+
+	if s, ok := v.samples[key]; ok {
+		return s.value
+	}
+
+	return 0
+}
+
+func (v *vector) write(w io.Writer) error {
+	v.mx.Lock()
+	defer v.mx.Unlock()
+
+	if err := v.writeHeader(w); err != nil {
+		return err
+	}
+
+	if len(v.labels) == 0 && len(v.samples) == 0 {
+		_, err := fmt.Fprintf(w, "%s 0\n", v.metricName)
+		return err
+	}
+
+	for _, key := range sortedKeys(v.samples) {
+		s := v.samples[key]
+
+		if _, err := fmt.Fprintf(w, "%s%s %s\n", v.metricName, v.formatLabels(s.labels), formatValue(s.value)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+//Counter is a metric that only goes up
+type Counter struct {
+	*vector
+}
+
+//NewCounter registers a counter with the given label names
+func (r *Registry) NewCounter(name, help string, labels ...string) *Counter {
+	c := &Counter{newVector(name, help, "counter", labels)}
+	r.register(c)
+
+	return c
+}
+
+//Inc adds one to the counter with the given label values
+func (c *Counter) Inc(labelValues ...string) {
+	c.add(1, labelValues)
+}
+
+//Add adds a positive value to the counter with the given label values
+func (c *Counter) Add(value float64, labelValues ...string) {
+	if value < 0 {
+		panic(fmt.Sprintf("Counter %s can not decrease", c.metricName))
+	}
+
+	c.add(value, labelValues)
+}
+
+//Value returns the current value of the counter with the given label values
+func (c *Counter) Value(labelValues ...string) float64 {
+	return c.get(labelValues)
+}
+
+//Gauge is a metric that can go up and down
+type Gauge struct {
+	*vector
+}
+
+//NewGauge registers a gauge with the given label names
+func (r *Registry) NewGauge(name, help string, labels ...string) *Gauge {
+	g := &Gauge{newVector(name, help, "gauge", labels)}
+	r.register(g)
+
+	return g
+}
+
+//Inc adds one to the gauge with the given label values
+func (g *Gauge) Inc(labelValues ...string) {
+	g.add(1, labelValues)
+}
+
+//Dec subtracts one from the gauge with the given label values
+func (g *Gauge) Dec(labelValues ...string) {
+	g.add(-1, labelValues)
+}
+
+//Set changes the value of the gauge with the given label values
+func (g *Gauge) Set(value float64, labelValues ...string) {
+	g.set(value, labelValues)
+}
+
+//Value returns the current value of the gauge with the given label values
+func (g *Gauge) Value(labelValues ...string) float64 {
+	return g.get(labelValues)
+}
+
+//gaugeFunc is a gauge whose value is read when the metrics are written
+type gaugeFunc struct {
+	desc
+	value func() float64
+}
+
+//NewGaugeFunc registers a gauge without labels whose value is returned by f when the metrics are written
+func (r *Registry) NewGaugeFunc(name, help string, f func() float64) {
+	r.register(&gaugeFunc{desc{name, help, "gauge", nil}, f})
+}
+
+func (g *gaugeFunc) write(w io.Writer) error {
+	if err := g.writeHeader(w); err != nil {
+		return err
+	}
+
+	_, err := fmt.Fprintf(w, "%s %s\n", g.metricName, formatValue(g.value()))
+	return err
+}
+
+type histogramSample struct {
+	labels []string
+	counts []uint64
+	count  uint64
+	sum    float64
+}
+
+//Histogram counts observations like request latencies in buckets
+type Histogram struct {
+	desc
+	buckets []float64
+	samples map[string]*histogramSample
+	mx      sync.Mutex
+}
+
+//NewHistogram registers a histogram with the given bucket upper bounds and label names
+func (r *Registry) NewHistogram(name, help string, buckets []float64, labels ...string) *Histogram {
+	buckets = append([]float64{}, buckets...)
+	sort.Float64s(buckets)
+
+	h := &Histogram{
+		desc:    desc{name, help, "histogram", labels},
+		buckets: buckets,
+		samples: map[string]*histogramSample{},
+	}
+	r.register(h)
+
+	return h
+}
+
+//Observe records a value for the given label values
+func (h *Histogram) Observe(value float64, labelValues ...string) {
+	key := h.key(labelValues)
+
+	h.mx.Lock()
+	defer h.mx.Unlock()
+
+	s, ok := h.samples[key]
+
+	if !ok {
+		s = &histogramSample{labels: append([]string{}, labelValues...), counts: make([]uint64, len(h.buckets))}
+		h.samples[key] = s
+	}
+
+	for i, bound := range h.buckets {
+		if value <= bound {
+			s.counts[i]++
+		}
+	}
+
+	s.count++
+	s.sum += value
+}
+
+//Count returns the number of observations for the given label values
+func (h *Histogram) Count(labelValues ...string) uint64 {
+	key := h.key(labelValues)
+
+	h.mx.Lock()
+	defer h.mx.Unlock()
+
+	if s, ok := h.samples[key]; ok {
+		return s.count
+	}
+
+	return 0
+}
+
+func (h *Histogram) write(w io.Writer) error {
+	h.mx.Lock()
+	defer h.mx.Unlock()
+
+	if err := h.writeHeader(w); err != nil {
+		return err
+	}
+
+	for _, key := range sortedKeys(h.samples) {
+		s := h.samples[key]
+
+		for i, bound := range h.buckets {
+			if _, err := fmt.Fprintf(w, "%s_bucket%s %d\n",
+				h.metricName, h.formatLabels(s.labels, "le", formatValue(bound)), s.counts[i]); err != nil {
+				return err
+			}
+		}
+
+		_, err := fmt.Fprintf(w, "%s_bucket%s %d\n%s_sum%s %s\n%s_count%s %d\n",
+			h.metricName, h.formatLabels(s.labels, "le", "+Inf"), s.count,
+			h.metricName, h.formatLabels(s.labels), formatValue(s.sum),
+			h.metricName, h.formatLabels(s.labels), s.count)
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func sortedKeys(samples interface{}) []string {
+	var keys []string
+
+	switch samples := samples.(type) {
+	case map[string]*sample:
+		for key := range samples {
+			keys = append(keys, key)
+		}
+	case map[string]*histogramSample:
+		for key := range samples {
+			keys = append(keys, key)
+		}
+	}
+
+	sort.Strings(keys)
+
+	return keys
+}
+
+func formatValue(value float64) string {
+	switch {
+	case math.IsInf(value, 1):
+		return "+Inf"
+	case math.IsInf(value, -1):
+		return "-Inf"
+	}
+
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
+
+func escapeHelp(help string) string {
+	return strings.NewReplacer(`\`, `\\`, "\n", `\n`).Replace(help)
+}
+
+func escapeLabel(value string) string {
+	return strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`).Replace(value)
+}
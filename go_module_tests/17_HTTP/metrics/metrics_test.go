@@ -0,0 +1,95 @@
+package metrics_test
+
+import (
+	"bytes"
+	"learning/17_HTTP/metrics"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRegistry(t *testing.T) {
+	t.Run("Metrics are written in the Prometheus text format", func(t *testing.T) {
+		registry := metrics.NewRegistry()
+
+		requests := registry.NewCounter("requests_total", "Requests served.", "route", "code")
+		requests.Inc("/league/", "200")
+		requests.Inc("/league/", "200")
+		requests.Add(3, "/players/", "404")
+
+		connections := registry.NewGauge("connections", "Open connections.")
+		connections.Inc()
+		connections.Inc()
+		connections.Dec()
+
+		registry.NewGaugeFunc("database_bytes", "Size of the database.", func() float64 { return 1024 })
+
+		latency := registry.NewHistogram("latency_seconds", "Request latency.", []float64{0.1, 1})
+		latency.Observe(0.05)
+		latency.Observe(0.5)
+		latency.Observe(2)
+
+		buffer := &bytes.Buffer{}
+		registry.Expose(buffer)
+
+		want := `# HELP connections Open connections.
+# TYPE connections gauge
+connections 1
+# HELP database_bytes Size of the database.
+# TYPE database_bytes gauge
+database_bytes 1024
+# HELP latency_seconds Request latency.
+# TYPE latency_seconds histogram
+latency_seconds_bucket{le="0.1"} 1
+latency_seconds_bucket{le="1"} 2
+latency_seconds_bucket{le="+Inf"} 3
+latency_seconds_sum 2.55
+latency_seconds_count 3
+# HELP requests_total Requests served.
+# TYPE requests_total counter
+requests_total{route="/league/",code="200"} 2
+requests_total{route="/players/",code="404"} 3
+`
+
+		if buffer.String() != want {
+			t.Errorf("got\n%s\nwant\n%s", buffer.String(), want)
+		}
+	})
+
+	t.Run("Label values are escaped", func(t *testing.T) {
+		registry := metrics.NewRegistry()
+		registry.NewCounter("errors_total", "Errors.", "message").Inc("say \"hi\"\n")
+
+		buffer := &bytes.Buffer{}
+		registry.Expose(buffer)
+
+		if !bytes.Contains(buffer.Bytes(), []byte(`errors_total{message="say \"hi\"\n"} 1`)) {
+			t.Errorf("Expected an escaped label but got %s", buffer.String())
+		}
+	})
+
+	t.Run("Metrics are served over HTTP", func(t *testing.T) {
+		registry := metrics.NewRegistry()
+		registry.NewCounter("wins_total", "Wins.").Inc()
+
+		response := httptest.NewRecorder()
+		registry.ServeHTTP(response, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+		if response.Header().Get("content-type") != metrics.ContentType {
+			t.Errorf("Unexpected content type %s", response.Header().Get("content-type"))
+		}
+	})
+
+	t.Run("Registering a name twice panics", func(t *testing.T) {
+		registry := metrics.NewRegistry()
+		registry.NewCounter("wins_total", "Wins.")
+
+		defer func() {
+			if recover() == nil {
+				t.Errorf("Expected a panic")
+			}
+		}()
+
+		registry.NewGauge("wins_total", "Wins.")
+	})
+}
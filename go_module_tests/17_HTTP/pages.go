@@ -0,0 +1,219 @@
+package poker
+
+import (
+	"fmt"
+	"html/template"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+//Templates of the server rendered pages
+const (
+	leagueTemplate  string = "league.html"
+	playerTemplate  string = "player.html"
+	historyTemplate string = "history.html"
+)
+
+//pageTemplates are every template the server renders
+var pageTemplates = []string{gameTemplate, leagueTemplate, playerTemplate, historyTemplate}
+
+const (
+	htmlContentType  string = "text/html"
+	defaultSort      string = "wins"
+	historyPageGames int    = 50
+	unknownPlayer    string = "?"
+)
+
+//leagueSorts orders the rows of the league page in ascending order
+var leagueSorts = map[string]func(a, b PlayerProfile) bool{
+	"name":    func(a, b PlayerProfile) bool { return strings.ToLower(a.Name) < strings.ToLower(b.Name) },
+	"wins":    func(a, b PlayerProfile) bool { return a.Wins < b.Wins },
+	"games":   func(a, b PlayerProfile) bool { return a.GamesPlayed < b.GamesPlayed },
+	"winRate": func(a, b PlayerProfile) bool { return a.WinRate < b.WinRate },
+	"rating":  func(a, b PlayerProfile) bool { return a.Rating < b.Rating },
+}
+
+//defaultOrder lists names from A to Z and numbers from the highest
+func defaultOrder(column string) string {
+	if column == "name" {
+		return "asc"
+	}
+
+	return "desc"
+}
+
+func reverseOrder(order string) string {
+	if order == "asc" {
+		return "desc"
+	}
+
+	return "asc"
+}
+
+var templateFuncs = template.FuncMap{
+	"percent": func(rate float64) string {
+		return fmt.Sprintf("%.0f%%", rate*100)
+	},
+	"date": func(t time.Time) string {
+		if t.IsZero() {
+			return ""
+		}
+
+		return t.Local().Format("2006-01-02 15:04")
+	},
+	"finishers": func(positions []string) string {
+		names := make([]string, len(positions))
+
+		for i, name := range positions {
+			names[i] = name
+
+			if name == "" {
+				names[i] = unknownPlayer
+			}
+		}
+
+		return strings.Join(names, ", ")
+	},
+}
+
+//LeagueRow is a line of the league page. Rank is the place of the player by wins
+type LeagueRow struct {
+	Rank int
+	PlayerProfile
+}
+
+//LeaguePage is the data of the league template
+type LeaguePage struct {
+	Rows  []LeagueRow
+	Sort  string
+	Order string
+	Query string
+}
+
+//SortLink returns the query that sorts the league by the given column. Sorting by the current
+//column again reverses the order
+func (l LeaguePage) SortLink(column string) string {
+	order := defaultOrder(column)
+
+	if column == l.Sort && l.Order == order {
+		order = reverseOrder(order)
+	}
+
+	query := url.Values{"sort": {column}, "order": {order}}
+
+	if l.Query != "" {
+		query.Set("q", l.Query)
+	}
+
+	return "?" + query.Encode()
+}
+
+//PlayerPage is the data of the player template
+type PlayerPage struct {
+	PlayerProfile
+	Games []GameResult
+}
+
+//HistoryPage is the data of the history template
+type HistoryPage struct {
+	Games []GameResult
+}
+
+//GamePage is the data of the game template. Players are offered when picking the winner
+type GamePage struct {
+	Players []string
+}
+
+func wantsHTML(req *http.Request) bool {
+	return strings.Contains(req.Header.Get("accept"), htmlContentType)
+}
+
+func (p *PlayerServer) renderPage(resp http.ResponseWriter, name string, data interface{}) {
+	tmpl, err := p.assets.Template(name)
+
+	if err != nil {
+		http.Error(resp, fmt.Sprintf("Error loading template %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	resp.Header().Set("content-type", htmlContentType+"; charset=utf-8")
+	tmpl.Execute(resp, data)
+}
+
+func (p *PlayerServer) leaguePage(resp http.ResponseWriter, req *http.Request) {
+	query := req.URL.Query()
+	page := LeaguePage{
+		Sort:  query.Get("sort"),
+		Order: query.Get("order"),
+		Query: strings.TrimSpace(query.Get("q")),
+	}
+
+	if _, ok := leagueSorts[page.Sort]; !ok {
+		page.Sort = defaultSort
+	}
+
+	if page.Order != "asc" && page.Order != "desc" {
+		page.Order = defaultOrder(page.Sort)
+	}
+
+	for i, player := range p.store.GetLeague() {
+		if !strings.Contains(strings.ToLower(player.Name), strings.ToLower(page.Query)) {
+			continue
+		}
+
+		profile, _ := p.playerProfile(player.Name)
+		page.Rows = append(page.Rows, LeagueRow{i + 1, profile})
+	}
+
+	less := leagueSorts[page.Sort]
+
+	sort.SliceStable(page.Rows, func(i, j int) bool {
+		if page.Order == "desc" {
+			return less(page.Rows[j].PlayerProfile, page.Rows[i].PlayerProfile)
+		}
+
+		return less(page.Rows[i].PlayerProfile, page.Rows[j].PlayerProfile)
+	})
+
+	p.renderPage(resp, leagueTemplate, page)
+}
+
+func (p *PlayerServer) playerPage(resp http.ResponseWriter, player string) {
+	profile, found := p.playerProfile(player)
+
+	if !found {
+		http.Error(resp, fmt.Sprintf("%s has not played yet", player), http.StatusNotFound)
+		return
+	}
+
+	page := PlayerPage{PlayerProfile: profile}
+
+	for _, game := range p.gameHistory() {
+		if game.Position(player) > 0 {
+			page.Games = append(page.Games, game)
+		}
+	}
+
+	p.renderPage(resp, playerTemplate, page)
+}
+
+func (p *PlayerServer) historyHandler(resp http.ResponseWriter, req *http.Request) {
+	games := p.gameHistory()
+
+	if len(games) > historyPageGames {
+		games = games[:historyPageGames]
+	}
+
+	p.renderPage(resp, historyTemplate, HistoryPage{games})
+}
+
+func (p *PlayerServer) gameHistory() []GameResult {
+	if history, ok := p.store.(GameHistory); ok {
+		return history.GetGames()
+	}
+
+	return nil
+}
@@ -0,0 +1,117 @@
+package poker_test
+
+import (
+	poker "learning/17_HTTP"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+const pagesDatabase string = `{"version": 3,
+	"players": [{"Name": "Cleo", "Wins": 1}, {"Name": "Chris", "Wins": 2}, {"Name": "Kiro", "Wins": 0}],
+	"games": [
+		{"PlayedAt": "2020-09-01T12:00:00Z", "FieldSize": 3, "Positions": ["Chris", "", "Kiro"]},
+		{"PlayedAt": "2020-09-02T12:00:00Z", "FieldSize": 3, "Positions": ["Cleo", "Chris", "Kiro"]},
+		{"PlayedAt": "2020-09-03T12:00:00Z", "FieldSize": 2, "Positions": ["Chris", "Cleo"]}
+	]}`
+
+func TestPages(t *testing.T) {
+	database, cleanDb := poker.CreateTempFile(t, pagesDatabase, "db")
+	defer cleanDb()
+
+	store, err := poker.NewFileSystemPlayerStore(database)
+	poker.AssertNoError(t, err)
+
+	server := poker.CreateNewPlayerServer(t, store, &poker.SpyGame{})
+
+	t.Run("The league is sorted by wins", func(t *testing.T) {
+		response := getPage(server, "/league/")
+
+		poker.AssertStatusCode(t, response.Code, http.StatusOK)
+		assertInOrder(t, response.Body.String(), ">Chris<", ">Cleo<", ">Kiro<")
+	})
+
+	t.Run("The league can be sorted by name", func(t *testing.T) {
+		body := getPage(server, "/league/?sort=name").Body.String()
+
+		assertInOrder(t, body, ">Chris<", ">Cleo<", ">Kiro<")
+
+		body = getPage(server, "/league/?sort=name&order=desc").Body.String()
+
+		assertInOrder(t, body, ">Kiro<", ">Cleo<", ">Chris<")
+	})
+
+	t.Run("The league can be searched", func(t *testing.T) {
+		body := getPage(server, "/league/?q=cl").Body.String()
+
+		if !strings.Contains(body, ">Cleo<") || strings.Contains(body, ">Chris<") {
+			t.Errorf("Expected only Cleo in the league but got %s", body)
+		}
+	})
+
+	t.Run("The league is still JSON for other clients", func(t *testing.T) {
+		response := httptest.NewRecorder()
+		server.ServeHTTP(response, poker.NewLeagueRequest())
+
+		poker.AssertJSONContentType(t, response)
+	})
+
+	t.Run("Profiles list the games of the player", func(t *testing.T) {
+		response := getPage(server, "/players/Kiro")
+		body := response.Body.String()
+
+		poker.AssertStatusCode(t, response.Code, http.StatusOK)
+		assertInOrder(t, body, "<h1>Kiro</h1>", "2020-09-02", "2020-09-01")
+
+		if strings.Contains(body, "2020-09-03") {
+			t.Errorf("Expected only the games of Kiro but got %s", body)
+		}
+	})
+
+	t.Run("Unknown players are not found", func(t *testing.T) {
+		poker.AssertStatusCode(t, getPage(server, "/players/Missing").Code, http.StatusNotFound)
+	})
+
+	t.Run("The history lists every game from the newest", func(t *testing.T) {
+		body := getPage(server, "/history/").Body.String()
+
+		assertInOrder(t, body, "Chris, Cleo", "Cleo, Chris, Kiro", "Chris, ?, Kiro")
+	})
+
+	t.Run("The winner can be picked from the known players", func(t *testing.T) {
+		body := getPage(server, "/game/").Body.String()
+
+		for _, player := range []string{"Chris", "Cleo", "Kiro"} {
+			if !strings.Contains(body, `<option value="`+player+`">`) {
+				t.Errorf("Expected %s to be offered as a winner", player)
+			}
+		}
+	})
+}
+
+func getPage(server http.Handler, path string) *httptest.ResponseRecorder {
+	request := httptest.NewRequest(http.MethodGet, path, nil)
+	request.Header.Set("Accept", "text/html,application/xhtml+xml")
+
+	response := httptest.NewRecorder()
+	server.ServeHTTP(response, request)
+
+	return response
+}
+
+func assertInOrder(t *testing.T, body string, parts ...string) {
+	t.Helper()
+
+	last := -1
+
+	for _, part := range parts {
+		index := strings.Index(body, part)
+
+		if index <= last {
+			t.Fatalf("Expected %q in order in %s", parts, body)
+		}
+
+		last = index
+	}
+}
@@ -3,15 +3,23 @@ package poker
 import (
 	"encoding/json"
 	"fmt"
-	"html/template"
+	"learning/17_HTTP/logging"
 	"net/http"
 	"strconv"
 	"strings"
+	"sync"
+
+	"github.com/gorilla/websocket"
 )
 
 const (
 	jsonContentType string = "application/json"
-	gamePath        string = "./html/game.html"
+	gameTemplate    string = "game.html"
+	gameIDHeader    string = "X-Game-Id"
+	gameTokenHeader string = "X-Game-Token"
+	gameTokenCookie string = "poker-game-token"
+	abandonedResult string = "Game abandoned"
+	shutdownResult  string = "Server shutting down"
 )
 
 //PlayerStore contains the information of the players
@@ -21,12 +29,34 @@ type PlayerStore interface {
 	GetLeague() League
 }
 
+//GameRecorder is a PlayerStore that keeps the results of whole games and not only the winners
+type GameRecorder interface {
+	RecordGame(result GameResult)
+}
+
+//ProfileStore is a PlayerStore that can build the statistics of its players
+type ProfileStore interface {
+	GetPlayerProfile(name string) (PlayerProfile, bool)
+}
+
+//GameHistory is a PlayerStore that can list the games it recorded
+type GameHistory interface {
+	GetGames() []GameResult
+}
+
 //PlayerServer is the httpHandler for request to /players/
 type PlayerServer struct {
 	store PlayerStore
 	http.Handler
-	tmpl *template.Template
-	game AbstractGame
+	assets    *Assets
+	newGame   func() AbstractGame
+	games     *GameRegistry
+	saved     []SavedGame
+	wsOptions WebSocketOptions
+	optionsMx sync.RWMutex
+	router    *http.ServeMux
+	metrics   *ServerMetrics
+	sessions  webSocketSessions
 }
 
 //Player represents a person with a name and a number of wins
@@ -36,47 +66,270 @@ type Player struct {
 }
 
 //NewPlayerServer is a constructor for PlayerServer that creates a router for it.
+//Every game over a websocket is played with the same game
 func NewPlayerServer(store PlayerStore, game AbstractGame) (*PlayerServer, error) {
-	p := new(PlayerServer)
+	return NewPlayerServerWithGames(store, func() AbstractGame { return game })
+}
 
-	tmpl, err := template.ParseFiles(gamePath)
+//NewPlayerServerWithGames is a constructor for PlayerServer that plays every game over a websocket
+//with a new game from newGame so games that keep state do not share it
+func NewPlayerServerWithGames(store PlayerStore, newGame func() AbstractGame) (*PlayerServer, error) {
+	p := new(PlayerServer)
 
-	if err != nil {
-		return nil, fmt.Errorf("Error loading template %v", err)
+	if err := p.SetAssetsDir(""); err != nil {
+		return nil, err
 	}
 
-	p.tmpl = tmpl
 	p.store = store
-	p.game = game
+	p.newGame = newGame
+	p.games = NewGameRegistry()
+	p.wsOptions = DefaultWebSocketOptions.withDefaults()
 
 	router := http.NewServeMux()
 	router.Handle("/players/", http.HandlerFunc(p.playersHandler))
 	router.Handle("/league/", http.HandlerFunc(p.leagueHandler))
 	router.Handle("/game/", http.HandlerFunc(p.gameHandler))
 	router.Handle("/ws/", http.HandlerFunc(p.webSocketHandler))
+	router.Handle("/games/", http.HandlerFunc(p.gamesHandler))
+	router.Handle("/history/", http.HandlerFunc(p.historyHandler))
+	router.Handle("/static/", http.StripPrefix("/static/", http.HandlerFunc(p.staticHandler)))
 
+	p.router = router
 	p.Handler = router
 
 	return p, nil
 }
 
+//SetMetrics makes the server count its requests, websockets and running games
+func (p *PlayerServer) SetMetrics(metrics *ServerMetrics) {
+	p.metrics = metrics
+	p.metrics.watchGames(p.games)
+	p.Handler = metrics.Instrument(p.router)
+}
+
+//SetAssetsDir makes the server prefer the templates and static files in dir over the embedded ones.
+//An empty dir only uses the embedded files
+func (p *PlayerServer) SetAssetsDir(dir string) error {
+	assets, err := NewAssets(dir)
+
+	if err != nil {
+		return err
+	}
+
+	if _, err := assets.Template(gameTemplate); err != nil {
+		return fmt.Errorf("Error loading template %v", err)
+	}
+
+	p.assets = assets
+
+	return nil
+}
+
+//CheckTemplates returns an error if one of the page templates can not be loaded
+func (p *PlayerServer) CheckTemplates() error {
+	for _, name := range pageTemplates {
+		if _, err := p.assets.Template(name); err != nil {
+			return fmt.Errorf("Error loading template %s %v", name, err)
+		}
+	}
+
+	return nil
+}
+
+//RunningGames returns the games that have not finished yet
+func (p *PlayerServer) RunningGames() []RunningGame {
+	return p.games.RunningGames()
+}
+
+//SetWebSocketOptions changes the keepalive settings of the websockets opened after the call.
+//Zero values keep their defaults
+func (p *PlayerServer) SetWebSocketOptions(options WebSocketOptions) {
+	p.optionsMx.Lock()
+	defer p.optionsMx.Unlock()
+
+	p.wsOptions = options.withDefaults()
+}
+
+//SetAllowedOrigins changes the origins that may open websockets while the server runs
+func (p *PlayerServer) SetAllowedOrigins(origins []string) {
+	p.optionsMx.Lock()
+	defer p.optionsMx.Unlock()
+
+	p.wsOptions.AllowedOrigins = origins
+}
+
+func (p *PlayerServer) webSocketOptions() WebSocketOptions {
+	p.optionsMx.RLock()
+	defer p.optionsMx.RUnlock()
+
+	return p.wsOptions
+}
+
+//webSocketHandler runs a game over a websocket. The id of the game is sent in the X-Game-Id header
+//of the upgrade response so other clients can follow it on /games/{id}/events.
+//The secret token of the game is sent in the X-Game-Token header and a cookie. A client whose
+//connection dropped takes the game back by connecting to /ws/?token={token}&last={id} where id
+//is the number of messages it already got
 func (p *PlayerServer) webSocketHandler(resp http.ResponseWriter, req *http.Request) {
-	conn := newPlayerServerWs(resp, req)
-	numberOfPlayers, _ := strconv.Atoi(conn.WaitForMsg())
+	if p.sessions.Draining() {
+		http.Error(resp, shutdownResult, http.StatusServiceUnavailable)
+		return
+	}
+
+	query := req.URL.Query()
+	id, events := p.games.Lookup(query.Get("token"))
+	resumed := query.Get("token") != ""
+
+	if resumed && events == nil {
+		http.Error(resp, "Unknown game token", http.StatusNotFound)
+		return
+	}
+
+	if !resumed {
+		id, events = p.games.New()
+	}
+
+	token := p.games.Token(id)
+	header := http.Header{gameIDHeader: {id}, gameTokenHeader: {token}}
+	header.Add("Set-Cookie", (&http.Cookie{Name: gameTokenCookie, Value: token, Path: "/"}).String())
+
+	conn := newPlayerServerWs(resp, req, header, p.webSocketOptions())
+
+	if conn == nil {
+		if !resumed {
+			events.Finish(abandonedResult)
+		}
+		return
+	}
+
+	defer conn.Close()
+
+	ctx, span := logging.StartSpan(req.Context(), "websocket.game")
+	defer span.End()
+	span.SetAttribute("game.id", id)
+	span.SetAttribute("game.resumed", resumed)
+
+	log := logging.FromContext(ctx).With("gameId", id)
+	log.Info("Game connected", "resumed", resumed)
+	conn.log = log
+
+	p.metrics.webSocketOpened()
+	defer p.metrics.webSocketClosed()
 
-	p.game.Start(numberOfPlayers, conn)
+	stopKeepAlive := conn.KeepAlive()
+	defer stopKeepAlive()
 
-	winnerMsg := conn.WaitForMsg()
-	p.game.Win(string(winnerMsg))
+	lastID, _ := strconv.Atoi(query.Get("last"))
+	backlog, live, cancel := events.Subscribe(lastID)
+
+	go conn.Forward(backlog, live)
+
+	//The server may have started draining since the check above
+	if !p.sessions.Add(conn) {
+		events.Finish(shutdownResult)
+		<-conn.forwarded
+		conn.sendClose(websocket.CloseServiceRestart, shutdownResult)
+		return
+	}
+
+	defer p.sessions.Done(conn)
+	events.Join()
+
+	err := p.playOverWebSocket(conn, events)
+	leave := events.Leave()
+
+	switch {
+	case err == nil:
+		log.Info("Game finished")
+	case events.Finished():
+		log.Info("Game interrupted", "error", err)
+	default:
+		log.Warn("Lost the websocket of the game", "error", err)
+		span.SetError(err)
+		cancel()
+		p.abandonLater(events, leave)
+	}
+
+	<-conn.forwarded
+}
+
+//playOverWebSocket starts the game unless it already was and applies the messages of the client
+//until the game finishes. An error means the client is gone and the game is still running
+func (p *PlayerServer) playOverWebSocket(conn *playerServerWS, events *GameEvents) error {
+	if !events.Started() {
+		msg, err := conn.WaitForMsg()
+
+		if err != nil {
+			return err
+		}
+
+		numberOfPlayers, _ := strconv.Atoi(msg)
+		game := p.newGame()
+
+		if events.MarkStarted(game) {
+			game.Start(numberOfPlayers, events)
+		}
+	}
+
+	game := events.Game()
+
+	for !events.Finished() {
+		msg, err := conn.WaitForMsg()
+
+		if err != nil {
+			return err
+		}
+
+		if events.Finished() {
+			break
+		}
+
+		if HandleGameMessage(game, msg, events) {
+			events.Finish(extractWinner(msg) + winSuffix)
+		}
+	}
+
+	return nil
+}
+
+//abandonLater finishes a game without a winner if no player took it back once the resume timeout
+//passes. Viewers of the event stream do not keep the game running. A player who takes the game
+//back and loses it again gets a resume timeout of their own
+func (p *PlayerServer) abandonLater(events *GameEvents, leave int) {
+	options := p.webSocketOptions()
+
+	options.Clock.AfterFunc(options.ResumeTimeout, func() {
+		if events.Abandoned(leave) {
+			events.Finish(abandonedResult)
+		}
+	})
 }
 
 func (p *PlayerServer) gameHandler(resp http.ResponseWriter, req *http.Request) {
-	p.tmpl.Execute(resp, nil)
+	var page GamePage
+
+	for _, player := range p.store.GetLeague() {
+		page.Players = append(page.Players, player.Name)
+	}
+
+	p.renderPage(resp, gameTemplate, page)
+}
+
+func (p *PlayerServer) staticHandler(resp http.ResponseWriter, req *http.Request) {
+	p.assets.ServeStatic(resp, req)
 }
 
 func (p *PlayerServer) leagueHandler(resp http.ResponseWriter, req *http.Request) {
+	if wantsHTML(req) {
+		p.leaguePage(resp, req)
+		return
+	}
+
+	var league League
+	storeCall(req.Context(), "GetLeague", func() { league = p.store.GetLeague() })
+
 	resp.Header().Set("content-type", jsonContentType)
-	json.NewEncoder(resp).Encode(p.store.GetLeague())
+	json.NewEncoder(resp).Encode(league)
 }
 
 func (p *PlayerServer) playersHandler(resp http.ResponseWriter, req *http.Request) {
@@ -84,20 +337,26 @@ func (p *PlayerServer) playersHandler(resp http.ResponseWriter, req *http.Reques
 
 	switch req.Method {
 	case http.MethodPost:
-		p.processWin(resp, player)
+		p.processWin(resp, req, player)
 	case http.MethodGet:
-		p.displayScore(resp, player)
+		if strings.Contains(req.Header.Get("accept"), jsonContentType) {
+			p.displayProfile(resp, req, player)
+		} else if wantsHTML(req) {
+			p.playerPage(resp, player)
+		} else {
+			p.displayScore(resp, req, player)
+		}
 	}
 }
 
-func (p *PlayerServer) processWin(resp http.ResponseWriter, player string) {
-	p.store.RecordWin(player)
+func (p *PlayerServer) processWin(resp http.ResponseWriter, req *http.Request, player string) {
+	storeCall(req.Context(), "RecordWin", func() { p.store.RecordWin(player) })
 	resp.WriteHeader(http.StatusAccepted)
 }
 
-func (p *PlayerServer) displayScore(resp http.ResponseWriter, player string) {
-
-	score := p.store.GetPlayerScore(player)
+func (p *PlayerServer) displayScore(resp http.ResponseWriter, req *http.Request, player string) {
+	var score int
+	storeCall(req.Context(), "GetPlayerScore", func() { score = p.store.GetPlayerScore(player) })
 
 	if score == 0 {
 		resp.WriteHeader(http.StatusNotFound)
@@ -105,3 +364,31 @@ func (p *PlayerServer) displayScore(resp http.ResponseWriter, player string) {
 
 	fmt.Fprint(resp, score)
 }
+
+func (p *PlayerServer) displayProfile(resp http.ResponseWriter, req *http.Request, player string) {
+	var profile PlayerProfile
+	var found bool
+	storeCall(req.Context(), "GetPlayerProfile", func() { profile, found = p.playerProfile(player) })
+
+	if !found {
+		resp.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	resp.Header().Set("content-type", jsonContentType)
+	json.NewEncoder(resp).Encode(profile)
+}
+
+func (p *PlayerServer) playerProfile(player string) (PlayerProfile, bool) {
+	if store, ok := p.store.(ProfileStore); ok {
+		return store.GetPlayerProfile(player)
+	}
+
+	score := p.store.GetPlayerScore(player)
+
+	if score == 0 {
+		return PlayerProfile{}, false
+	}
+
+	return NewPlayerProfile(Player{player, score}, nil), true
+}
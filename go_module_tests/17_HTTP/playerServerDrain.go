@@ -0,0 +1,135 @@
+package poker
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/gorilla/websocket"
+)
+
+//webSocketSessions tracks the open websockets so they can be closed when the server shuts down
+type webSocketSessions struct {
+	conns    map[*playerServerWS]struct{}
+	draining bool
+	wg       sync.WaitGroup
+	mx       sync.Mutex
+}
+
+//Add tracks a websocket until Done is called. It returns false once the server is draining
+func (s *webSocketSessions) Add(conn *playerServerWS) bool {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	if s.draining {
+		return false
+	}
+
+	if s.conns == nil {
+		s.conns = map[*playerServerWS]struct{}{}
+	}
+
+	s.conns[conn] = struct{}{}
+	s.wg.Add(1)
+
+	return true
+}
+
+//Done stops tracking a websocket
+func (s *webSocketSessions) Done(conn *playerServerWS) {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	if _, ok := s.conns[conn]; ok {
+		delete(s.conns, conn)
+		s.wg.Done()
+	}
+}
+
+//Draining tells if new websockets are refused
+func (s *webSocketSessions) Draining() bool {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	return s.draining
+}
+
+//drain refuses new websockets and returns the open ones
+func (s *webSocketSessions) drain() []*playerServerWS {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	s.draining = true
+
+	var conns []*playerServerWS
+
+	for conn := range s.conns {
+		conns = append(conns, conn)
+	}
+
+	return conns
+}
+
+//wait blocks until every websocket is done or ctx is
+func (s *webSocketSessions) wait(ctx context.Context) error {
+	done := make(chan struct{})
+
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+//Drain refuses new games and finishes the running ones with a result telling their players that
+//the server is shutting down. Websockets are closed once the result was sent. The interrupted
+//games are written to w after the ones loaded by LoadGames so games saved by earlier restarts
+//are kept. Only the most recent ones are written.
+//It waits for the websockets to close until ctx is done
+func (p *PlayerServer) Drain(ctx context.Context, w io.Writer) error {
+	conns := p.sessions.drain()
+	saved := append(append([]SavedGame{}, p.saved...), p.games.Interrupt(shutdownResult)...)
+
+	if len(saved) > maxFinishedGames {
+		saved = saved[len(saved)-maxFinishedGames:]
+	}
+
+	if err := json.NewEncoder(w).Encode(saved); err != nil {
+		return fmt.Errorf("Could not save the interrupted games %v", err)
+	}
+
+	for _, conn := range conns {
+		select {
+		case <-conn.forwarded:
+		case <-ctx.Done():
+		}
+
+		conn.sendClose(websocket.CloseServiceRestart, shutdownResult)
+	}
+
+	return p.sessions.wait(ctx)
+}
+
+//LoadGames adds the games saved by Drain as finished games so their logs can still be followed.
+//Interrupted games are not resumed. Their log and the standing of their tournament are kept and
+//saved again by the next Drain
+func (p *PlayerServer) LoadGames(r io.Reader) error {
+	var games []SavedGame
+
+	if err := json.NewDecoder(r).Decode(&games); err != nil {
+		return fmt.Errorf("Could not load the interrupted games %v", err)
+	}
+
+	p.games.Restore(games)
+	p.saved = append(p.saved, games...)
+
+	return nil
+}
@@ -0,0 +1,106 @@
+package poker
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+const eventStreamContentType string = "text/event-stream"
+
+//GameSummary describes a game known to the server
+type GameSummary struct {
+	ID       string
+	Finished bool
+}
+
+//gamesHandler lists the known games on /games/ and streams the events of a game
+//as server-sent events on /games/{id}/events
+func (p *PlayerServer) gamesHandler(resp http.ResponseWriter, req *http.Request) {
+	path := strings.Trim(strings.TrimPrefix(req.URL.Path, "/games/"), "/")
+
+	if path == "" {
+		p.listGames(resp)
+		return
+	}
+
+	parts := strings.Split(path, "/")
+
+	if len(parts) != 2 || parts[1] != "events" {
+		resp.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	events := p.games.Get(parts[0])
+
+	if events == nil {
+		resp.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	streamGameEvents(resp, req, events)
+}
+
+func (p *PlayerServer) listGames(resp http.ResponseWriter) {
+	games := []GameSummary{}
+
+	for _, id := range p.games.IDs() {
+		if events := p.games.Get(id); events != nil {
+			games = append(games, GameSummary{id, events.Finished()})
+		}
+	}
+
+	resp.Header().Set("content-type", jsonContentType)
+	json.NewEncoder(resp).Encode(games)
+}
+
+//streamGameEvents writes game events as server-sent events. Clients that reconnect with a
+//Last-Event-ID header only get the events they missed
+func streamGameEvents(resp http.ResponseWriter, req *http.Request, events *GameEvents) {
+	flusher, ok := resp.(http.Flusher)
+
+	if !ok {
+		http.Error(resp, "Streaming is not supported", http.StatusInternalServerError)
+		return
+	}
+
+	lastID, _ := strconv.Atoi(req.Header.Get("Last-Event-ID"))
+	backlog, live, cancel := events.Subscribe(lastID)
+	defer cancel()
+
+	resp.Header().Set("content-type", eventStreamContentType)
+	resp.Header().Set("cache-control", "no-cache")
+	resp.WriteHeader(http.StatusOK)
+
+	for _, event := range backlog {
+		writeServerSentEvent(resp, event)
+	}
+
+	flusher.Flush()
+
+	for {
+		select {
+		case event, ok := <-live:
+			if !ok {
+				return
+			}
+
+			writeServerSentEvent(resp, event)
+			flusher.Flush()
+		case <-req.Context().Done():
+			return
+		}
+	}
+}
+
+func writeServerSentEvent(resp http.ResponseWriter, event GameEvent) {
+	fmt.Fprintf(resp, "id: %d\nevent: %s\n", event.ID, event.Type)
+
+	for _, line := range strings.Split(strings.TrimSuffix(event.Data, "\n"), "\n") {
+		fmt.Fprintf(resp, "data: %s\n", line)
+	}
+
+	fmt.Fprint(resp, "\n")
+}
@@ -1,8 +1,13 @@
 package poker
 
 import (
-	"log"
+	"errors"
+	"learning/17_HTTP/logging"
 	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/gorilla/websocket"
 )
@@ -12,21 +17,175 @@ var wsUpgrader websocket.Upgrader = websocket.Upgrader{
 	WriteBufferSize: 1024,
 }
 
+//ErrConnectionClosed is returned by WaitForMsg when the client closes the websocket with a close frame
+var ErrConnectionClosed = errors.New("Websocket closed by the client")
+
+//WebSocketOptions controls which pages may open the websocket of a game and how it is kept alive.
+//Pings are scheduled on Clock while read and write deadlines always follow real time
+//because they are enforced by the network connection.
+//Pages served by the server itself are always allowed. AllowedOrigins lists other origins
+//like "https://poker.example.com" and "*" allows every origin
+type WebSocketOptions struct {
+	PingPeriod     time.Duration
+	PongWait       time.Duration
+	WriteWait      time.Duration
+	ResumeTimeout  time.Duration
+	AllowedOrigins []string
+	Clock          Clock
+}
+
+//DefaultWebSocketOptions are used by a PlayerServer unless others are set.
+//A game nobody reconnects to within ResumeTimeout is abandoned
+var DefaultWebSocketOptions = WebSocketOptions{
+	PingPeriod:    54 * time.Second,
+	PongWait:      60 * time.Second,
+	WriteWait:     10 * time.Second,
+	ResumeTimeout: 30 * time.Minute,
+}
+
+func (o WebSocketOptions) withDefaults() WebSocketOptions {
+	if o.PingPeriod <= 0 {
+		o.PingPeriod = DefaultWebSocketOptions.PingPeriod
+	}
+
+	if o.PongWait <= 0 {
+		o.PongWait = DefaultWebSocketOptions.PongWait
+	}
+
+	if o.WriteWait <= 0 {
+		o.WriteWait = DefaultWebSocketOptions.WriteWait
+	}
+
+	if o.ResumeTimeout <= 0 {
+		o.ResumeTimeout = DefaultWebSocketOptions.ResumeTimeout
+	}
+
+	o.Clock = clockOrSystem(o.Clock)
+
+	return o
+}
+
+//checkOrigin allows requests without an Origin header, from the host of the request
+//and from the allowed origins
+func (o WebSocketOptions) checkOrigin(req *http.Request) bool {
+	origin := req.Header.Get("Origin")
+
+	if origin == "" {
+		return true
+	}
+
+	originURL, err := url.Parse(origin)
+
+	if err == nil && strings.EqualFold(originURL.Host, req.Host) {
+		return true
+	}
+
+	for _, allowed := range o.AllowedOrigins {
+		if allowed == "*" || strings.EqualFold(strings.TrimSuffix(allowed, "/"), origin) {
+			return true
+		}
+	}
+
+	return false
+}
+
 type playerServerWS struct {
 	*websocket.Conn
+	options   WebSocketOptions
+	log       *logging.Logger
+	forwarded chan struct{}
+	writeMx   sync.Mutex
 }
 
-func newPlayerServerWs(resp http.ResponseWriter, req *http.Request) *playerServerWS {
-	conn, err := wsUpgrader.Upgrade(resp, req, nil)
+func newPlayerServerWs(resp http.ResponseWriter, req *http.Request, header http.Header, options WebSocketOptions) *playerServerWS {
+	upgrader := wsUpgrader
+	upgrader.CheckOrigin = options.checkOrigin
+
+	conn, err := upgrader.Upgrade(resp, req, header)
 
 	if err != nil {
-		log.Printf("Problem upgrading http connection to web socket %v", err)
+		logging.FromContext(req.Context()).Warn("Problem upgrading http connection to web socket", "error", err)
+		return nil
+	}
+
+	ws := &playerServerWS{
+		Conn:      conn,
+		options:   options,
+		log:       logging.FromContext(req.Context()),
+		forwarded: make(chan struct{}),
+	}
+	ws.extendReadDeadline()
+	ws.SetPongHandler(func(string) error {
+		ws.extendReadDeadline()
+		return nil
+	})
+
+	return ws
+}
+
+//KeepAlive pings the client every PingPeriod until the returned function is called.
+//A client that does not answer within PongWait makes WaitForMsg fail
+func (p *playerServerWS) KeepAlive() (stop func()) {
+	var mx sync.Mutex
+	var timer Timer
+	stopped := false
+
+	var ping func()
+	ping = func() {
+		deadline := time.Now().Add(p.options.WriteWait)
+
+		if err := p.WriteControl(websocket.PingMessage, nil, deadline); err != nil {
+			return
+		}
+
+		mx.Lock()
+		defer mx.Unlock()
+
+		if !stopped {
+			timer = p.options.Clock.AfterFunc(p.options.PingPeriod, ping)
+		}
+	}
+
+	mx.Lock()
+	timer = p.options.Clock.AfterFunc(p.options.PingPeriod, ping)
+	mx.Unlock()
+
+	return func() {
+		mx.Lock()
+		defer mx.Unlock()
+
+		stopped = true
+		timer.Stop()
+	}
+}
+
+//Forward writes the data of the backlog and then of every live game event to the websocket
+//until the channel is closed
+func (p *playerServerWS) Forward(backlog []GameEvent, live <-chan GameEvent) {
+	defer close(p.forwarded)
+
+	for _, event := range backlog {
+		p.forward(event)
 	}
 
-	return &playerServerWS{conn}
+	for event := range live {
+		p.forward(event)
+	}
+}
+
+func (p *playerServerWS) forward(event GameEvent) {
+	if _, err := p.Write([]byte(event.Data)); err != nil {
+		p.log.Debug("Error forwarding game event", "eventId", event.ID, "error", err)
+	}
 }
 
+//Write sends a text message. Alerts and game messages are written from different goroutines
+//so writes are serialised
 func (p *playerServerWS) Write(msg []byte) (n int, err error) {
+	p.writeMx.Lock()
+	defer p.writeMx.Unlock()
+
+	p.SetWriteDeadline(time.Now().Add(p.options.WriteWait))
 	err = p.WriteMessage(websocket.TextMessage, msg)
 
 	if err != nil {
@@ -36,12 +195,37 @@ func (p *playerServerWS) Write(msg []byte) (n int, err error) {
 	return len(msg), nil
 }
 
-func (p *playerServerWS) WaitForMsg() string {
+//Close sends a close frame to the client before closing the connection
+func (p *playerServerWS) Close() error {
+	p.sendClose(websocket.CloseNormalClosure, "")
+
+	return p.Conn.Close()
+}
+
+//sendClose asks the client to close the websocket. Its answer makes WaitForMsg return
+func (p *playerServerWS) sendClose(code int, reason string) {
+	deadline := time.Now().Add(p.options.WriteWait)
+	p.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(code, reason), deadline)
+}
+
+//WaitForMsg blocks until the client sends a message. It fails with ErrConnectionClosed when the
+//client closes the websocket and with the read error when the connection drops or times out
+func (p *playerServerWS) WaitForMsg() (string, error) {
 	_, msg, err := p.ReadMessage()
 
+	if websocket.IsCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway) {
+		return "", ErrConnectionClosed
+	}
+
 	if err != nil {
-		log.Printf("Error reading msg %v", err)
+		return "", err
 	}
 
-	return string(msg)
+	p.extendReadDeadline()
+
+	return string(msg), nil
+}
+
+func (p *playerServerWS) extendReadDeadline() {
+	p.SetReadDeadline(time.Now().Add(p.options.PongWait))
 }
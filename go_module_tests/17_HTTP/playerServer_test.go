@@ -1,9 +1,14 @@
 package poker
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
 	"net/http"
 	"net/http/httptest"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 
@@ -58,6 +63,34 @@ func TestGETPlayerScore(t *testing.T) {
 
 		AssertStatusCode(t, response.Code, http.StatusNotFound)
 	})
+
+	t.Run("Returns a JSON profile when it is accepted", func(t *testing.T) {
+		request := NewGetProfileRequest("gosho")
+		response := httptest.NewRecorder()
+
+		server.ServeHTTP(response, request)
+
+		AssertStatusCode(t, response.Code, http.StatusOK)
+		AssertJSONContentType(t, response)
+
+		var got PlayerProfile
+		if err := json.NewDecoder(response.Body).Decode(&got); err != nil {
+			t.Fatalf("Unable to parse profile %v", err)
+		}
+
+		if got.Name != "gosho" || got.Wins != 20 || got.GamesPlayed != 20 {
+			t.Errorf("Unexpected profile %+v", got)
+		}
+	})
+
+	t.Run("404 when profile of a missing player is requested", func(t *testing.T) {
+		request := NewGetProfileRequest("missing")
+		response := httptest.NewRecorder()
+
+		server.ServeHTTP(response, request)
+
+		AssertStatusCode(t, response.Code, http.StatusNotFound)
+	})
 }
 
 func TestStoreWins(t *testing.T) {
@@ -142,6 +175,342 @@ func TestGame(t *testing.T) {
 		AssertGameWinCalled(t, game, winner)
 		within(t, tenMS, func() { assertWebsocketGotMsg(t, ws, wantedBlindAlert) })
 	})
+
+	t.Run("Eliminations are sent over the websocket before the winner", func(t *testing.T) {
+		store := &StubPlayerStore{}
+		game := NewTournament(store, BlindAlerterFunc(func(time.Duration, int, io.Writer) {}), TournamentOptions{})
+		server := httptest.NewServer(CreateNewPlayerServer(t, store, game))
+		ws := createWebSocket(t, "ws"+strings.TrimPrefix(server.URL, "http")+"/ws/")
+
+		defer server.Close()
+		defer ws.Close()
+
+		sendWebSocketMessage(t, ws, "3")
+		sendWebSocketMessage(t, ws, "Kiro is out")
+
+		within(t, tenMS, func() { assertWebsocketGotMsg(t, ws, "Kiro finishes in position 3\n") })
+	})
+
+	t.Run("Games played at the same time have their own tournament", func(t *testing.T) {
+		store := &StubPlayerStore{}
+		tournaments := NewTournaments(store, BlindAlerterFunc(func(time.Duration, int, io.Writer) {}), TournamentOptions{})
+		playerServer, err := NewPlayerServerWithGames(store, tournaments.New)
+		AssertNoError(t, err)
+
+		server := httptest.NewServer(playerServer)
+		first := createWebSocket(t, webSocketURL(server, ""))
+		second := createWebSocket(t, webSocketURL(server, ""))
+
+		defer server.Close()
+		defer first.Close()
+		defer second.Close()
+
+		sendWebSocketMessage(t, first, "3")
+		sendWebSocketMessage(t, second, "5")
+		retryUntil(500*time.Millisecond, func() bool {
+			return playerServer.games.Get("1").Started() && playerServer.games.Get("2").Started()
+		})
+
+		sendWebSocketMessage(t, first, "Kiro is out")
+		sendWebSocketMessage(t, second, "Ruth is out")
+		sendWebSocketMessage(t, first, "Chris is out")
+
+		within(t, tenMS, func() { assertWebsocketGotMsg(t, first, "Kiro finishes in position 3\n") })
+		within(t, tenMS, func() { assertWebsocketGotMsg(t, second, "Ruth finishes in position 5\n") })
+		within(t, tenMS, func() { assertWebsocketGotMsg(t, first, "Chris finishes in position 2\n") })
+	})
+}
+
+func TestWebSocketConnectionLoss(t *testing.T) {
+	t.Run("A dropped connection does not declare a winner", func(t *testing.T) {
+		game := &SpyGame{}
+		playerServer := CreateNewPlayerServer(t, &StubPlayerStore{}, game)
+		server := httptest.NewServer(playerServer)
+		defer server.Close()
+
+		ws := createWebSocket(t, webSocketURL(server, ""))
+		sendWebSocketMessage(t, ws, "3")
+		AssertGameStartedWithXNumberOfPlayers(t, game, 3)
+
+		ws.UnderlyingConn().Close()
+
+		events := playerServer.games.Get("1")
+		retryUntil(500*time.Millisecond, func() bool { return events.Subscribers() == 0 })
+
+		AssertFalse(t, game.WinCalled())
+		AssertFalse(t, events.Finished())
+	})
+
+	t.Run("A client can reconnect with the game token and resume the game", func(t *testing.T) {
+		game := &SpyGame{BlindAlert: []byte("Blind is 100")}
+		server := httptest.NewServer(CreateNewPlayerServer(t, &StubPlayerStore{}, game))
+		defer server.Close()
+
+		ws, resp, err := websocket.DefaultDialer.Dial(webSocketURL(server, ""), nil)
+		AssertNoError(t, err)
+
+		token := resp.Header.Get(gameTokenHeader)
+
+		sendWebSocketMessage(t, ws, "3")
+		within(t, tenMS, func() { assertWebsocketGotMsg(t, ws, "Blind is 100") })
+		ws.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseGoingAway, ""))
+		ws.Close()
+
+		resumed := createWebSocket(t, webSocketURL(server, "?token="+token+"&last=0"))
+		defer resumed.Close()
+
+		within(t, tenMS, func() { assertWebsocketGotMsg(t, resumed, "Blind is 100") })
+		sendWebSocketMessage(t, resumed, "Ruth wins")
+
+		AssertGameWinCalled(t, game, "Ruth")
+		within(t, tenMS, func() { assertWebsocketGotMsg(t, resumed, "Ruth wins") })
+
+		if game.StartCalledWith() != 3 {
+			t.Errorf("Expected the game to be started once with 3 players but got %d", game.StartCalledWith())
+		}
+	})
+
+	t.Run("Unknown game tokens are rejected", func(t *testing.T) {
+		server := httptest.NewServer(CreateNewPlayerServer(t, &StubPlayerStore{}, &SpyGame{}))
+		defer server.Close()
+
+		_, resp, err := websocket.DefaultDialer.Dial(webSocketURL(server, "?token=nope"), nil)
+
+		AssertError(t, err)
+		AssertStatusCode(t, resp.StatusCode, http.StatusNotFound)
+	})
+
+	t.Run("Clients that do not answer pings time out and the game is abandoned", func(t *testing.T) {
+		clock := NewFakeClock(time.Date(2020, 1, 1, 20, 0, 0, 0, time.UTC))
+		game := &SpyGame{}
+		playerServer := CreateNewPlayerServer(t, &StubPlayerStore{}, game)
+		playerServer.SetWebSocketOptions(WebSocketOptions{PongWait: 50 * time.Millisecond, Clock: clock})
+		server := httptest.NewServer(playerServer)
+		defer server.Close()
+
+		ws := createWebSocket(t, webSocketURL(server, ""))
+		defer ws.Close()
+
+		pinged := make(chan struct{}, 1)
+		ws.SetPingHandler(func(string) error {
+			pinged <- struct{}{}
+			return nil
+		})
+
+		closed := make(chan struct{})
+
+		go func() {
+			for {
+				if _, _, err := ws.ReadMessage(); err != nil {
+					close(closed)
+					return
+				}
+			}
+		}()
+
+		clock.BlockUntil(1)
+		clock.Advance(DefaultWebSocketOptions.PingPeriod)
+
+		within(t, time.Second, func() { <-pinged })
+		within(t, time.Second, func() { <-closed })
+
+		events := playerServer.games.Get("1")
+		abandoned := retryUntil(time.Second, func() bool {
+			clock.Advance(DefaultWebSocketOptions.ResumeTimeout)
+			return events.Finished()
+		})
+
+		if !abandoned {
+			t.Errorf("Expected the game to be abandoned")
+		}
+
+		AssertFalse(t, game.WinCalled())
+	})
+
+	t.Run("Viewers do not keep a game without players running", func(t *testing.T) {
+		clock := NewFakeClock(time.Date(2020, 1, 1, 20, 0, 0, 0, time.UTC))
+		playerServer := CreateNewPlayerServer(t, &StubPlayerStore{}, &SpyGame{})
+		playerServer.SetWebSocketOptions(WebSocketOptions{Clock: clock})
+		server := httptest.NewServer(playerServer)
+		defer server.Close()
+
+		ws := createWebSocket(t, webSocketURL(server, ""))
+		sendWebSocketMessage(t, ws, "3")
+
+		events := playerServer.games.Get("1")
+		retryUntil(500*time.Millisecond, events.Started)
+
+		_, _, stopViewing := events.Subscribe(0)
+		defer stopViewing()
+
+		ws.UnderlyingConn().Close()
+
+		abandoned := retryUntil(time.Second, func() bool {
+			clock.Advance(DefaultWebSocketOptions.ResumeTimeout)
+			return events.Finished()
+		})
+
+		if !abandoned {
+			t.Errorf("Expected the game to be abandoned while it is only viewed")
+		}
+	})
+
+	t.Run("A game taken back and lost again gets a new resume timeout", func(t *testing.T) {
+		events := NewGameEvents()
+
+		events.Join()
+		first := events.Leave()
+		events.Join()
+		second := events.Leave()
+
+		AssertFalse(t, events.Abandoned(first))
+
+		if !events.Abandoned(second) {
+			t.Errorf("Expected the game to be abandoned after the last player left")
+		}
+	})
+}
+
+func TestWebSocketOrigins(t *testing.T) {
+	playerServer := CreateNewPlayerServer(t, &StubPlayerStore{}, &SpyGame{})
+	playerServer.SetWebSocketOptions(WebSocketOptions{AllowedOrigins: []string{"https://poker.example.com/"}})
+	server := httptest.NewServer(playerServer)
+	defer server.Close()
+
+	cases := []struct {
+		origin string
+		want   int
+	}{
+		{server.URL, http.StatusSwitchingProtocols},
+		{"https://poker.example.com", http.StatusSwitchingProtocols},
+		{"https://evil.example.com", http.StatusForbidden},
+	}
+
+	for _, test := range cases {
+		t.Run(test.origin, func(t *testing.T) {
+			ws, resp, _ := websocket.DefaultDialer.Dial(webSocketURL(server, ""), http.Header{"Origin": {test.origin}})
+
+			if ws != nil {
+				ws.Close()
+			}
+
+			AssertStatusCode(t, resp.StatusCode, test.want)
+		})
+	}
+}
+
+func TestPlayerServerDrain(t *testing.T) {
+	game := &SpyGame{BlindAlert: []byte("Blind is 100")}
+	playerServer := CreateNewPlayerServer(t, &StubPlayerStore{}, game)
+	server := httptest.NewServer(playerServer)
+	defer server.Close()
+
+	ws := createWebSocket(t, webSocketURL(server, ""))
+	defer ws.Close()
+
+	sendWebSocketMessage(t, ws, "3")
+	assertWebsocketGotMsg(t, ws, "Blind is 100")
+
+	saved := &bytes.Buffer{}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	t.Run("Players are told the server is shutting down", func(t *testing.T) {
+		drained := make(chan error, 1)
+		go func() { drained <- playerServer.Drain(ctx, saved) }()
+
+		assertWebsocketGotMsg(t, ws, shutdownResult)
+
+		_, _, err := ws.ReadMessage()
+
+		if !websocket.IsCloseError(err, websocket.CloseServiceRestart) {
+			t.Errorf("Expected the websocket to be closed for a restart but got %v", err)
+		}
+
+		AssertNoError(t, <-drained)
+		AssertFalse(t, game.WinCalled())
+	})
+
+	t.Run("New games are refused", func(t *testing.T) {
+		_, resp, err := websocket.DefaultDialer.Dial(webSocketURL(server, ""), nil)
+
+		AssertError(t, err)
+		AssertStatusCode(t, resp.StatusCode, http.StatusServiceUnavailable)
+	})
+
+	t.Run("Interrupted games are loaded after a restart", func(t *testing.T) {
+		restarted := CreateNewPlayerServer(t, &StubPlayerStore{}, game)
+		AssertNoError(t, restarted.LoadGames(saved))
+
+		events := restarted.games.Get("1")
+
+		if events == nil || !events.Finished() || len(events.Events()) != 2 {
+			t.Fatalf("Expected the interrupted game to be loaded")
+		}
+	})
+
+	t.Run("Games saved by an earlier restart are saved again", func(t *testing.T) {
+		first := `[{"ID": "1", "Events": [{"ID": 1, "Type": "result", "Data": "Server shutting down"}]}]`
+
+		restarted := CreateNewPlayerServer(t, &StubPlayerStore{}, game)
+		AssertNoError(t, restarted.LoadGames(strings.NewReader(first)))
+		restarted.games.New()
+
+		second := &bytes.Buffer{}
+		AssertNoError(t, restarted.Drain(ctx, second))
+
+		var saved []SavedGame
+		AssertNoError(t, json.NewDecoder(second).Decode(&saved))
+
+		if len(saved) != 2 || saved[0].ID != "1" || saved[1].ID != "2" {
+			t.Errorf("Expected games 1 and 2 to be saved but got %+v", saved)
+		}
+	})
+}
+
+func TestPlayerServerDrainRace(t *testing.T) {
+	game := &SpyGame{}
+	playerServer := CreateNewPlayerServer(t, &StubPlayerStore{}, game)
+
+	//The keep alive of the websocket is scheduled after the draining check and before the
+	//websocket is tracked so draining there hits the window between them
+	clock := &drainingClock{Clock: NewFakeClock(time.Now()), drain: func() { playerServer.sessions.drain() }}
+	playerServer.SetWebSocketOptions(WebSocketOptions{Clock: clock})
+
+	server := httptest.NewServer(playerServer)
+	defer server.Close()
+
+	ws := createWebSocket(t, webSocketURL(server, ""))
+	defer ws.Close()
+
+	sendWebSocketMessage(t, ws, "3")
+	assertWebsocketGotMsg(t, ws, shutdownResult)
+
+	_, _, err := ws.ReadMessage()
+
+	if !websocket.IsCloseError(err, websocket.CloseServiceRestart) {
+		t.Errorf("Expected the websocket to be closed for a restart but got %v", err)
+	}
+
+	if retryUntil(100*time.Millisecond, game.StartCalled) {
+		t.Errorf("Expected the game not to be started while the server drains")
+	}
+}
+
+//drainingClock calls drain the first time something is scheduled on it
+type drainingClock struct {
+	Clock
+	drain func()
+	once  sync.Once
+}
+
+func (c *drainingClock) AfterFunc(duration time.Duration, f func()) Timer {
+	c.once.Do(c.drain)
+	return c.Clock.AfterFunc(duration, f)
+}
+
+func webSocketURL(server *httptest.Server, query string) string {
+	return "ws" + strings.TrimPrefix(server.URL, "http") + "/ws/" + query
 }
 
 func newGameRequest() *http.Request {
@@ -1,16 +1,35 @@
 package poker
 
 import (
-	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"sort"
+	"sync"
+	"time"
 )
 
+//ErrPlayerNotFound is returned when changing a player that is not in the league
+var ErrPlayerNotFound = errors.New("Player not found")
+
+//ErrPlayerExists is returned when renaming a player to a name that is already taken
+var ErrPlayerExists = errors.New("Player already exists")
+
+//AdminStore is a store whose players can be corrected by an operator
+type AdminStore interface {
+	RenamePlayer(from, to string) error
+	MergePlayers(from, into string) error
+	RevokeWins(name string, wins int) (Player, error)
+}
+
 //FileSystemPlayerStore stores the player data in files
 type FileSystemPlayerStore struct {
-	database *json.Encoder
+	database io.Writer
 	league   League
+	games    []GameResult
+	metrics  *ServerMetrics
+	mx       sync.RWMutex
 }
 
 //NewFileSystemPlayerStore is a constructor for FileSystemPlayer store that reads the database file
@@ -22,16 +41,52 @@ func NewFileSystemPlayerStore(file *os.File) (*FileSystemPlayerStore, error) {
 		return nil, fmt.Errorf("Could not initialise playerDb file %s %v", file.Name(), err)
 	}
 
-	league, err := NewLeague(file)
+	data, version, err := DecodeLeagueData(file)
 
 	if err != nil {
-		return nil, fmt.Errorf("Failed to load player storef with this file %s %v", file.Name(), err)
+		return nil, fmt.Errorf("Failed to load player storef with this file %s %w", file.Name(), err)
+	}
+
+	store := &FileSystemPlayerStore{
+		database: &tape{file},
+		league:   data.Players,
+		games:    data.Games,
+	}
+
+	if version < LeagueSchemaVersion {
+		err = store.migrate(file, version)
 	}
 
-	return &FileSystemPlayerStore{
-		database: json.NewEncoder(&tape{file}),
-		league:   league,
-	}, nil
+	if err != nil {
+		return nil, err
+	}
+
+	return store, nil
+}
+
+//migrate keeps a copy of a database file in an old format next to it and rewrites the file
+//in the current format
+func (f *FileSystemPlayerStore) migrate(file *os.File, version int) error {
+	backupName := fmt.Sprintf("%s.v%d.bak", file.Name(), version)
+	backup, err := os.OpenFile(backupName, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+
+	if err != nil {
+		return fmt.Errorf("Could not create backup %s before migrating %s %v", backupName, file.Name(), err)
+	}
+
+	defer backup.Close()
+
+	file.Seek(0, 0)
+
+	if _, err = io.Copy(backup, file); err != nil {
+		return fmt.Errorf("Could not back up %s to %s %v", file.Name(), backupName, err)
+	}
+
+	if err = f.write(); err != nil {
+		return fmt.Errorf("Could not migrate %s from version %d %v", file.Name(), version, err)
+	}
+
+	return nil
 }
 
 func initialiseDbFile(file *os.File) error {
@@ -43,7 +98,7 @@ func initialiseDbFile(file *os.File) error {
 	}
 
 	if info.Size() == 0 {
-		file.Write([]byte("[]"))
+		EncodeLeagueData(file, LeagueData{})
 		file.Seek(0, 0)
 	}
 
@@ -51,16 +106,24 @@ func initialiseDbFile(file *os.File) error {
 }
 
 //GetLeague reads the league from a file
-func (f FileSystemPlayerStore) GetLeague() League {
-	sort.Slice(f.league, func(fst, snd int) bool {
-		return f.league[fst].Wins > f.league[snd].Wins
+func (f *FileSystemPlayerStore) GetLeague() League {
+	f.mx.RLock()
+	league := make(League, len(f.league))
+	copy(league, f.league)
+	f.mx.RUnlock()
+
+	sort.SliceStable(league, func(fst, snd int) bool {
+		return league[fst].Wins > league[snd].Wins
 	})
 
-	return f.league
+	return league
 }
 
 //GetPlayerScore takes in a player name and returns their score
-func (f FileSystemPlayerStore) GetPlayerScore(name string) int {
+func (f *FileSystemPlayerStore) GetPlayerScore(name string) int {
+	f.mx.RLock()
+	defer f.mx.RUnlock()
+
 	player := f.league.Find(name)
 
 	if player == nil {
@@ -72,6 +135,9 @@ func (f FileSystemPlayerStore) GetPlayerScore(name string) int {
 
 //RecordWin updates a players win count
 func (f *FileSystemPlayerStore) RecordWin(name string) {
+	f.mx.Lock()
+	defer f.mx.Unlock()
+
 	player := f.league.Find(name)
 
 	if player != nil {
@@ -80,5 +146,208 @@ func (f *FileSystemPlayerStore) RecordWin(name string) {
 		f.league = append(f.league, Player{name, 1})
 	}
 
-	f.database.Encode(f.league)
+	f.metrics.winRecorded()
+	f.write()
+}
+
+//RecordGame stores the result of a finished game and increments the wins of its winner.
+//Every player with a known position is added to the league
+func (f *FileSystemPlayerStore) RecordGame(result GameResult) {
+	f.mx.Lock()
+	defer f.mx.Unlock()
+
+	for i, name := range result.Positions {
+		if name == "" {
+			continue
+		}
+
+		player := f.league.Find(name)
+
+		if player == nil {
+			f.league = append(f.league, Player{name, 0})
+			player = &f.league[len(f.league)-1]
+		}
+
+		if i == 0 {
+			player.Wins++
+		}
+	}
+
+	f.games = append(f.games, result)
+
+	if result.Winner() != "" {
+		f.metrics.winRecorded()
+	}
+
+	f.write()
+}
+
+//GetPlayerProfile returns the statistics of a player and false if the player is not in the league
+func (f *FileSystemPlayerStore) GetPlayerProfile(name string) (PlayerProfile, bool) {
+	f.mx.RLock()
+	defer f.mx.RUnlock()
+
+	player := f.league.Find(name)
+
+	if player == nil {
+		return PlayerProfile{}, false
+	}
+
+	return NewPlayerProfile(*player, f.games), true
+}
+
+//GetGames returns the recorded games from the newest to the oldest
+func (f *FileSystemPlayerStore) GetGames() []GameResult {
+	f.mx.RLock()
+	defer f.mx.RUnlock()
+
+	games := make([]GameResult, len(f.games))
+
+	for i, game := range f.games {
+		games[len(f.games)-1-i] = game
+	}
+
+	return games
+}
+
+//RenamePlayer changes the name of a player in the league and in the recorded games
+func (f *FileSystemPlayerStore) RenamePlayer(from, to string) error {
+	f.mx.Lock()
+	defer f.mx.Unlock()
+
+	player := f.league.Find(from)
+
+	if player == nil {
+		return fmt.Errorf("%w: %q", ErrPlayerNotFound, from)
+	}
+
+	if to == "" || f.league.Find(to) != nil {
+		return fmt.Errorf("%w: %q", ErrPlayerExists, to)
+	}
+
+	player.Name = to
+	f.renameInGames(from, to)
+
+	return f.write()
+}
+
+//MergePlayers adds the wins and games of from to into and removes from. It is used when the same
+//person played under two names
+func (f *FileSystemPlayerStore) MergePlayers(from, into string) error {
+	f.mx.Lock()
+	defer f.mx.Unlock()
+
+	if from == into {
+		return fmt.Errorf("Can not merge player %q into itself", from)
+	}
+
+	player := f.league.Find(from)
+	target := f.league.Find(into)
+
+	if player == nil {
+		return fmt.Errorf("%w: %q", ErrPlayerNotFound, from)
+	}
+
+	if target == nil {
+		return fmt.Errorf("%w: %q", ErrPlayerNotFound, into)
+	}
+
+	target.Wins += player.Wins
+	f.league = f.league.remove(from)
+	f.renameInGames(from, into)
+
+	return f.write()
+}
+
+//RevokeWins takes wins away from a player, for example after they were recorded by mistake.
+//Wins never drop below zero. It returns the player with their remaining wins
+func (f *FileSystemPlayerStore) RevokeWins(name string, wins int) (Player, error) {
+	f.mx.Lock()
+	defer f.mx.Unlock()
+
+	if wins < 1 {
+		return Player{}, fmt.Errorf("Can not revoke %d wins", wins)
+	}
+
+	player := f.league.Find(name)
+
+	if player == nil {
+		return Player{}, fmt.Errorf("%w: %q", ErrPlayerNotFound, name)
+	}
+
+	player.Wins -= wins
+
+	if player.Wins < 0 {
+		player.Wins = 0
+	}
+
+	return *player, f.write()
+}
+
+//renameInGames copies the positions it changes because GetGames shares them with its callers
+func (f *FileSystemPlayerStore) renameInGames(from, to string) {
+	for g, game := range f.games {
+		positions := make([]string, len(game.Positions))
+
+		for i, name := range game.Positions {
+			if name == from {
+				name = to
+			}
+
+			positions[i] = name
+		}
+
+		f.games[g].Positions = positions
+	}
+}
+
+//SetMetrics makes the store report its wins and writes
+func (f *FileSystemPlayerStore) SetMetrics(metrics *ServerMetrics) {
+	f.mx.Lock()
+	defer f.mx.Unlock()
+
+	f.metrics = metrics
+}
+
+//Ping returns an error when the database file has been closed and the store can not be written anymore
+func (f *FileSystemPlayerStore) Ping() error {
+	database, ok := f.database.(*tape)
+
+	if !ok {
+		return nil
+	}
+
+	if _, err := database.file.Stat(); err != nil {
+		return fmt.Errorf("Database file is not open %v", err)
+	}
+
+	return nil
+}
+
+func (f *FileSystemPlayerStore) write() error {
+	start := time.Now()
+	err := EncodeLeagueData(f.database, LeagueData{f.league, f.games})
+	f.metrics.storeWritten(time.Since(start), err)
+
+	return err
+}
+
+//Snapshot writes a point in time copy of the league to the given writer. Writes are blocked
+//while the copy is made so the snapshot is always consistent
+func (f *FileSystemPlayerStore) Snapshot(to io.Writer) error {
+	f.mx.RLock()
+	defer f.mx.RUnlock()
+
+	return EncodeLeagueData(to, LeagueData{f.league, f.games})
+}
+
+//Restore replaces the whole league with the given one and persists it to the database file
+func (f *FileSystemPlayerStore) Restore(data LeagueData) error {
+	f.mx.Lock()
+	defer f.mx.Unlock()
+
+	f.league = data.Players
+	f.games = data.Games
+
+	return f.write()
 }
@@ -1,8 +1,14 @@
 package poker
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
 	"testing"
+	"time"
 )
 
 const fileName string = "db"
@@ -113,6 +119,20 @@ func TestPlayerScore(t *testing.T) {
 	})
 }
 
+func TestPing(t *testing.T) {
+	database, cleanDatabase := CreateTempFile(t, "", fileName)
+	defer cleanDatabase()
+
+	store, err := NewFileSystemPlayerStore(database)
+	AssertNoError(t, err)
+
+	AssertNoError(t, store.Ping())
+
+	database.Close()
+
+	AssertError(t, store.Ping())
+}
+
 func TestWorksWithEmptyFiles(t *testing.T) {
 	t.Run("works with an empty file", func(t *testing.T) {
 		database, cleanDatabase := CreateTempFile(t, "", fileName)
@@ -123,3 +143,122 @@ func TestWorksWithEmptyFiles(t *testing.T) {
 		AssertNoError(t, err)
 	})
 }
+
+func TestLeagueSchemaVersion(t *testing.T) {
+	t.Run("Legacy files are backed up and upgraded to the current version", func(t *testing.T) {
+		legacy := `[{"Name": "Cleo", "Wins": 10}]`
+		database, cleanDb := CreateTempFile(t, legacy, fileName)
+		defer cleanDb()
+
+		backupName := fmt.Sprintf("%s.v1.bak", database.Name())
+		defer os.Remove(backupName)
+
+		store, err := NewFileSystemPlayerStore(database)
+		AssertNoError(t, err)
+		AssertLeague(t, store.GetLeague(), League{{"Cleo", 10}})
+
+		backup, err := ioutil.ReadFile(backupName)
+		AssertNoError(t, err)
+		AssertResponseBody(t, string(backup), legacy)
+
+		database.Seek(0, 0)
+		_, version, err := DecodeLeague(database)
+		AssertNoError(t, err)
+
+		if version != LeagueSchemaVersion {
+			t.Errorf("Expected database to be migrated to version %d but got %d", LeagueSchemaVersion, version)
+		}
+	})
+
+	t.Run("Versioned files are read without a backup", func(t *testing.T) {
+		current := fmt.Sprintf(`{"version": %d, "players": [{"Name": "Cleo", "Wins": 10}]}`, LeagueSchemaVersion)
+		database, cleanDb := CreateTempFile(t, current, fileName)
+		defer cleanDb()
+
+		store, err := NewFileSystemPlayerStore(database)
+		AssertNoError(t, err)
+		AssertLeague(t, store.GetLeague(), League{{"Cleo", 10}})
+
+		backupName := fmt.Sprintf("%s.v%d.bak", database.Name(), LeagueSchemaVersion)
+		if _, err := os.Stat(backupName); !os.IsNotExist(err) {
+			t.Errorf("Expected no backup for a file in the current version")
+		}
+	})
+
+	t.Run("Files newer than the binary are rejected", func(t *testing.T) {
+		database, cleanDb := CreateTempFile(t, `{"version": 99, "players": []}`, fileName)
+		defer cleanDb()
+
+		_, err := NewFileSystemPlayerStore(database)
+
+		if !errors.Is(err, ErrUnsupportedLeagueVersion) {
+			t.Errorf("Expected %v but got %v", ErrUnsupportedLeagueVersion, err)
+		}
+	})
+
+	t.Run("Files without a version are rejected", func(t *testing.T) {
+		_, _, err := DecodeLeague(strings.NewReader(`{"players": []}`))
+
+		AssertError(t, err)
+	})
+
+	t.Run("Written leagues are wrapped in a versioned envelope", func(t *testing.T) {
+		buffer := &bytes.Buffer{}
+
+		AssertNoError(t, EncodeLeagueData(buffer, LeagueData{Players: League{{"Cleo", 10}}}))
+		AssertResponseBody(t, buffer.String(), `{"version":3,"players":[{"Name":"Cleo","Wins":10}]}`+"\n")
+	})
+}
+
+func TestRecordGame(t *testing.T) {
+	database, cleanDb := CreateTempFile(t, `[{"Name": "Cleo", "Wins": 10}]`, fileName)
+	defer cleanDb()
+	defer os.Remove(database.Name() + ".v1.bak")
+
+	store, err := NewFileSystemPlayerStore(database)
+	AssertNoError(t, err)
+
+	playedAt := time.Date(2020, time.September, 1, 20, 0, 0, 0, time.UTC)
+	store.RecordGame(GameResult{playedAt, 4, []string{"Chris", "Cleo"}})
+
+	t.Run("The winner and every finisher are in the league", func(t *testing.T) {
+		AssertLeague(t, store.GetLeague(), League{{"Cleo", 10}, {"Chris", 1}})
+	})
+
+	t.Run("Profiles are built from the recorded games", func(t *testing.T) {
+		profile, found := store.GetPlayerProfile("Cleo")
+
+		if !found {
+			t.Fatalf("Expected a profile for Cleo")
+		}
+
+		if profile.GamesPlayed != 11 || profile.CurrentStreak != 0 || profile.AverageFieldSize != 4 {
+			t.Errorf("Unexpected profile %+v", profile)
+		}
+
+		if _, found := store.GetPlayerProfile("Missing"); found {
+			t.Errorf("Expected no profile for a player that is not in the league")
+		}
+	})
+
+	t.Run("Game results survive reopening the database", func(t *testing.T) {
+		database.Seek(0, 0)
+		reopened, err := NewFileSystemPlayerStore(database)
+		AssertNoError(t, err)
+
+		profile, _ := reopened.GetPlayerProfile("Chris")
+
+		if profile.LastPlayed == nil || !profile.LastPlayed.Equal(playedAt) {
+			t.Errorf("Expected game played at %v to be stored but got %+v", playedAt, profile)
+		}
+	})
+
+	t.Run("Games are listed from the newest", func(t *testing.T) {
+		store.RecordGame(GameResult{playedAt.Add(time.Hour), 2, []string{"Cleo", "Chris"}})
+		games := store.GetGames()
+
+		if len(games) != 2 || games[0].Winner() != "Cleo" || games[1].Winner() != "Chris" {
+			t.Errorf("Unexpected games %+v", games)
+		}
+	})
+}
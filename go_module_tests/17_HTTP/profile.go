@@ -0,0 +1,105 @@
+package poker
+
+import "time"
+
+const (
+	initialRating float64 = 1500
+	ratingFactor  float64 = 32
+)
+
+//GameResult is a finished game. Positions holds the names of the players in finishing order
+//with the winner first. Players whose position is not known are left out
+type GameResult struct {
+	PlayedAt  time.Time
+	FieldSize int
+	Positions []string
+}
+
+//Winner returns the name of the player who won the game
+func (g GameResult) Winner() string {
+	if len(g.Positions) == 0 {
+		return ""
+	}
+
+	return g.Positions[0]
+}
+
+//Position returns the finishing position of a player starting from 1 or 0 if the player is unknown.
+//Empty names mark positions whose player is not known
+func (g GameResult) Position(name string) int {
+	for i, player := range g.Positions {
+		if player == name && name != "" {
+			return i + 1
+		}
+	}
+
+	return 0
+}
+
+//PlayerProfile holds the statistics of a single player
+type PlayerProfile struct {
+	Name             string
+	Wins             int
+	GamesPlayed      int
+	WinRate          float64
+	CurrentStreak    int
+	LongestStreak    int
+	LastPlayed       *time.Time `json:",omitempty"`
+	AverageFieldSize float64
+	Rating           float64
+}
+
+//NewPlayerProfile builds the statistics of a player from their wins and the history of games.
+//Wins recorded without a game result count as games played but do not affect streaks or rating
+func NewPlayerProfile(player Player, games []GameResult) PlayerProfile {
+	profile := PlayerProfile{Name: player.Name, Wins: player.Wins, Rating: initialRating}
+
+	historyWins, fieldSizeGames, fieldSizeTotal := 0, 0, 0
+
+	for i := range games {
+		game := games[i]
+		position := game.Position(player.Name)
+
+		if position == 0 {
+			continue
+		}
+
+		profile.GamesPlayed++
+		profile.LastPlayed = &games[i].PlayedAt
+
+		if position == 1 {
+			historyWins++
+			profile.CurrentStreak++
+		} else {
+			profile.CurrentStreak = 0
+		}
+
+		if profile.CurrentStreak > profile.LongestStreak {
+			profile.LongestStreak = profile.CurrentStreak
+		}
+
+		if game.FieldSize > 0 {
+			fieldSizeGames++
+			fieldSizeTotal += game.FieldSize
+		}
+
+		if game.FieldSize > 1 {
+			score := float64(game.FieldSize-position) / float64(game.FieldSize-1)
+			profile.Rating += ratingFactor * (score - 0.5)
+		}
+	}
+
+	if player.Wins > historyWins {
+		profile.GamesPlayed += player.Wins - historyWins
+	}
+
+	if profile.GamesPlayed > 0 {
+		profile.WinRate = float64(profile.Wins) / float64(profile.GamesPlayed)
+	}
+
+	if fieldSizeGames > 0 {
+		profile.AverageFieldSize = float64(fieldSizeTotal) / float64(fieldSizeGames)
+	}
+
+	return profile
+}
@@ -0,0 +1,66 @@
+package poker
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNewPlayerProfile(t *testing.T) {
+	day := time.Date(2020, time.September, 1, 20, 0, 0, 0, time.UTC)
+
+	games := []GameResult{
+		{day, 5, []string{"Chris", "Cleo"}},
+		{day.Add(24 * time.Hour), 3, []string{"Chris"}},
+		{day.Add(48 * time.Hour), 4, []string{"Cleo", "Chris"}},
+		{day.Add(72 * time.Hour), 6, []string{"Chris"}},
+		{day.Add(96 * time.Hour), 2, []string{"Kiro"}},
+	}
+
+	t.Run("Statistics are built from the history of games", func(t *testing.T) {
+		got := NewPlayerProfile(Player{"Chris", 3}, games)
+
+		want := PlayerProfile{
+			Name:             "Chris",
+			Wins:             3,
+			GamesPlayed:      4,
+			WinRate:          0.75,
+			CurrentStreak:    1,
+			LongestStreak:    2,
+			LastPlayed:       &games[3].PlayedAt,
+			AverageFieldSize: 4.5,
+			Rating:           initialRating + 16 + 16 + 32*(2.0/3.0-0.5) + 16,
+		}
+
+		assertProfile(t, got, want)
+	})
+
+	t.Run("Wins without history count as played games", func(t *testing.T) {
+		got := NewPlayerProfile(Player{"Pepper", 4}, nil)
+
+		want := PlayerProfile{
+			Name:        "Pepper",
+			Wins:        4,
+			GamesPlayed: 4,
+			WinRate:     1,
+			Rating:      initialRating,
+		}
+
+		assertProfile(t, got, want)
+	})
+}
+
+func assertProfile(t *testing.T, got, want PlayerProfile) {
+	t.Helper()
+
+	gotLastPlayed, wantLastPlayed := got.LastPlayed, want.LastPlayed
+	got.LastPlayed, want.LastPlayed = nil, nil
+
+	if got != want {
+		t.Errorf("got profile %+v want %+v", got, want)
+	}
+
+	if (gotLastPlayed == nil) != (wantLastPlayed == nil) ||
+		(gotLastPlayed != nil && !gotLastPlayed.Equal(*wantLastPlayed)) {
+		t.Errorf("got last played %v want %v", gotLastPlayed, wantLastPlayed)
+	}
+}
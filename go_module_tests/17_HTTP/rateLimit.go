@@ -0,0 +1,123 @@
+package poker
+
+import (
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+//maxRateLimitedClients is the number of clients tracked before the ones with a full bucket are forgotten
+const maxRateLimitedClients int = 10000
+
+//RateLimit is the number of requests per second a client may make on average and how many it may
+//make at once. Requests are not limited when RequestsPerSecond is zero
+type RateLimit struct {
+	RequestsPerSecond float64
+	Burst             int
+}
+
+//bucket holds the requests a client may still make. It refills at the rate of the limit
+type bucket struct {
+	tokens  float64
+	updated time.Time
+}
+
+//RateLimiter limits the requests of every client by their IP address
+type RateLimiter struct {
+	limit   RateLimit
+	clients map[string]*bucket
+	now     func() time.Time
+	mx      sync.Mutex
+}
+
+//NewRateLimiter is a constructor for RateLimiter
+func NewRateLimiter(limit RateLimit) *RateLimiter {
+	return &RateLimiter{limit: limit.withDefaults(), clients: map[string]*bucket{}, now: time.Now}
+}
+
+func (l RateLimit) withDefaults() RateLimit {
+	if l.Burst < 1 {
+		l.Burst = 1
+	}
+
+	return l
+}
+
+//SetLimit changes the limit while requests are being served. Clients keep the requests they had left
+//up to the new burst
+func (r *RateLimiter) SetLimit(limit RateLimit) {
+	r.mx.Lock()
+	defer r.mx.Unlock()
+
+	r.limit = limit.withDefaults()
+}
+
+//Allow takes a request from the bucket of client. It returns false when the bucket is empty
+func (r *RateLimiter) Allow(client string) bool {
+	r.mx.Lock()
+	defer r.mx.Unlock()
+
+	if r.limit.RequestsPerSecond <= 0 {
+		return true
+	}
+
+	now := r.now()
+	burst := float64(r.limit.Burst)
+	b, ok := r.clients[client]
+
+	if !ok {
+		r.prune(now)
+		b = &bucket{burst, now}
+		r.clients[client] = b
+	}
+
+	b.tokens += now.Sub(b.updated).Seconds() * r.limit.RequestsPerSecond
+	b.updated = now
+
+	if b.tokens > burst {
+		b.tokens = burst
+	}
+
+	if b.tokens < 1 {
+		return false
+	}
+
+	b.tokens--
+
+	return true
+}
+
+//prune forgets the clients whose bucket refilled once too many clients are tracked
+func (r *RateLimiter) prune(now time.Time) {
+	if len(r.clients) < maxRateLimitedClients {
+		return
+	}
+
+	refill := time.Duration(float64(r.limit.Burst) / r.limit.RequestsPerSecond * float64(time.Second))
+
+	for client, b := range r.clients {
+		if now.Sub(b.updated) >= refill {
+			delete(r.clients, client)
+		}
+	}
+}
+
+//Limit responds with 429 Too Many Requests to the clients that go over the limit
+func (r *RateLimiter) Limit(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
+		client, _, err := net.SplitHostPort(req.RemoteAddr)
+
+		if err != nil {
+			client = req.RemoteAddr
+		}
+
+		if !r.Allow(client) {
+			resp.Header().Set("Retry-After", "1")
+			http.Error(resp, "Too many requests", http.StatusTooManyRequests)
+			return
+		}
+
+		next.ServeHTTP(resp, req)
+	})
+}
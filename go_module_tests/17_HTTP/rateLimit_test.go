@@ -0,0 +1,61 @@
+package poker
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestRateLimiter(t *testing.T) {
+	t.Run("Clients get their burst and then the rate", func(t *testing.T) {
+		limiter, tick := newTestRateLimiter(RateLimit{RequestsPerSecond: 2, Burst: 3})
+
+		assertAllowed(t, limiter, "cleo", true, true, true, false)
+		assertAllowed(t, limiter, "chris", true)
+
+		tick(500 * time.Millisecond)
+		assertAllowed(t, limiter, "cleo", true, false)
+	})
+
+	t.Run("A new limit applies to the following requests", func(t *testing.T) {
+		limiter, _ := newTestRateLimiter(RateLimit{RequestsPerSecond: 1, Burst: 1})
+
+		assertAllowed(t, limiter, "cleo", true, false)
+
+		limiter.SetLimit(RateLimit{})
+		assertAllowed(t, limiter, "cleo", true, true)
+	})
+
+	t.Run("Limited requests get 429", func(t *testing.T) {
+		limiter, _ := newTestRateLimiter(RateLimit{RequestsPerSecond: 1, Burst: 1})
+		handler := limiter.Limit(http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {}))
+
+		codes := []int{http.StatusOK, http.StatusTooManyRequests}
+
+		for _, want := range codes {
+			response := httptest.NewRecorder()
+			handler.ServeHTTP(response, NewLeagueRequest())
+
+			AssertStatusCode(t, response.Code, want)
+		}
+	})
+}
+
+func newTestRateLimiter(limit RateLimit) (*RateLimiter, func(time.Duration)) {
+	limiter := NewRateLimiter(limit)
+	now := time.Date(2020, time.September, 1, 20, 0, 0, 0, time.UTC)
+	limiter.now = func() time.Time { return now }
+
+	return limiter, func(d time.Duration) { now = now.Add(d) }
+}
+
+func assertAllowed(t *testing.T, limiter *RateLimiter, client string, want ...bool) {
+	t.Helper()
+
+	for i, allowed := range want {
+		if got := limiter.Allow(client); got != allowed {
+			t.Errorf("Request %d of %s got allowed %v want %v", i+1, client, got, allowed)
+		}
+	}
+}
@@ -0,0 +1,62 @@
+package poker
+
+import (
+	"context"
+	"learning/17_HTTP/logging"
+	"net/http"
+	"time"
+)
+
+//requestIDHeader carries the id of a request. Ids sent by clients or proxies are kept
+const requestIDHeader string = "X-Request-Id"
+
+//LogRequests gives every request an id, a logger that adds the id to its entries and a span when
+//tracer is not nil. The id is sent back in the X-Request-Id header and each request is logged
+//once it is served
+func LogRequests(logger *logging.Logger, tracer *logging.Tracer, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
+		id := req.Header.Get(requestIDHeader)
+
+		if id == "" {
+			id = logging.NewRequestID()
+		}
+
+		resp.Header().Set(requestIDHeader, id)
+
+		requestLogger := logger.With("requestId", id)
+
+		ctx := logging.ContextWithTraceParent(req.Context(), req.Header.Get(logging.TraceParentHeader))
+		ctx, span := tracer.Start(ctx, "HTTP "+req.Method)
+		ctx = logging.WithRequestID(logging.NewContext(ctx, requestLogger), id)
+
+		span.SetAttribute("http.method", req.Method)
+		span.SetAttribute("http.target", req.URL.Path)
+		span.SetAttribute("http.request_id", id)
+
+		recorder := &statusRecorder{ResponseWriter: resp, status: http.StatusOK}
+		start := time.Now()
+
+		next.ServeHTTP(recorder, req.WithContext(ctx))
+
+		span.SetAttribute("http.status_code", recorder.status)
+		span.End()
+
+		requestLogger.Info("Request served",
+			"method", req.Method,
+			"path", req.URL.Path,
+			"status", recorder.status,
+			"duration", time.Since(start))
+	})
+}
+
+//storeCall runs an operation on the player store inside a span of the request and logs how long
+//it took with the id of the request
+func storeCall(ctx context.Context, operation string, call func()) {
+	_, span := logging.StartSpan(ctx, "store."+operation)
+	start := time.Now()
+
+	call()
+
+	span.End()
+	logging.FromContext(ctx).Debug("Store call", "operation", operation, "duration", time.Since(start))
+}
@@ -0,0 +1,76 @@
+package poker_test
+
+import (
+	"bytes"
+	poker "learning/17_HTTP"
+	"learning/17_HTTP/logging"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestLogRequests(t *testing.T) {
+	t.Run("Requests get an id that is logged and sent back", func(t *testing.T) {
+		logs := &bytes.Buffer{}
+		server := poker.CreateNewPlayerServer(t, &poker.StubPlayerStore{}, &poker.SpyGame{})
+		handler := poker.LogRequests(logging.New(logs, logging.DebugLevel), nil, server)
+
+		response := httptest.NewRecorder()
+		handler.ServeHTTP(response, httptest.NewRequest(http.MethodPost, "/players/Pepper", nil))
+
+		id := response.Header().Get("X-Request-Id")
+
+		if id == "" {
+			t.Fatalf("Expected a request id header")
+		}
+
+		for _, msg := range []string{"Store call", "Request served"} {
+			if !strings.Contains(logs.String(), `"msg":"`+msg+`","requestId":"`+id+`"`) {
+				t.Errorf("Expected %q to be logged with the request id %s\n%s", msg, id, logs.String())
+			}
+		}
+
+		if !strings.Contains(logs.String(), `"status":202`) {
+			t.Errorf("Expected the status to be logged\n%s", logs.String())
+		}
+	})
+
+	t.Run("Ids sent by the client are kept", func(t *testing.T) {
+		server := poker.CreateNewPlayerServer(t, &poker.StubPlayerStore{}, &poker.SpyGame{})
+		handler := poker.LogRequests(logging.New(&bytes.Buffer{}, logging.InfoLevel), nil, server)
+
+		request := httptest.NewRequest(http.MethodGet, "/league/", nil)
+		request.Header.Set("X-Request-Id", "from-the-proxy")
+		response := httptest.NewRecorder()
+		handler.ServeHTTP(response, request)
+
+		if got := response.Header().Get("X-Request-Id"); got != "from-the-proxy" {
+			t.Errorf("got request id %q want %q", got, "from-the-proxy")
+		}
+	})
+
+	t.Run("Store calls are traced as children of the request", func(t *testing.T) {
+		spans := &bytes.Buffer{}
+		server := poker.CreateNewPlayerServer(t, &poker.StubPlayerStore{}, &poker.SpyGame{})
+		tracer := logging.NewTracer(spans, "poker")
+		handler := poker.LogRequests(logging.New(&bytes.Buffer{}, logging.InfoLevel), tracer, server)
+
+		request := httptest.NewRequest(http.MethodGet, "/league/", nil)
+		request.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+		handler.ServeHTTP(httptest.NewRecorder(), request)
+
+		lines := strings.Split(strings.TrimSpace(spans.String()), "\n")
+
+		if len(lines) != 2 || !strings.Contains(lines[0], `"name":"store.GetLeague"`) ||
+			!strings.Contains(lines[1], `"name":"HTTP GET"`) {
+			t.Fatalf("Expected a store span and a request span but got\n%s", spans.String())
+		}
+
+		for _, line := range lines {
+			if !strings.Contains(line, `"traceId":"4bf92f3577b34da6a3ce929d0e0e4736"`) {
+				t.Errorf("Expected the span to continue the incoming trace %s", line)
+			}
+		}
+	})
+}
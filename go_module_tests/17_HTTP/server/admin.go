@@ -0,0 +1,54 @@
+package server
+
+import (
+	"errors"
+	poker "learning/17_HTTP"
+	"net/http"
+	"net/http/pprof"
+)
+
+//NewAdminHandler routes the admin API. It serves the player corrections and running games of admin,
+//the snapshots, a POST to /admin/config/reload that calls reload, the metrics on /metrics, the build
+//information on /version and the pprof profiles under /debug/pprof/. The public router is a ServeMux
+//of its own so none of these can be reached on the public port, including the profiles
+//net/http/pprof adds to http.DefaultServeMux
+func NewAdminHandler(admin *poker.AdminServer, snapshots *poker.SnapshotServer, metrics http.Handler,
+	reload func() error) http.Handler {
+
+	router := http.NewServeMux()
+	router.Handle("/admin/players/", admin)
+	router.Handle("/admin/games", admin)
+	router.Handle("/admin/snapshots/", snapshots)
+	router.Handle("/admin/config/reload", reloadHandler(reload))
+	router.Handle("/metrics", metrics)
+	router.HandleFunc("/version", versionHandler)
+
+	router.HandleFunc("/debug/pprof/", pprof.Index)
+	router.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	router.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	router.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	router.HandleFunc("/debug/pprof/trace", pprof.Trace)
+
+	return router
+}
+
+func reloadHandler(reload func() error) http.Handler {
+	return http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
+		if req.Method != http.MethodPost {
+			resp.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+
+		err := reload()
+		var configErr *ConfigError
+
+		switch {
+		case errors.As(err, &configErr):
+			http.Error(resp, err.Error(), http.StatusUnprocessableEntity)
+		case err != nil:
+			http.Error(resp, err.Error(), http.StatusInternalServerError)
+		default:
+			resp.WriteHeader(http.StatusNoContent)
+		}
+	})
+}
@@ -0,0 +1,184 @@
+package server_test
+
+import (
+	"errors"
+	"fmt"
+	poker "learning/17_HTTP"
+	"learning/17_HTTP/metrics"
+	server "learning/17_HTTP/server"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestAdminHandler(t *testing.T) {
+	var reloadErr error
+	reloaded := 0
+
+	handler := server.NewAdminHandler(poker.NewAdminServer(&poker.FileSystemPlayerStore{}, poker.NewGameRegistry()),
+		poker.NewSnapshotServer(poker.NewSnapshotter(nil, t.TempDir(), 0)), metrics.NewRegistry(), func() error {
+			reloaded++
+			return reloadErr
+		})
+
+	cases := []struct {
+		name      string
+		method    string
+		path      string
+		reloadErr error
+		code      int
+	}{
+		{"pprof profiles are served", http.MethodGet, "/debug/pprof/", nil, http.StatusOK},
+		{"running games are served", http.MethodGet, "/admin/games", nil, http.StatusOK},
+		{"snapshots are served", http.MethodGet, "/admin/snapshots/", nil, http.StatusOK},
+		{"metrics are served", http.MethodGet, "/metrics", nil, http.StatusOK},
+		{"the version is served", http.MethodGet, "/version", nil, http.StatusOK},
+		{"the configuration is reloaded", http.MethodPost, "/admin/config/reload", nil, http.StatusNoContent},
+		{"an invalid configuration is rejected", http.MethodPost, "/admin/config/reload",
+			&server.ConfigError{Section: "logging", Err: errors.New("Unknown log level")}, http.StatusUnprocessableEntity},
+		{"reloading needs a POST", http.MethodGet, "/admin/config/reload", nil, http.StatusMethodNotAllowed},
+	}
+
+	for _, test := range cases {
+		t.Run(test.name, func(t *testing.T) {
+			reloadErr = test.reloadErr
+			response := httptest.NewRecorder()
+
+			handler.ServeHTTP(response, httptest.NewRequest(test.method, test.path, nil))
+
+			poker.AssertStatusCode(t, response.Code, test.code)
+		})
+	}
+
+	if reloaded != 2 {
+		t.Errorf("Expected the configuration to be reloaded twice but it was %d times", reloaded)
+	}
+}
+
+func TestAdminPort(t *testing.T) {
+	t.Run("The admin API can not share the public port", func(t *testing.T) {
+		dir := t.TempDir()
+		_, err := server.CreateDefaultApplication(writeConfig(t, dir, testConfig{
+			DbFileName: filepath.Join(dir, "game.db.json"),
+			Port:       "127.0.0.1:8000",
+			AdminPort:  "127.0.0.1:8000",
+		}))
+
+		assertErrorAs(t, err, new(*server.ConfigError))
+	})
+
+	t.Run("The admin API is only served on the admin port", func(t *testing.T) {
+		//Registers the SIGINT handler before the signal is sent so it can not kill the test
+		server.GenerateContextWithSigint()
+
+		dir := t.TempDir()
+		port, adminPort := freeAddress(t), freeAddress(t)
+		app, err := server.CreateDefaultApplication(writeConfig(t, dir, testConfig{
+			DbFileName: filepath.Join(dir, "game.db.json"),
+			Port:       port,
+			AdminPort:  adminPort,
+		}))
+		poker.AssertNoError(t, err)
+
+		done := make(chan error)
+		go func() { done <- app.Start() }()
+
+		waitUntilServing(t, adminPort)
+
+		//Connections dialed by a keep-alive client but never used would hold up the shutdown
+		client := &http.Client{Transport: &http.Transport{DisableKeepAlives: true}}
+
+		for _, path := range []string{"/admin/games", "/admin/snapshots/", "/debug/pprof/", "/metrics", "/version"} {
+			response, err := client.Get("http://" + port + path)
+			poker.AssertNoError(t, err)
+			response.Body.Close()
+
+			poker.AssertStatusCode(t, response.StatusCode, http.StatusNotFound)
+		}
+
+		response, err := client.Post("http://"+adminPort+"/admin/config/reload", "", nil)
+		poker.AssertNoError(t, err)
+		response.Body.Close()
+
+		poker.AssertStatusCode(t, response.StatusCode, http.StatusNoContent)
+
+		response, err = client.Get("http://" + adminPort + "/metrics")
+		poker.AssertNoError(t, err)
+		response.Body.Close()
+
+		poker.AssertStatusCode(t, response.StatusCode, http.StatusOK)
+
+		syscall.Kill(syscall.Getpid(), syscall.SIGINT)
+		poker.AssertNoError(t, <-done)
+	})
+
+	t.Run("The admin API is not served without an admin port", func(t *testing.T) {
+		server.GenerateContextWithSigint()
+
+		dir := t.TempDir()
+		port := freeAddress(t)
+		app, err := server.CreateDefaultApplication(writeConfig(t, dir, testConfig{
+			DbFileName: filepath.Join(dir, "game.db.json"),
+			Port:       port,
+		}))
+		poker.AssertNoError(t, err)
+
+		done := make(chan error)
+		go func() { done <- app.Start() }()
+
+		waitUntilServing(t, port)
+
+		client := &http.Client{Transport: &http.Transport{DisableKeepAlives: true}}
+		requests := []struct {
+			method string
+			path   string
+		}{
+			{http.MethodGet, "/admin/games"},
+			{http.MethodGet, "/admin/snapshots/"},
+			{http.MethodPost, "/admin/snapshots/"},
+			{http.MethodPost, "/admin/snapshots/restore/snapshot.json"},
+			{http.MethodPost, "/admin/config/reload"},
+		}
+
+		for _, r := range requests {
+			request, _ := http.NewRequest(r.method, "http://"+port+r.path, nil)
+			response, err := client.Do(request)
+			poker.AssertNoError(t, err)
+			response.Body.Close()
+
+			poker.AssertStatusCode(t, response.StatusCode, http.StatusNotFound)
+		}
+
+		syscall.Kill(syscall.Getpid(), syscall.SIGINT)
+		poker.AssertNoError(t, <-done)
+	})
+}
+
+//waitUntilServing waits for a server to accept connections on addr
+func waitUntilServing(t *testing.T, addr string) {
+	t.Helper()
+
+	for deadline := time.Now().Add(time.Second); time.Now().Before(deadline); time.Sleep(5 * time.Millisecond) {
+		if conn, err := net.Dial("tcp", addr); err == nil {
+			conn.Close()
+			return
+		}
+	}
+
+	t.Fatalf("Nothing is serving on %s", addr)
+}
+
+//freeAddress returns a local address that nothing listens on
+func freeAddress(t *testing.T) string {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", localAddress)
+	poker.AssertNoError(t, err)
+	defer listener.Close()
+
+	return fmt.Sprint(listener.Addr())
+}
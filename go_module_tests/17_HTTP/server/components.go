@@ -0,0 +1,73 @@
+package server
+
+import (
+	"context"
+	"learning/17_HTTP/logging"
+	"net"
+	"net/http"
+)
+
+//Names of the components registered by the application
+const (
+	StoreComponent        string = "store"
+	HTTPComponent         string = "http"
+	RedirectComponent     string = "redirect"
+	CertificatesComponent string = "certificates"
+	GamesComponent        string = "games"
+	TracesComponent       string = "traces"
+	AdminComponent        string = "admin"
+	ConfigComponent       string = "config"
+)
+
+//serverComponent serves on a listener from Start until Stop. Its socket is handed off to a new
+//process under name
+type serverComponent struct {
+	server   Server
+	name     string
+	addr     string
+	message  string
+	listener net.Listener
+	failed   chan<- error
+}
+
+//Start listens and serves in the background. Errors while serving are sent to failed
+func (s *serverComponent) Start(ctx context.Context) error {
+	listener, err := Listen(s.name, s.addr)
+
+	if err != nil {
+		return &BindError{s.addr, err}
+	}
+
+	s.listener = listener
+
+	go func() {
+		if err := s.server.Serve(listener); err != nil && err != http.ErrServerClosed {
+			s.failed <- err
+		}
+	}()
+
+	logging.Default().Info(s.message, "address", listener.Addr().String())
+
+	return nil
+}
+
+//Stop waits for the requests being served until ctx is done
+func (s *serverComponent) Stop(ctx context.Context) error {
+	return s.server.Shutdown(ctx)
+}
+
+//certificatesComponent reloads the certificate of the server on SIGHUP while it runs
+type certificatesComponent struct {
+	reloader      *CertificateReloader
+	stopReloading func()
+}
+
+func (c *certificatesComponent) Start(ctx context.Context) error {
+	c.stopReloading = ReloadOnSighup(c.reloader)
+	return nil
+}
+
+func (c *certificatesComponent) Stop(ctx context.Context) error {
+	c.stopReloading()
+	return nil
+}
@@ -0,0 +1,65 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	configuration "learning/17_HTTP/config"
+	"path/filepath"
+	"text/tabwriter"
+
+	"github.com/spf13/pflag"
+)
+
+//ValidateConfig reads a configuration file and the flags that override it and writes every problem
+//of the server and database configuration to out. It returns a ConfigError when the file can not be
+//read or is invalid
+func ValidateConfig(configFileName, configFilePath string, flags *pflag.FlagSet, out io.Writer) error {
+	conf, err := readConfiguration(configFileName, configFilePath, flags)
+
+	if err != nil {
+		fmt.Fprintf(out, "Could not read the configuration %v\n", err)
+		return err
+	}
+
+	err = conf.Validate()
+	var invalid *configuration.ValidationError
+
+	if errors.As(err, &invalid) {
+		for _, problem := range invalid.Problems {
+			fmt.Fprintln(out, problem)
+		}
+
+		return &ConfigError{"server and database", err}
+	}
+
+	fmt.Fprintf(out, "Configuration %s in %s is valid\n", configFileName, configFilePath)
+
+	return nil
+}
+
+//DumpConfig writes the effective value of every key and where it comes from to out. Secrets are
+//redacted. It returns a ConfigError when the file can not be read
+func DumpConfig(configFileName, configFilePath string, flags *pflag.FlagSet, out io.Writer) error {
+	conf, err := readConfiguration(configFileName, configFilePath, flags)
+
+	if err != nil {
+		return err
+	}
+
+	writer := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(writer, "KEY\tVALUE\tSOURCE")
+
+	for _, setting := range conf.Dump() {
+		fmt.Fprintf(writer, "%s\t%s\t%s\n", setting.Key, setting.Value, setting.Source)
+	}
+
+	return writer.Flush()
+}
+
+//SplitConfigPath splits the path of a configuration file into the name and the directory that
+//ValidateConfig and CreateDefaultApplication expect. A name with an extension is read as that
+//exact file, one without it is looked up with every supported extension
+func SplitConfigPath(path string) (configFileName, configFilePath string) {
+	return filepath.Base(path), filepath.Dir(path)
+}
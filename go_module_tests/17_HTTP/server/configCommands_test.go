@@ -0,0 +1,99 @@
+package server_test
+
+import (
+	"bytes"
+	poker "learning/17_HTTP"
+	configuration "learning/17_HTTP/config"
+	server "learning/17_HTTP/server"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestValidateConfig(t *testing.T) {
+	t.Run("A valid configuration is reported as valid", func(t *testing.T) {
+		dir := t.TempDir()
+		out := &bytes.Buffer{}
+
+		name, path := writeConfig(t, dir, testConfig{DbFileName: filepath.Join(dir, "game.db.json")})
+		poker.AssertNoError(t, server.ValidateConfig(name, path, nil, out))
+
+		if !strings.Contains(out.String(), "is valid") {
+			t.Errorf("got output %q want it to report a valid configuration", out.String())
+		}
+	})
+
+	t.Run("Every problem is written on its own line", func(t *testing.T) {
+		dir := t.TempDir()
+		out := &bytes.Buffer{}
+
+		name, path := writeConfig(t, dir, testConfig{
+			DbFileName: filepath.Join(dir, "missing", "game.db.json"),
+			Port:       "localhost",
+			AdminPort:  "127.0.0.1:70000",
+		})
+
+		assertErrorAs(t, server.ValidateConfig(name, path, nil, out), new(*server.ConfigError))
+
+		lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+
+		if len(lines) != 3 {
+			t.Fatalf("got %d problems want 3 in %q", len(lines), out.String())
+		}
+
+		source := "(file " + filepath.Join(dir, "testConfig.yaml") + ")"
+
+		for i, key := range []string{"server.port", "server.adminPort", "database.fileName"} {
+			if !strings.HasPrefix(lines[i], key+" "+source+": ") {
+				t.Errorf("got %q want the problem of %s %s", lines[i], key, source)
+			}
+		}
+	})
+
+	t.Run("A missing file is a config error", func(t *testing.T) {
+		assertErrorAs(t, server.ValidateConfig("missing", t.TempDir(), nil, &bytes.Buffer{}), new(*server.ConfigError))
+	})
+}
+
+func TestDumpConfig(t *testing.T) {
+	dir := t.TempDir()
+	out := &bytes.Buffer{}
+	flags := configuration.NewFlagSet("test")
+	poker.AssertNoError(t, flags.Parse([]string{"--log-level", "debug"}))
+
+	name, path := writeConfig(t, dir, testConfig{DbFileName: filepath.Join(dir, "game.db.json")})
+	poker.AssertNoError(t, server.DumpConfig(name, path, flags, out))
+
+	got := map[string]string{}
+
+	for _, line := range strings.Split(out.String(), "\n") {
+		if fields := strings.Fields(line); len(fields) > 1 {
+			got[fields[0]] = strings.Join(fields[1:], " ")
+		}
+	}
+
+	want := map[string]string{
+		"logging.level":     "debug flag --log-level",
+		"database.filename": filepath.Join(dir, "game.db.json") + " file " + filepath.Join(dir, "testConfig.yaml"),
+	}
+
+	for key, setting := range want {
+		if got[key] != setting {
+			t.Errorf("got %s %q want %q", key, got[key], setting)
+		}
+	}
+}
+
+func TestSplitConfigPath(t *testing.T) {
+	for path, want := range map[string][]string{
+		"config/viperConfig.yaml": {"viperConfig.yaml", "config"},
+		"./config/viperConfig":    {"viperConfig", "config"},
+	} {
+		name, dir := server.SplitConfigPath(path)
+
+		if got := []string{name, dir}; !reflect.DeepEqual(got, want) {
+			t.Errorf("got %v want %v", got, want)
+		}
+	}
+}
@@ -0,0 +1,127 @@
+package server
+
+import (
+	"context"
+	viperRepo "learning/17_HTTP/config/viper"
+	"learning/17_HTTP/logging"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+//configSettleTime is how long the config file must stay unchanged before it is reloaded. Editors
+//and deployment tools often write a file in several steps
+const configSettleTime = 100 * time.Millisecond
+
+//ReloadSignals make the application reload its configuration file
+var ReloadSignals = []os.Signal{syscall.SIGHUP}
+
+//configWatcher calls reload when the configuration file or one of the fragments next to it changes
+//or one of the ReloadSignals is received. The directories are watched because many tools replace a
+//file instead of writing to it
+type configWatcher struct {
+	name    string
+	dir     string
+	reload  func() error
+	watcher *fsnotify.Watcher
+	signals chan os.Signal
+	done    chan struct{}
+	stopped chan struct{}
+}
+
+//newConfigWatcher watches the configuration file with the given name and any extension in dir and
+//the fragments in its conf.d directory when there is one
+func newConfigWatcher(name, dir string, reload func() error) *configWatcher {
+	return &configWatcher{name: name, dir: dir, reload: reload}
+}
+
+func (c *configWatcher) Start(ctx context.Context) error {
+	watcher, err := fsnotify.NewWatcher()
+
+	if err != nil {
+		return err
+	}
+
+	if err := watcher.Add(c.dir); err != nil {
+		watcher.Close()
+		return err
+	}
+
+	if info, err := os.Stat(c.fragmentDir()); err == nil && info.IsDir() {
+		if err := watcher.Add(c.fragmentDir()); err != nil {
+			watcher.Close()
+			return err
+		}
+	}
+
+	c.watcher = watcher
+	c.signals = make(chan os.Signal, 1)
+	c.done = make(chan struct{})
+	c.stopped = make(chan struct{})
+
+	signal.Notify(c.signals, ReloadSignals...)
+	go c.watch()
+
+	return nil
+}
+
+func (c *configWatcher) Stop(ctx context.Context) error {
+	signal.Stop(c.signals)
+	close(c.done)
+	<-c.stopped
+
+	return c.watcher.Close()
+}
+
+func (c *configWatcher) watch() {
+	defer close(c.stopped)
+
+	settle := time.NewTimer(configSettleTime)
+	settle.Stop()
+
+	for {
+		select {
+		case event := <-c.watcher.Events:
+			if c.isConfigFile(event.Name) && event.Op&(fsnotify.Write|fsnotify.Create|fsnotify.Rename) != 0 {
+				settle.Reset(configSettleTime)
+			}
+		case err := <-c.watcher.Errors:
+			logging.Default().Error("Could not watch the configuration file", "error", err)
+		case sig := <-c.signals:
+			logging.Default().Info("System call", "signal", sig)
+			c.reloadAndLog()
+		case <-settle.C:
+			c.reloadAndLog()
+		case <-c.done:
+			settle.Stop()
+			return
+		}
+	}
+}
+
+func (c *configWatcher) isConfigFile(fileName string) bool {
+	if filepath.Dir(fileName) == c.fragmentDir() {
+		return viperRepo.IsConfigType(strings.TrimPrefix(filepath.Ext(fileName), "."))
+	}
+
+	return withoutExt(filepath.Base(fileName)) == withoutExt(c.name)
+}
+
+func (c *configWatcher) fragmentDir() string {
+	return filepath.Join(c.dir, viperRepo.ConfigDir)
+}
+
+func withoutExt(name string) string {
+	return strings.TrimSuffix(name, filepath.Ext(name))
+}
+
+func (c *configWatcher) reloadAndLog() {
+	if err := c.reload(); err != nil {
+		logging.Default().Error("Keeping the running configuration", "error", err)
+	}
+}
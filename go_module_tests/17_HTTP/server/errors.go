@@ -0,0 +1,65 @@
+package server
+
+import "fmt"
+
+//ConfigError is returned when the configuration can not be read or a section of it is invalid.
+//Section is the key of the section like "tournament"
+type ConfigError struct {
+	Section string
+	Err     error
+}
+
+func (c *ConfigError) Error() string {
+	return fmt.Sprintf("Invalid %s configuration %v", c.Section, c.Err)
+}
+
+func (c *ConfigError) Unwrap() error {
+	return c.Err
+}
+
+//StoreError is returned when the player store or the saved games can not be opened
+type StoreError struct {
+	FileName string
+	Err      error
+}
+
+func (s *StoreError) Error() string {
+	return fmt.Sprintf("Could not open the store %s %v", s.FileName, s.Err)
+}
+
+func (s *StoreError) Unwrap() error {
+	return s.Err
+}
+
+//TemplateError is returned when the templates or static files can not be loaded. Dir is empty for
+//the embedded ones
+type TemplateError struct {
+	Dir string
+	Err error
+}
+
+func (t *TemplateError) Error() string {
+	if t.Dir == "" {
+		return fmt.Sprintf("Could not load the embedded templates %v", t.Err)
+	}
+
+	return fmt.Sprintf("Could not load the templates in %s %v", t.Dir, t.Err)
+}
+
+func (t *TemplateError) Unwrap() error {
+	return t.Err
+}
+
+//BindError is returned when a server can not listen on its address
+type BindError struct {
+	Addr string
+	Err  error
+}
+
+func (b *BindError) Error() string {
+	return fmt.Sprintf("Could not listen on %s %v", b.Addr, b.Err)
+}
+
+func (b *BindError) Unwrap() error {
+	return b.Err
+}
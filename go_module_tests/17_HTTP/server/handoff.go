@@ -0,0 +1,208 @@
+package server
+
+import (
+	"fmt"
+	"io/ioutil"
+	"learning/17_HTTP/logging"
+	"net"
+	"os"
+	"os/exec"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+//ListenersEnv tells a process started by a restart which listening sockets it inherited. Every
+//socket is named after the component that listened on it like "http=3,admin=4,redirect=5" where
+//the number is its file descriptor
+const ListenersEnv string = "POKER_LISTENERS"
+
+//ReleaseEnv tells a process started by a restart the file descriptor of the pipe it inherited.
+//The pipe is closed once the process that restarted it closed the store
+const ReleaseEnv string = "POKER_RELEASE"
+
+//firstInheritedFd is the file descriptor of the first socket passed with exec.Cmd.ExtraFiles
+const firstInheritedFd = 3
+
+//Listen returns the socket of the component name inherited from the process that restarted this
+//one when it listens on addr. Otherwise it returns a new socket listening on addr. An inherited
+//socket on another address, as when the address was changed before the restart, is closed
+func Listen(name, addr string) (net.Listener, error) {
+	inherited := inheritedListeners()
+	fd, ok := inherited[name]
+
+	if !ok {
+		return net.Listen("tcp", addr)
+	}
+
+	delete(inherited, name)
+	os.Setenv(ListenersEnv, encodeListeners(inherited))
+
+	file := os.NewFile(uintptr(fd), name)
+	listener, err := net.FileListener(file)
+	file.Close()
+
+	if err != nil {
+		return nil, fmt.Errorf("Could not use inherited listener %s %v", name, err)
+	}
+
+	if !listensOn(listener, addr) {
+		logging.Default().Info("Inherited listener is not on the configured address", "component", name,
+			"inherited", listener.Addr().String(), "address", addr)
+		listener.Close()
+
+		return net.Listen("tcp", addr)
+	}
+
+	return listener, nil
+}
+
+//CloseInheritedListeners closes the inherited sockets that no component listened on, like the one
+//of a server that was turned off before the restart
+func CloseInheritedListeners() {
+	for name, fd := range inheritedListeners() {
+		os.NewFile(uintptr(fd), name).Close()
+	}
+
+	os.Unsetenv(ListenersEnv)
+}
+
+//inheritedListeners returns the file descriptor of every inherited socket that was not used yet
+func inheritedListeners() map[string]int {
+	inherited := map[string]int{}
+
+	for _, pair := range strings.Split(os.Getenv(ListenersEnv), ",") {
+		parts := strings.SplitN(pair, "=", 2)
+
+		if len(parts) != 2 {
+			continue
+		}
+
+		if fd, err := strconv.Atoi(parts[1]); err == nil && fd >= firstInheritedFd {
+			inherited[parts[0]] = fd
+		}
+	}
+
+	return inherited
+}
+
+//encodeListeners is the value of ListenersEnv for the sockets with the given file descriptors
+func encodeListeners(listeners map[string]int) string {
+	var pairs []string
+
+	for name, fd := range listeners {
+		pairs = append(pairs, fmt.Sprintf("%s=%d", name, fd))
+	}
+
+	sort.Strings(pairs)
+
+	return strings.Join(pairs, ",")
+}
+
+//listensOn tells if listener listens on addr. A zero port matches any port and a host that
+//listens on every address only matches a listener that does too
+func listensOn(listener net.Listener, addr string) bool {
+	actual, ok := listener.Addr().(*net.TCPAddr)
+	wanted, err := net.ResolveTCPAddr("tcp", addr)
+
+	if !ok || err != nil {
+		return false
+	}
+
+	if wanted.Port != 0 && wanted.Port != actual.Port {
+		return false
+	}
+
+	if wanted.IP == nil || wanted.IP.IsUnspecified() {
+		return actual.IP.IsUnspecified()
+	}
+
+	return wanted.IP.Equal(actual.IP)
+}
+
+//listenerFiles duplicates the sockets of the listeners so they stay open once the servers stop
+func listenerFiles(listeners []net.Listener) ([]*os.File, error) {
+	var files []*os.File
+
+	for _, listener := range listeners {
+		filer, ok := listener.(interface{ File() (*os.File, error) })
+
+		if !ok {
+			closeFiles(files)
+			return nil, fmt.Errorf("Listener %T can not be handed off", listener)
+		}
+
+		file, err := filer.File()
+
+		if err != nil {
+			closeFiles(files)
+			return nil, fmt.Errorf("Could not duplicate listener %s %v", listener.Addr(), err)
+		}
+
+		files = append(files, file)
+	}
+
+	return files, nil
+}
+
+//WaitForRelease blocks until the process that restarted this one closed the store so both never
+//use it at the same time. Processes that were not started by a restart do not wait
+func WaitForRelease() {
+	fd, err := strconv.Atoi(os.Getenv(ReleaseEnv))
+
+	if err != nil || fd < firstInheritedFd {
+		return
+	}
+
+	defer os.Unsetenv(ReleaseEnv)
+
+	pipe := os.NewFile(uintptr(fd), "release")
+	defer pipe.Close()
+
+	//Returns once the other end is closed, also when the process that restarted this one died
+	ioutil.ReadAll(pipe)
+}
+
+//startProcess runs the binary of this process again with the same arguments and passes it the
+//listening sockets under the names of their components. The new process waits in WaitForRelease
+//until release is called. It returns the pid of the new process
+func startProcess(names []string, files []*os.File) (pid int, release func(), err error) {
+	executable, err := os.Executable()
+
+	if err != nil {
+		return 0, nil, fmt.Errorf("Could not find the executable %v", err)
+	}
+
+	reader, writer, err := os.Pipe()
+
+	if err != nil {
+		return 0, nil, fmt.Errorf("Could not create the release pipe %v", err)
+	}
+
+	defer reader.Close()
+
+	inherited := map[string]int{}
+
+	for i, name := range names {
+		inherited[name] = firstInheritedFd + i
+	}
+
+	cmd := exec.Command(executable, os.Args[1:]...)
+	cmd.Env = append(os.Environ(), ListenersEnv+"="+encodeListeners(inherited),
+		fmt.Sprintf("%s=%d", ReleaseEnv, firstInheritedFd+len(files)))
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	cmd.ExtraFiles = append(append([]*os.File{}, files...), reader)
+
+	if err := cmd.Start(); err != nil {
+		writer.Close()
+		return 0, nil, fmt.Errorf("Could not start %s %v", executable, err)
+	}
+
+	return cmd.Process.Pid, func() { writer.Close() }, nil
+}
+
+func closeFiles(files []*os.File) {
+	for _, file := range files {
+		file.Close()
+	}
+}
@@ -0,0 +1,291 @@
+//go:build linux
+// +build linux
+
+package server_test
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	poker "learning/17_HTTP"
+	"learning/17_HTTP/logging"
+	server "learning/17_HTTP/server"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+//handoffHelperEnv makes the test binary run TestHandoffHelperProcess as a poker server
+const handoffHelperEnv string = "POKER_HANDOFF_HELPER"
+
+//storeClosed is logged by TestHandoffHelperProcess when it closes its store
+const storeClosed string = "Store closed"
+
+//TestHandoffHelperProcess is not a real test. It is the server process started by TestListenerHandoff
+//and restarted by its SIGUSR2
+func TestHandoffHelperProcess(t *testing.T) {
+	if os.Getenv(handoffHelperEnv) == "" {
+		t.Skip("Only runs as the process started by TestListenerHandoff")
+	}
+
+	router := http.NewServeMux()
+	router.HandleFunc("/pid", func(resp http.ResponseWriter, req *http.Request) {
+		fmt.Fprint(resp, os.Getpid())
+	})
+	router.HandleFunc("/slow", func(resp http.ResponseWriter, req *http.Request) {
+		time.Sleep(500 * time.Millisecond)
+		fmt.Fprint(resp, os.Getpid())
+	})
+
+	server.WaitForRelease()
+
+	conf := &SpyConfiguration{dbFileName: poker.TestDbFileName, serverPort: localAddress}
+	app := server.CreateApplication(conf, &http.Server{Handler: router}, func() {
+		logging.Default().Info(storeClosed)
+	})
+	app.Start()
+
+	os.Exit(0)
+}
+
+func TestListenerHandoff(t *testing.T) {
+	logFile := filepath.Join(t.TempDir(), "server.log")
+	logs, err := os.Create(logFile)
+	poker.AssertNoError(t, err)
+	defer logs.Close()
+
+	parent := exec.Command(os.Args[0], "-test.run=^TestHandoffHelperProcess$")
+	parent.Env = append(os.Environ(), handoffHelperEnv+"=1")
+	parent.Stdout, parent.Stderr = logs, logs
+	poker.AssertNoError(t, parent.Start())
+
+	exited := make(chan error, 1)
+	go func() { exited <- parent.Wait() }()
+
+	address := waitForAddress(t, logFile)
+	client := &http.Client{Transport: &http.Transport{DisableKeepAlives: true}, Timeout: 5 * time.Second}
+	child := 0
+
+	defer func() {
+		parent.Process.Kill()
+
+		if child != 0 {
+			syscall.Kill(child, syscall.SIGKILL)
+		}
+	}()
+
+	if pid := getPid(t, client, "http://"+address+"/pid"); pid != parent.Process.Pid {
+		t.Fatalf("Expected the parent %d to serve but got %d", parent.Process.Pid, pid)
+	}
+
+	slow := make(chan int, 1)
+	go func() { slow <- getPid(t, client, "http://"+address+"/slow") }()
+	time.Sleep(100 * time.Millisecond)
+
+	poker.AssertNoError(t, parent.Process.Signal(syscall.SIGUSR2))
+
+	t.Run("Requests in flight complete", func(t *testing.T) {
+		if pid := <-slow; pid != parent.Process.Pid {
+			t.Errorf("Expected the parent %d to finish the slow request but got %d", parent.Process.Pid, pid)
+		}
+	})
+
+	t.Run("The parent exits", func(t *testing.T) {
+		select {
+		case err := <-exited:
+			poker.AssertNoError(t, err)
+		case <-time.After(5 * time.Second):
+			t.Fatalf("Expected the parent to exit after handing the listener off")
+		}
+	})
+
+	t.Run("The child serves on the same address", func(t *testing.T) {
+		child = getPid(t, client, "http://"+address+"/pid")
+
+		if child == parent.Process.Pid {
+			t.Errorf("Expected a new process to serve but got the parent %d", child)
+		}
+	})
+
+	t.Run("The child starts once the parent closed the store", func(t *testing.T) {
+		content, _ := ioutil.ReadFile(logFile)
+		var messages []string
+		scanner := bufio.NewScanner(strings.NewReader(string(content)))
+
+		for scanner.Scan() {
+			var entry struct{ Msg string }
+
+			if json.Unmarshal(scanner.Bytes(), &entry) == nil {
+				messages = append(messages, entry.Msg)
+			}
+		}
+
+		want := []string{"Server started", "Handed the listeners off", storeClosed, "Server started"}
+
+		if !containsInOrder(messages, want) {
+			t.Errorf("Expected the logs to contain %v in order but got %v", want, messages)
+		}
+	})
+}
+
+func TestListen(t *testing.T) {
+	defer os.Unsetenv(server.ListenersEnv)
+
+	t.Run("An inherited socket on the configured address is used", func(t *testing.T) {
+		original := listenLocally(t)
+		defer original.Close()
+
+		os.Setenv(server.ListenersEnv, fmt.Sprintf("admin=%d", inherit(t, original)))
+
+		listener, err := server.Listen("admin", original.Addr().String())
+		poker.AssertNoError(t, err)
+		defer listener.Close()
+
+		assertSameAddress(t, listener.Addr(), original.Addr())
+	})
+
+	t.Run("Sockets are matched by the name of their component", func(t *testing.T) {
+		original := listenLocally(t)
+		defer original.Close()
+
+		os.Setenv(server.ListenersEnv, fmt.Sprintf("admin=%d", inherit(t, original)))
+		defer server.CloseInheritedListeners()
+
+		listener, err := server.Listen("http", localAddress)
+		poker.AssertNoError(t, err)
+		defer listener.Close()
+
+		if listener.Addr().String() == original.Addr().String() {
+			t.Errorf("Expected the http server to get a new socket but got the one of the admin API")
+		}
+	})
+
+	t.Run("An inherited socket on another address is replaced", func(t *testing.T) {
+		original := listenLocally(t)
+		defer original.Close()
+
+		os.Setenv(server.ListenersEnv, fmt.Sprintf("http=%d", inherit(t, original)))
+		addr := freeAddress(t)
+
+		listener, err := server.Listen("http", addr)
+		poker.AssertNoError(t, err)
+		defer listener.Close()
+
+		if listener.Addr().String() != addr {
+			t.Errorf("got a socket on %s want one on %s", listener.Addr(), addr)
+		}
+	})
+
+	t.Run("Inherited sockets that are not used are closed", func(t *testing.T) {
+		original := listenLocally(t)
+		defer original.Close()
+
+		fd := inherit(t, original)
+		os.Setenv(server.ListenersEnv, fmt.Sprintf("redirect=%d", fd))
+
+		server.CloseInheritedListeners()
+
+		var stat syscall.Stat_t
+
+		if err := syscall.Fstat(fd, &stat); err != syscall.EBADF {
+			t.Errorf("Expected the socket of the redirect server to be closed but got %v", err)
+		}
+
+		if os.Getenv(server.ListenersEnv) != "" {
+			t.Errorf("Expected %s to be unset", server.ListenersEnv)
+		}
+	})
+}
+
+func listenLocally(t *testing.T) net.Listener {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", localAddress)
+	poker.AssertNoError(t, err)
+
+	return listener
+}
+
+//inherit duplicates the socket of listener like a restart passes it to a new process
+func inherit(t *testing.T, listener net.Listener) int {
+	t.Helper()
+
+	file, err := listener.(*net.TCPListener).File()
+	poker.AssertNoError(t, err)
+	defer file.Close()
+
+	fd, err := syscall.Dup(int(file.Fd()))
+	poker.AssertNoError(t, err)
+
+	return fd
+}
+
+func assertSameAddress(t *testing.T, got, want net.Addr) {
+	t.Helper()
+
+	if got.String() != want.String() {
+		t.Errorf("got a socket on %s want the inherited one on %s", got, want)
+	}
+}
+
+//containsInOrder tells if want is a subsequence of got
+func containsInOrder(got, want []string) bool {
+	for _, message := range got {
+		if len(want) > 0 && message == want[0] {
+			want = want[1:]
+		}
+	}
+
+	return len(want) == 0
+}
+
+//waitForAddress reads the address from the first "Server started" entry of the log
+func waitForAddress(t *testing.T, logFile string) string {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+
+	for time.Now().Before(deadline) {
+		content, _ := ioutil.ReadFile(logFile)
+		scanner := bufio.NewScanner(strings.NewReader(string(content)))
+
+		for scanner.Scan() {
+			var entry struct{ Msg, Address string }
+
+			if json.Unmarshal(scanner.Bytes(), &entry) == nil && entry.Msg == "Server started" {
+				return entry.Address
+			}
+		}
+
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	t.Fatalf("The server did not start")
+	return ""
+}
+
+func getPid(t *testing.T, client *http.Client, url string) int {
+	t.Helper()
+
+	resp, err := client.Get(url)
+
+	if err != nil {
+		t.Errorf("Could not get %s %v", url, err)
+		return 0
+	}
+
+	defer resp.Body.Close()
+
+	body, _ := ioutil.ReadAll(resp.Body)
+	pid, _ := strconv.Atoi(string(body))
+
+	return pid
+}
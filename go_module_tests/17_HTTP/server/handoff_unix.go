@@ -0,0 +1,12 @@
+//go:build !windows
+// +build !windows
+
+package server
+
+import (
+	"os"
+	"syscall"
+)
+
+//RestartSignals make the application hand its listening sockets off to a new process
+var RestartSignals = []os.Signal{syscall.SIGUSR2}
@@ -0,0 +1,6 @@
+package server
+
+import "os"
+
+//RestartSignals is empty because Windows has no SIGUSR2 and can not pass sockets to a new process
+var RestartSignals []os.Signal
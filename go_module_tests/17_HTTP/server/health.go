@@ -0,0 +1,143 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"runtime"
+	"runtime/debug"
+	"sync"
+)
+
+const (
+	jsonContentType string = "application/json"
+	healthyStatus   string = "ok"
+)
+
+//ErrDraining makes the server unready once it started shutting down
+var ErrDraining = errors.New("Server is shutting down")
+
+//Health serves the liveness and readiness endpoints of the server.
+//The server is ready while every readiness check passes and it is not draining
+type Health struct {
+	checks   []readinessCheck
+	draining bool
+	mx       sync.RWMutex
+}
+
+type readinessCheck struct {
+	name  string
+	check func() error
+}
+
+//ReadinessReport is the body of /readyz. Checks maps the name of every check to "ok" or its error
+type ReadinessReport struct {
+	Ready  bool
+	Checks map[string]string
+}
+
+//VersionInfo is the body of /version on the admin port
+type VersionInfo struct {
+	Path         string
+	Version      string
+	Sum          string
+	GoVersion    string
+	Dependencies []Dependency
+}
+
+//Dependency is a module the binary was built with
+type Dependency struct {
+	Path    string
+	Version string
+}
+
+//NewHealth creates a Health without readiness checks
+func NewHealth() *Health {
+	return &Health{}
+}
+
+//AddReadinessCheck makes the server unready while check returns an error
+func (h *Health) AddReadinessCheck(name string, check func() error) {
+	h.mx.Lock()
+	defer h.mx.Unlock()
+
+	h.checks = append(h.checks, readinessCheck{name, check})
+}
+
+//Drain makes the server unready so load balancers stop sending it new requests
+func (h *Health) Drain() {
+	h.mx.Lock()
+	defer h.mx.Unlock()
+
+	h.draining = true
+}
+
+//Ready runs the readiness checks
+func (h *Health) Ready() ReadinessReport {
+	h.mx.RLock()
+	defer h.mx.RUnlock()
+
+	report := ReadinessReport{Ready: true, Checks: map[string]string{"draining": healthyStatus}}
+
+	if h.draining {
+		report.Ready = false
+		report.Checks["draining"] = ErrDraining.Error()
+	}
+
+	for _, c := range h.checks {
+		report.Checks[c.name] = healthyStatus
+
+		if err := c.check(); err != nil {
+			report.Ready = false
+			report.Checks[c.name] = err.Error()
+		}
+	}
+
+	return report
+}
+
+//Register adds /healthz and /readyz to the router
+func (h *Health) Register(router *http.ServeMux) {
+	router.Handle("/healthz", http.HandlerFunc(h.livenessHandler))
+	router.Handle("/readyz", http.HandlerFunc(h.readinessHandler))
+}
+
+//livenessHandler answers as long as the process can serve requests
+func (h *Health) livenessHandler(resp http.ResponseWriter, req *http.Request) {
+	resp.Write([]byte(healthyStatus))
+}
+
+func (h *Health) readinessHandler(resp http.ResponseWriter, req *http.Request) {
+	report := h.Ready()
+
+	resp.Header().Set("content-type", jsonContentType)
+
+	if !report.Ready {
+		resp.WriteHeader(http.StatusServiceUnavailable)
+	}
+
+	json.NewEncoder(resp).Encode(report)
+}
+
+func versionHandler(resp http.ResponseWriter, req *http.Request) {
+	resp.Header().Set("content-type", jsonContentType)
+	json.NewEncoder(resp).Encode(NewVersionInfo())
+}
+
+//NewVersionInfo describes the binary from the build information embedded by the go tool
+func NewVersionInfo() VersionInfo {
+	info := VersionInfo{GoVersion: runtime.Version()}
+	build, ok := debug.ReadBuildInfo()
+
+	if !ok {
+		return info
+	}
+
+	info.Path, info.Version, info.Sum = build.Main.Path, build.Main.Version, build.Main.Sum
+
+	for _, dep := range build.Deps {
+		info.Dependencies = append(info.Dependencies, Dependency{dep.Path, dep.Version})
+	}
+
+	return info
+}
@@ -0,0 +1,82 @@
+package server_test
+
+import (
+	"encoding/json"
+	"errors"
+	poker "learning/17_HTTP"
+	server "learning/17_HTTP/server"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"testing"
+)
+
+func TestHealth(t *testing.T) {
+	t.Run("The server is ready when every check passes", func(t *testing.T) {
+		health := server.NewHealth()
+		health.AddReadinessCheck("store", func() error { return nil })
+
+		response := serveHealth(health, "/readyz")
+		report := decodeReadiness(t, response)
+
+		poker.AssertStatusCode(t, response.Code, http.StatusOK)
+
+		if !report.Ready || report.Checks["store"] != "ok" || report.Checks["draining"] != "ok" {
+			t.Errorf("Unexpected readiness report %+v", report)
+		}
+	})
+
+	t.Run("A failing check makes the server unready", func(t *testing.T) {
+		health := server.NewHealth()
+		health.AddReadinessCheck("store", func() error { return nil })
+		health.AddReadinessCheck("templates", func() error { return errors.New("missing game.html") })
+
+		response := serveHealth(health, "/readyz")
+		report := decodeReadiness(t, response)
+
+		poker.AssertStatusCode(t, response.Code, http.StatusServiceUnavailable)
+
+		if report.Ready || report.Checks["templates"] != "missing game.html" || report.Checks["store"] != "ok" {
+			t.Errorf("Unexpected readiness report %+v", report)
+		}
+	})
+
+	t.Run("Draining makes the server unready but still alive", func(t *testing.T) {
+		health := server.NewHealth()
+		health.Drain()
+
+		poker.AssertStatusCode(t, serveHealth(health, "/readyz").Code, http.StatusServiceUnavailable)
+		poker.AssertStatusCode(t, serveHealth(health, "/healthz").Code, http.StatusOK)
+	})
+
+	t.Run("The version is not public", func(t *testing.T) {
+		poker.AssertStatusCode(t, serveHealth(server.NewHealth(), "/version").Code, http.StatusNotFound)
+	})
+
+	t.Run("The version comes from the build information", func(t *testing.T) {
+		info := server.NewVersionInfo()
+
+		if info.GoVersion != runtime.Version() {
+			t.Errorf("got go version %q want %q", info.GoVersion, runtime.Version())
+		}
+	})
+}
+
+func serveHealth(health *server.Health, path string) *httptest.ResponseRecorder {
+	router := http.NewServeMux()
+	health.Register(router)
+
+	response := httptest.NewRecorder()
+	router.ServeHTTP(response, httptest.NewRequest(http.MethodGet, path, nil))
+
+	return response
+}
+
+func decodeReadiness(t *testing.T, response *httptest.ResponseRecorder) server.ReadinessReport {
+	t.Helper()
+
+	var report server.ReadinessReport
+	poker.AssertNoError(t, json.NewDecoder(response.Body).Decode(&report))
+
+	return report
+}
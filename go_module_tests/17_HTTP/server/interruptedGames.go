@@ -0,0 +1,50 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	poker "learning/17_HTTP"
+	"os"
+)
+
+//LoadInterruptedGames brings back the games saved when the server last stopped. Nothing is loaded
+//when fileName is empty or the file does not exist
+func LoadInterruptedGames(playerServer *poker.PlayerServer, fileName string) error {
+	if fileName == "" {
+		return nil
+	}
+
+	file, err := os.Open(fileName)
+
+	if os.IsNotExist(err) {
+		return nil
+	}
+
+	if err != nil {
+		return fmt.Errorf("Could not open %s %v", fileName, err)
+	}
+
+	defer file.Close()
+
+	return playerServer.LoadGames(file)
+}
+
+//SaveInterruptedGames drains the player server and saves the games it interrupted to fileName
+//together with the ones LoadInterruptedGames brought back. The games are only finished when
+//fileName is empty
+func SaveInterruptedGames(ctx context.Context, playerServer *poker.PlayerServer, fileName string) error {
+	if fileName == "" {
+		return playerServer.Drain(ctx, ioutil.Discard)
+	}
+
+	file, err := os.OpenFile(fileName, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+
+	if err != nil {
+		return fmt.Errorf("Could not create %s %v", fileName, err)
+	}
+
+	defer file.Close()
+
+	return playerServer.Drain(ctx, file)
+}
@@ -0,0 +1,253 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"learning/17_HTTP/logging"
+	"strings"
+	"sync"
+	"time"
+)
+
+//Component is a part of the application that is started before the server takes requests and
+//stopped when it shuts down
+type Component interface {
+	Start(ctx context.Context) error
+	Stop(ctx context.Context) error
+}
+
+//ComponentOptions controls when a component is started and how long it may take.
+//Components are started after the ones they depend on and stopped before them.
+//Zero timeouts use the timeouts of the Lifecycle. A stop timeout can only shorten the time left
+//until the stop deadline of the Lifecycle
+type ComponentOptions struct {
+	DependsOn    []string
+	StartTimeout time.Duration
+	StopTimeout  time.Duration
+}
+
+//ComponentFuncs turns a pair of functions into a Component. Nil functions do nothing
+type ComponentFuncs struct {
+	OnStart func(ctx context.Context) error
+	OnStop  func(ctx context.Context) error
+}
+
+//Start calls OnStart
+func (c ComponentFuncs) Start(ctx context.Context) error {
+	if c.OnStart == nil {
+		return nil
+	}
+
+	return c.OnStart(ctx)
+}
+
+//Stop calls OnStop
+func (c ComponentFuncs) Stop(ctx context.Context) error {
+	if c.OnStop == nil {
+		return nil
+	}
+
+	return c.OnStop(ctx)
+}
+
+//ComponentError is returned when a component fails to start or stop
+type ComponentError struct {
+	Component string
+	Action    string
+	Err       error
+}
+
+func (c *ComponentError) Error() string {
+	return fmt.Sprintf("Could not %s %s %v", c.Action, c.Component, c.Err)
+}
+
+func (c *ComponentError) Unwrap() error {
+	return c.Err
+}
+
+//StopErrors are the errors of every component that failed to stop
+type StopErrors []error
+
+func (s StopErrors) Error() string {
+	messages := make([]string, len(s))
+
+	for i, err := range s {
+		messages[i] = err.Error()
+	}
+
+	return strings.Join(messages, "; ")
+}
+
+type registration struct {
+	name      string
+	component Component
+	options   ComponentOptions
+}
+
+//Lifecycle starts the components of an application in dependency order and stops them in reverse
+type Lifecycle struct {
+	StartTimeout time.Duration
+	StopTimeout  time.Duration
+
+	components []registration
+	started    []registration
+	mx         sync.Mutex
+}
+
+//NewLifecycle creates a Lifecycle whose components get startTimeout to start unless they have their
+//own. Stopping all of them together takes at most stopTimeout
+func NewLifecycle(startTimeout, stopTimeout time.Duration) *Lifecycle {
+	return &Lifecycle{StartTimeout: startTimeout, StopTimeout: stopTimeout}
+}
+
+//Register adds a component under a unique name. Components must be registered before Start
+func (l *Lifecycle) Register(name string, component Component, options ComponentOptions) error {
+	l.mx.Lock()
+	defer l.mx.Unlock()
+
+	for _, r := range l.components {
+		if r.name == name {
+			return fmt.Errorf("Component %s is already registered", name)
+		}
+	}
+
+	l.components = append(l.components, registration{name, component, options})
+
+	return nil
+}
+
+//Start starts every component after the ones it depends on. When one fails the components that
+//already started are stopped again and its error is returned
+func (l *Lifecycle) Start(ctx context.Context) error {
+	l.mx.Lock()
+	defer l.mx.Unlock()
+
+	order, err := l.order()
+
+	if err != nil {
+		return err
+	}
+
+	for _, r := range order {
+		startCtx, cancel := withTimeout(ctx, r.options.StartTimeout, l.StartTimeout)
+		err := r.component.Start(startCtx)
+		cancel()
+
+		if err != nil {
+			l.stop(ctx)
+			return &ComponentError{r.name, "start", err}
+		}
+
+		logging.Default().Debug("Started component", "component", r.name)
+		l.started = append(l.started, r)
+	}
+
+	return nil
+}
+
+//Stop stops the started components in the reverse order they were started in. Every component
+//is stopped even when others fail and their errors are returned as StopErrors. They share one
+//deadline so a slow component leaves less time to the ones after it
+func (l *Lifecycle) Stop(ctx context.Context) error {
+	l.mx.Lock()
+	defer l.mx.Unlock()
+
+	return l.stop(ctx)
+}
+
+func (l *Lifecycle) stop(ctx context.Context) error {
+	var errs StopErrors
+
+	ctx, cancelAll := withTimeout(ctx, l.StopTimeout, 0)
+	defer cancelAll()
+
+	for i := len(l.started) - 1; i >= 0; i-- {
+		r := l.started[i]
+
+		stopCtx, cancel := withTimeout(ctx, r.options.StopTimeout, 0)
+		err := r.component.Stop(stopCtx)
+		cancel()
+
+		if err != nil {
+			errs = append(errs, &ComponentError{r.name, "stop", err})
+			continue
+		}
+
+		logging.Default().Debug("Stopped component", "component", r.name)
+	}
+
+	l.started = nil
+
+	if errs != nil {
+		return errs
+	}
+
+	return nil
+}
+
+//order sorts the components so each comes after its dependencies. Registration order is kept
+//otherwise
+func (l *Lifecycle) order() ([]registration, error) {
+	byName := map[string]registration{}
+
+	for _, r := range l.components {
+		byName[r.name] = r
+	}
+
+	var order []registration
+	state := map[string]int{}
+
+	const (
+		visiting = 1
+		visited  = 2
+	)
+
+	var visit func(r registration, path []string) error
+	visit = func(r registration, path []string) error {
+		switch state[r.name] {
+		case visited:
+			return nil
+		case visiting:
+			return fmt.Errorf("Components depend on each other %s", strings.Join(append(path, r.name), " -> "))
+		}
+
+		state[r.name] = visiting
+
+		for _, name := range r.options.DependsOn {
+			dependency, ok := byName[name]
+
+			if !ok {
+				return fmt.Errorf("Component %s depends on unknown component %s", r.name, name)
+			}
+
+			if err := visit(dependency, append(path, r.name)); err != nil {
+				return err
+			}
+		}
+
+		state[r.name] = visited
+		order = append(order, r)
+
+		return nil
+	}
+
+	for _, r := range l.components {
+		if err := visit(r, nil); err != nil {
+			return nil, err
+		}
+	}
+
+	return order, nil
+}
+
+func withTimeout(ctx context.Context, timeout, fallback time.Duration) (context.Context, context.CancelFunc) {
+	if timeout <= 0 {
+		timeout = fallback
+	}
+
+	if timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+
+	return context.WithTimeout(ctx, timeout)
+}
@@ -0,0 +1,164 @@
+package server_test
+
+import (
+	"context"
+	"errors"
+	poker "learning/17_HTTP"
+	server "learning/17_HTTP/server"
+	"reflect"
+	"testing"
+	"time"
+)
+
+//SpyComponent records when it is started and stopped in a shared list of calls
+type SpyComponent struct {
+	name     string
+	calls    *[]string
+	startErr error
+	stopErr  error
+	deadline *time.Duration
+}
+
+func (s *SpyComponent) Start(ctx context.Context) error {
+	*s.calls = append(*s.calls, "start "+s.name)
+	return s.startErr
+}
+
+func (s *SpyComponent) Stop(ctx context.Context) error {
+	*s.calls = append(*s.calls, "stop "+s.name)
+
+	if deadline, ok := ctx.Deadline(); ok && s.deadline != nil {
+		*s.deadline = time.Until(deadline)
+	}
+
+	return s.stopErr
+}
+
+func TestLifecycle(t *testing.T) {
+	t.Run("Components start after their dependencies and stop before them", func(t *testing.T) {
+		var calls []string
+		lifecycle := server.NewLifecycle(0, time.Second)
+
+		register(t, lifecycle, &SpyComponent{name: "games", calls: &calls}, "store", "http")
+		register(t, lifecycle, &SpyComponent{name: "http", calls: &calls}, "store")
+		register(t, lifecycle, &SpyComponent{name: "store", calls: &calls})
+
+		poker.AssertNoError(t, lifecycle.Start(context.Background()))
+		poker.AssertNoError(t, lifecycle.Stop(context.Background()))
+
+		assertCalls(t, calls, "start store", "start http", "start games", "stop games", "stop http", "stop store")
+	})
+
+	t.Run("Components that started are stopped when another fails", func(t *testing.T) {
+		var calls []string
+		lifecycle := server.NewLifecycle(0, time.Second)
+		failure := errors.New("address already in use")
+
+		register(t, lifecycle, &SpyComponent{name: "store", calls: &calls})
+		register(t, lifecycle, &SpyComponent{name: "http", calls: &calls, startErr: failure}, "store")
+		register(t, lifecycle, &SpyComponent{name: "games", calls: &calls}, "http")
+
+		err := lifecycle.Start(context.Background())
+
+		var componentErr *server.ComponentError
+
+		if !errors.As(err, &componentErr) || componentErr.Component != "http" || !errors.Is(err, failure) {
+			t.Errorf("Expected the start error of http but got %v", err)
+		}
+
+		assertCalls(t, calls, "start store", "start http", "stop store")
+	})
+
+	t.Run("Every component is stopped even when one fails", func(t *testing.T) {
+		var calls []string
+		lifecycle := server.NewLifecycle(0, time.Second)
+
+		register(t, lifecycle, &SpyComponent{name: "store", calls: &calls})
+		register(t, lifecycle, &SpyComponent{name: "http", calls: &calls, stopErr: context.DeadlineExceeded}, "store")
+
+		poker.AssertNoError(t, lifecycle.Start(context.Background()))
+		err := lifecycle.Stop(context.Background())
+
+		if !errors.As(err, &server.StopErrors{}) {
+			t.Errorf("Expected StopErrors but got %v", err)
+		}
+
+		assertCalls(t, calls, "start store", "start http", "stop http", "stop store")
+	})
+
+	t.Run("Components can get a shorter stop timeout", func(t *testing.T) {
+		var calls []string
+		var short, long, other time.Duration
+		lifecycle := server.NewLifecycle(0, time.Second)
+
+		poker.AssertNoError(t, lifecycle.Register("short", &SpyComponent{name: "short", calls: &calls, deadline: &short},
+			server.ComponentOptions{StopTimeout: 100 * time.Millisecond}))
+		poker.AssertNoError(t, lifecycle.Register("long", &SpyComponent{name: "long", calls: &calls, deadline: &long},
+			server.ComponentOptions{StopTimeout: time.Minute}))
+		register(t, lifecycle, &SpyComponent{name: "other", calls: &calls, deadline: &other})
+
+		lifecycle.Start(context.Background())
+		lifecycle.Stop(context.Background())
+
+		if short > 100*time.Millisecond || long > time.Second || other > time.Second {
+			t.Errorf("Expected at most 100ms for short and a second for long and other but got %v, %v and %v",
+				short, long, other)
+		}
+	})
+
+	t.Run("Components share one stop deadline", func(t *testing.T) {
+		var calls []string
+		var last time.Duration
+		timeout := 300 * time.Millisecond
+		lifecycle := server.NewLifecycle(0, timeout)
+
+		register(t, lifecycle, &SpyComponent{name: "last", calls: &calls, deadline: &last})
+		poker.AssertNoError(t, lifecycle.Register("slow", server.ComponentFuncs{OnStop: func(ctx context.Context) error {
+			time.Sleep(timeout / 2)
+			return nil
+		}}, server.ComponentOptions{}))
+
+		lifecycle.Start(context.Background())
+		lifecycle.Stop(context.Background())
+
+		if last > timeout/2 {
+			t.Errorf("Expected the time the slow component took to be taken from the last one but it got %v", last)
+		}
+	})
+
+	t.Run("Unknown and circular dependencies are rejected", func(t *testing.T) {
+		var calls []string
+
+		unknown := server.NewLifecycle(0, 0)
+		register(t, unknown, &SpyComponent{name: "http", calls: &calls}, "store")
+		poker.AssertError(t, unknown.Start(context.Background()))
+
+		circular := server.NewLifecycle(0, 0)
+		register(t, circular, &SpyComponent{name: "http", calls: &calls}, "games")
+		register(t, circular, &SpyComponent{name: "games", calls: &calls}, "http")
+		poker.AssertError(t, circular.Start(context.Background()))
+
+		poker.AssertError(t, circular.Register("http", &SpyComponent{}, server.ComponentOptions{}))
+
+		assertCalls(t, calls)
+	})
+}
+
+func register(t *testing.T, lifecycle *server.Lifecycle, component *SpyComponent, dependsOn ...string) {
+	t.Helper()
+
+	err := lifecycle.Register(component.name, component, server.ComponentOptions{DependsOn: dependsOn})
+	poker.AssertNoError(t, err)
+}
+
+func assertCalls(t *testing.T, got []string, want ...string) {
+	t.Helper()
+
+	if len(got) == 0 && len(want) == 0 {
+		return
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got calls %v want %v", got, want)
+	}
+}
@@ -0,0 +1,44 @@
+package server
+
+import (
+	"fmt"
+	configuration "learning/17_HTTP/config"
+	"learning/17_HTTP/logging"
+	"os"
+)
+
+//traceService is the service name the spans of the server are exported with
+const traceService string = "poker"
+
+//stdoutOutput is the trace output that writes spans to the standard output
+const stdoutOutput string = "stdout"
+
+//NewLogging creates the logger and the tracer described by the configuration. The tracer is nil
+//when tracing is off. close releases the trace file
+func NewLogging(conf configuration.LoggingConfiguration) (logger *logging.Logger, tracer *logging.Tracer, close func(), err error) {
+	level, err := logging.ParseLevel(conf.Level)
+
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	logger = logging.New(os.Stdout, level)
+	close = func() {}
+
+	switch conf.TraceOutput {
+	case "":
+	case stdoutOutput:
+		tracer = logging.NewTracer(os.Stdout, traceService)
+	default:
+		file, err := os.OpenFile(conf.TraceOutput, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("Could not open trace output %v", err)
+		}
+
+		tracer = logging.NewTracer(file, traceService)
+		close = func() { file.Close() }
+	}
+
+	return logger, tracer, close, nil
+}
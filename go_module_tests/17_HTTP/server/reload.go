@@ -0,0 +1,153 @@
+package server
+
+import (
+	"fmt"
+	poker "learning/17_HTTP"
+	configuration "learning/17_HTTP/config"
+	"learning/17_HTTP/logging"
+	"reflect"
+	"sort"
+)
+
+//runtimeSettings are the settings that can change while the application runs
+type runtimeSettings struct {
+	level      logging.Level
+	rateLimit  poker.RateLimit
+	tournament poker.TournamentOptions
+	origins    []string
+}
+
+//newRuntimeSettings converts the reloadable part of a configuration. It returns a ConfigError when
+//one of the settings is invalid
+func newRuntimeSettings(conf configuration.Configuration) (runtimeSettings, error) {
+	level, err := logging.ParseLevel(conf.GetLoggingConfiguration().Level)
+
+	if err != nil {
+		return runtimeSettings{}, &ConfigError{"logging", err}
+	}
+
+	rateLimit, err := NewRateLimit(conf.GetRateLimit())
+
+	if err != nil {
+		return runtimeSettings{}, &ConfigError{"server.rateLimit", err}
+	}
+
+	tournament, err := NewTournamentOptions(conf.GetTournamentConfiguration())
+
+	if err != nil {
+		return runtimeSettings{}, &ConfigError{"tournament", err}
+	}
+
+	return runtimeSettings{level, rateLimit, tournament, conf.GetAllowedOrigins()}, nil
+}
+
+//NewRateLimit converts the rate limit configuration into a poker.RateLimit
+func NewRateLimit(conf configuration.RateLimitConfiguration) (poker.RateLimit, error) {
+	if conf.RequestsPerSecond < 0 || conf.Burst < 0 {
+		return poker.RateLimit{}, fmt.Errorf("requestsPerSecond and burst can not be negative")
+	}
+
+	return poker.RateLimit{RequestsPerSecond: conf.RequestsPerSecond, Burst: conf.Burst}, nil
+}
+
+//restartSettings returns the settings that are only read when the application starts by their key.
+//They are compared on every reload so changes to them are reported instead of silently ignored
+func restartSettings(c configuration.Configuration) map[string]interface{} {
+	return map[string]interface{}{
+		"server.port":                   c.GetServerPort(),
+		"server.adminPort":              c.GetAdminPort(),
+		"server.tls":                    c.GetTLSConfiguration(),
+		"server.assetsDir":              c.GetAssetsDir(),
+		"server.shutdownTimeout":        c.GetShutdownTimeout(),
+		"server.drainGracePeriod":       c.GetDrainGracePeriod(),
+		"database.fileName":             c.GetDatabaseFileName(),
+		"database.snapshotDir":          c.GetSnapshotDir(),
+		"database.snapshotRetention":    c.GetSnapshotRetention(),
+		"database.interruptedGamesFile": c.GetInterruptedGamesFile(),
+		"logging.traceOutput":           c.GetLoggingConfiguration().TraceOutput,
+		"alerters":                      c.GetAlerters(),
+	}
+}
+
+//ReloadConfig reads the configuration file again and applies the log level, rate limit, tournament
+//and allowed origins while the application runs. Nothing is applied when the file can not be read
+//or is invalid. Changes to settings that are only read at startup, like the port or the database
+//file, are logged and ignored until the next restart
+func (a *Application) ReloadConfig() error {
+	a.reloadMx.Lock()
+	defer a.reloadMx.Unlock()
+
+	conf, err := readConfiguration(a.configFileName, a.configFilePath, a.flags)
+
+	if err != nil {
+		return err
+	}
+
+	if err := conf.Validate(); err != nil {
+		return &ConfigError{"server and database", err}
+	}
+
+	settings, err := newRuntimeSettings(conf)
+
+	if err != nil {
+		return err
+	}
+
+	a.apply(settings)
+
+	running, configured := restartSettings(a.config), restartSettings(conf)
+	keys := make([]string, 0, len(running))
+
+	for key := range running {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		if reflect.DeepEqual(running[key], configured[key]) {
+			continue
+		}
+
+		if !loggable(running[key]) || a.config.IsRedacted(key) || conf.IsRedacted(key) {
+			a.logger.Warn("Configuration change needs a restart, keeping the running value", "key", key)
+			continue
+		}
+
+		a.logger.Warn("Configuration change needs a restart, keeping the running value", "key", key,
+			"running", a.config.Redact(fmt.Sprint(running[key])),
+			"configured", conf.Redact(fmt.Sprint(configured[key])))
+	}
+
+	a.logger.Info("Configuration reloaded", "level", settings.level.String())
+
+	return nil
+}
+
+//loggable tells if a value can be logged. Lists and structures like the alerters are not since
+//their URLs can hold tokens that are not secret references
+func loggable(value interface{}) bool {
+	switch reflect.ValueOf(value).Kind() {
+	case reflect.Slice, reflect.Map, reflect.Struct, reflect.Ptr:
+		return false
+	}
+
+	return true
+}
+
+//apply changes the settings of the parts of the application that were created from the configuration
+func (a *Application) apply(settings runtimeSettings) {
+	a.logger.SetLevel(settings.level)
+
+	if a.limiter != nil {
+		a.limiter.SetLimit(settings.rateLimit)
+	}
+
+	if a.tournaments != nil {
+		a.tournaments.SetOptions(settings.tournament)
+	}
+
+	if a.playerServer != nil {
+		a.playerServer.SetAllowedOrigins(settings.origins)
+	}
+}
@@ -0,0 +1,225 @@
+package server_test
+
+import (
+	"io/ioutil"
+	poker "learning/17_HTTP"
+	viperRepo "learning/17_HTTP/config/viper"
+	"learning/17_HTTP/logging"
+	server "learning/17_HTTP/server"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestReloadConfig(t *testing.T) {
+	t.Run("Invalid settings are not applied", func(t *testing.T) {
+		dir := t.TempDir()
+		conf := testConfig{DbFileName: filepath.Join(dir, "game.db.json")}
+		app, err := server.CreateDefaultApplication(writeConfig(t, dir, conf))
+		poker.AssertNoError(t, err)
+
+		conf.LogLevel = "debug"
+		conf.Payouts = "[{minEntries: 2, shares: [60, 60]}]"
+		writeConfig(t, dir, conf)
+
+		assertErrorAs(t, app.ReloadConfig(), new(*server.ConfigError))
+		assertLogLevel(t, logging.ErrorLevel)
+	})
+
+	t.Run("Changes that need a restart are logged without their values", func(t *testing.T) {
+		dir := t.TempDir()
+		conf := testConfig{
+			DbFileName: filepath.Join(dir, "game.db.json"),
+			LogLevel:   "warn",
+			Alerters:   "[{type: webhook, url: 'https://hooks.example.com/T0/first-token'}]",
+		}
+
+		var app *server.Application
+		logs := captureStdout(t, func() {
+			var err error
+			app, err = server.CreateDefaultApplication(writeConfig(t, dir, conf))
+			poker.AssertNoError(t, err)
+		})
+
+		conf.Alerters = "[{type: webhook, url: 'https://hooks.example.com/T0/second-token'}]"
+		writeConfig(t, dir, conf)
+
+		poker.AssertNoError(t, app.ReloadConfig())
+		output := logs()
+
+		if !strings.Contains(output, `"key":"alerters"`) {
+			t.Errorf("Expected the alerters change to be logged but got %s", output)
+		}
+
+		if strings.Contains(output, "first-token") || strings.Contains(output, "second-token") {
+			t.Errorf("Expected the webhook tokens not to be logged but got %s", output)
+		}
+	})
+
+	t.Run("A config file that can not be read is not applied", func(t *testing.T) {
+		dir := t.TempDir()
+		app, err := server.CreateDefaultApplication(writeConfig(t, dir, testConfig{
+			DbFileName: filepath.Join(dir, "game.db.json"),
+		}))
+		poker.AssertNoError(t, err)
+
+		poker.AssertNoError(t, os.Remove(filepath.Join(dir, "testConfig.yaml")))
+
+		assertErrorAs(t, app.ReloadConfig(), new(*server.ConfigError))
+		assertLogLevel(t, logging.ErrorLevel)
+	})
+
+	t.Run("Changes to the file are applied while the application runs", func(t *testing.T) {
+		//Registers the SIGINT handler before the signal is sent so it can not kill the test
+		server.GenerateContextWithSigint()
+
+		dir := t.TempDir()
+		port := freeAddress(t)
+		conf := testConfig{DbFileName: filepath.Join(dir, "game.db.json"), Port: port}
+		app, err := server.CreateDefaultApplication(writeConfig(t, dir, conf))
+		poker.AssertNoError(t, err)
+
+		done := make(chan error)
+		go func() { done <- app.Start() }()
+		waitUntilServing(t, port)
+
+		conf.LogLevel = "debug"
+		conf.RateLimit = "{requestsPerSecond: 0.001, burst: 1}"
+		conf.Port = freeAddress(t)
+		writeConfig(t, dir, conf)
+
+		eventually(t, "the log level to change", func() bool {
+			return logging.Default().Level() == logging.DebugLevel
+		})
+
+		client := &http.Client{Transport: &http.Transport{DisableKeepAlives: true}}
+
+		for _, want := range []int{http.StatusOK, http.StatusTooManyRequests} {
+			response, err := client.Get("http://" + port + "/healthz")
+			poker.AssertNoError(t, err)
+			response.Body.Close()
+
+			poker.AssertStatusCode(t, response.StatusCode, want)
+		}
+
+		syscall.Kill(syscall.Getpid(), syscall.SIGINT)
+		poker.AssertNoError(t, <-done)
+	})
+
+	t.Run("Fragments in conf.d are applied while the application runs", func(t *testing.T) {
+		server.GenerateContextWithSigint()
+
+		dir := t.TempDir()
+		fragments := filepath.Join(dir, viperRepo.ConfigDir)
+		poker.AssertNoError(t, os.Mkdir(fragments, 0700))
+
+		port := freeAddress(t)
+		app, err := server.CreateDefaultApplication(writeConfig(t, dir, testConfig{
+			DbFileName: filepath.Join(dir, "game.db.json"),
+			Port:       port,
+		}))
+		poker.AssertNoError(t, err)
+
+		done := make(chan error)
+		go func() { done <- app.Start() }()
+		waitUntilServing(t, port)
+
+		fragment := []byte(`{"logging": {"level": "debug"}}`)
+		poker.AssertNoError(t, ioutil.WriteFile(filepath.Join(fragments, "50-debug.json"), fragment, 0600))
+
+		eventually(t, "the fragment to change the log level", func() bool {
+			return logging.Default().Level() == logging.DebugLevel
+		})
+
+		syscall.Kill(syscall.Getpid(), syscall.SIGINT)
+		poker.AssertNoError(t, <-done)
+	})
+
+	t.Run("SIGHUP reloads the file", func(t *testing.T) {
+		//Registers the SIGINT and SIGHUP handlers before the signals are sent so they can not kill the test
+		server.GenerateContextWithSigint()
+		signal.Notify(make(chan os.Signal, 1), syscall.SIGHUP)
+
+		dir := t.TempDir()
+		port := freeAddress(t)
+		conf := testConfig{DbFileName: filepath.Join(dir, "game.db.json"), Port: port}
+		app, err := server.CreateDefaultApplication(writeConfig(t, dir, conf))
+		poker.AssertNoError(t, err)
+
+		//Written before the file is watched so only the signal can apply it
+		conf.LogLevel = "warn"
+		writeConfig(t, dir, conf)
+
+		done := make(chan error)
+		go func() { done <- app.Start() }()
+		waitUntilServing(t, port)
+
+		assertLogLevel(t, logging.ErrorLevel)
+
+		eventually(t, "SIGHUP to change the log level", func() bool {
+			syscall.Kill(syscall.Getpid(), syscall.SIGHUP)
+			time.Sleep(10 * time.Millisecond)
+
+			return logging.Default().Level() == logging.WarnLevel
+		})
+
+		syscall.Kill(syscall.Getpid(), syscall.SIGINT)
+		poker.AssertNoError(t, <-done)
+	})
+}
+
+//captureStdout returns what the loggers created by create write to stdout until the returned
+//function is called. The default logger is put back when the test ends
+func captureStdout(t *testing.T, create func()) func() string {
+	t.Helper()
+
+	previous := logging.Default()
+	t.Cleanup(func() { logging.SetDefault(previous) })
+
+	reader, writer, err := os.Pipe()
+	poker.AssertNoError(t, err)
+
+	stdout := os.Stdout
+	os.Stdout = writer
+	create()
+	os.Stdout = stdout
+
+	output := make(chan string, 1)
+
+	go func() {
+		content, _ := ioutil.ReadAll(reader)
+		reader.Close()
+		output <- string(content)
+	}()
+
+	return func() string {
+		writer.Close()
+		return <-output
+	}
+}
+
+func assertLogLevel(t *testing.T, want logging.Level) {
+	t.Helper()
+
+	if got := logging.Default().Level(); got != want {
+		t.Errorf("got log level %v want %v", got, want)
+	}
+}
+
+//eventually fails the test when condition does not become true within a second
+func eventually(t *testing.T, what string, condition func() bool) {
+	t.Helper()
+
+	for deadline := time.Now().Add(time.Second); time.Now().Before(deadline); time.Sleep(5 * time.Millisecond) {
+		if condition() {
+			return
+		}
+	}
+
+	t.Fatalf("Timed out waiting for %s", what)
+}
@@ -2,39 +2,64 @@ package server
 
 import (
 	"context"
+	"fmt"
 	poker "learning/17_HTTP"
 	configuration "learning/17_HTTP/config"
 	viperRepo "learning/17_HTTP/config/viper"
-	"log"
+	"learning/17_HTTP/logging"
+	"learning/17_HTTP/metrics"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
+	"sync"
+	"syscall"
 	"time"
+
+	"github.com/spf13/pflag"
 )
 
 //Server is an abstraction of a http.server
 type Server interface {
 	Shutdown(ctx context.Context) error
-	ListenAndServe() error
+	Serve(listener net.Listener) error
 }
 
-//Application holds the http server and the configuration of the app
+//Application holds the configuration of the app and the components it starts and stops
 type Application struct {
-	server  Server
-	config  configuration.Configuration
-	dbClose func()
+	config    configuration.Configuration
+	lifecycle *Lifecycle
+	servers   []*serverComponent
+	health    *Health
+	failed    chan error
+	grace     time.Duration
+
+	configFileName string
+	configFilePath string
+	flags          *pflag.FlagSet
+	logger         *logging.Logger
+	limiter        *poker.RateLimiter
+	tournaments    *poker.Tournaments
+	playerServer   *poker.PlayerServer
+	reloadMx       sync.Mutex
 }
 
-//GenerateContextWithSigint creates a context and waits for a sigint to cancell it
+//defaultShutdownTimeout is used when no shutdown timeout is configured
+const defaultShutdownTimeout = 5 * time.Second
+
+//ShutdownSignals make the application drain and stop
+var ShutdownSignals = []os.Signal{os.Interrupt, syscall.SIGTERM, syscall.SIGQUIT}
+
+//GenerateContextWithSigint creates a context and waits for one of the ShutdownSignals to cancell it
 func GenerateContextWithSigint() context.Context {
 	c := make(chan os.Signal, 1)
-	signal.Notify(c, os.Interrupt)
+	signal.Notify(c, ShutdownSignals...)
 
 	ctx, cancel := context.WithCancel(context.Background())
 
 	go func() {
 		oscall := <-c
-		log.Printf("System call %v", oscall)
+		logging.Default().Info("System call", "signal", oscall)
 		cancel()
 	}()
 
@@ -43,76 +68,428 @@ func GenerateContextWithSigint() context.Context {
 
 //CreateApplication creates a application with injected server, configuration and dbClose methods
 func CreateApplication(conf configuration.Configuration, server Server, dbClose func()) *Application {
+	app := newApplication(conf)
+
+	app.Register(StoreComponent, closer(dbClose), ComponentOptions{})
+	app.addServer(HTTPComponent, server, conf.GetServerPort(), "Server started",
+		ComponentOptions{DependsOn: []string{StoreComponent}})
+
+	return app
+}
+
+//newApplication creates an application without components. Components are stopped within the
+//shutdown timeout of the configuration after the drain grace period
+func newApplication(conf configuration.Configuration) *Application {
+	timeout := conf.GetShutdownTimeout()
+
+	if timeout <= 0 {
+		timeout = defaultShutdownTimeout
+	}
+
 	return &Application{
-		server,
-		conf,
-		dbClose,
+		config:    conf,
+		lifecycle: NewLifecycle(0, timeout),
+		health:    NewHealth(),
+		failed:    make(chan error, 2),
+		grace:     conf.GetDrainGracePeriod(),
+		logger:    logging.Default(),
 	}
 }
 
-//CreateDefaultApplication reads a config file and creates a new application
-func CreateDefaultApplication(configFileName, configFilePath string) *Application {
-	vprRepo := viperRepo.NewViperReader()
-	appConfig := configuration.NewConfiguration(vprRepo)
-	err := appConfig.Read(configFileName, configFilePath, nil)
+//Register adds a component that is started with the application and stopped when it shuts down
+func (a *Application) Register(name string, component Component, options ComponentOptions) error {
+	return a.lifecycle.Register(name, component, options)
+}
+
+//addServer registers a server. Its listening socket is handed off on restarts
+func (a *Application) addServer(name string, server Server, addr, message string, options ComponentOptions) {
+	component := &serverComponent{
+		server:  server,
+		name:    name,
+		addr:    addr,
+		message: message,
+		failed:  a.failed,
+	}
+
+	a.servers = append(a.servers, component)
+	a.Register(name, component, options)
+}
+
+//closer is a component that calls close when it stops
+func closer(close func()) Component {
+	return ComponentFuncs{OnStop: func(context.Context) error {
+		close()
+		return nil
+	}}
+}
+
+//DefaultConfiguration holds the values used for keys missing from the configuration file
+var DefaultConfiguration = viperRepo.DefaultConfiguration{
+	"database.snapshotDir":       "snapshots",
+	"database.snapshotRetention": 10,
+	"server.shutdownTimeout":     defaultShutdownTimeout,
+}
+
+//readConfiguration reads the defaults, the config file, the environment and the flags that were
+//given in order of precedence. Flags may be nil
+func readConfiguration(configFileName, configFilePath string, flags *pflag.FlagSet) (configuration.Configuration,
+	error) {
+
+	appConfig := configuration.NewConfiguration(viperRepo.NewViperReader())
+
+	if flags != nil {
+		if err := appConfig.BindFlags(flags); err != nil {
+			return nil, &ConfigError{"startup", err}
+		}
+	}
+
+	if err := appConfig.Read(configFileName, configFilePath, DefaultConfiguration); err != nil {
+		return nil, &ConfigError{"startup", err}
+	}
+
+	return appConfig, nil
+}
+
+//CreateDefaultApplication reads a config file and creates a new application without flags
+func CreateDefaultApplication(configFileName, configFilePath string) (*Application, error) {
+	return CreateApplicationWithFlags(configFileName, configFilePath, nil)
+}
+
+//CreateApplicationWithFlags reads a config file and the flags that override it and creates a new
+//application. It returns a ConfigError, StoreError or TemplateError when a part of the application
+//can not be created. Anything opened before the failure is closed again
+func CreateApplicationWithFlags(configFileName, configFilePath string,
+	flags *pflag.FlagSet) (app *Application, err error) {
+
+	WaitForRelease()
+
+	appConfig, err := readConfiguration(configFileName, configFilePath, flags)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if err := appConfig.Validate(); err != nil {
+		return nil, &ConfigError{"server and database", err}
+	}
+
+	logger, tracer, closeTraces, err := NewLogging(appConfig.GetLoggingConfiguration())
+
+	if err != nil {
+		return nil, &ConfigError{"logging", err}
+	}
+
+	defer closeOnError(&err, closeTraces)
+
+	logging.SetDefault(logger)
+
+	store, closeStore, err := poker.GenerateFileSystemPlayerStore(appConfig.GetDatabaseFileName())
 
 	if err != nil {
-		log.Fatalf("Could not read startup configuration file %v", err)
+		return nil, &StoreError{appConfig.GetDatabaseFileName(), err}
 	}
 
-	store, dbClose, err := poker.GenerateFileSystemPlayerStore(appConfig.GetDatabaseFileName())
+	defer closeOnError(&err, closeStore)
+
+	registry := metrics.NewRegistry()
+	serverMetrics := poker.NewServerMetrics(registry)
+	store.SetMetrics(serverMetrics)
+	registry.NewGaugeFunc("poker_database_size_bytes", "Size of the JSON database file.",
+		databaseSize(appConfig.GetDatabaseFileName()))
+
+	tournamentOptions, err := NewTournamentOptions(appConfig.GetTournamentConfiguration())
 
 	if err != nil {
-		log.Fatalf("Could not generate FileSystem player store from file, %v", err)
+		return nil, &ConfigError{"tournament", err}
 	}
 
-	game := poker.NewGame(store, poker.BlindAlerterFunc(poker.GenericAlerter))
-	playerServer, err := poker.NewPlayerServer(store, game)
+	alerter, err := NewAlerter(appConfig.GetAlerters())
 
 	if err != nil {
-		log.Fatalf("Failed to create playerServer %v", err)
+		return nil, &ConfigError{"alerters", err}
 	}
 
-	server := &http.Server{
-		Addr:    appConfig.GetServerPort(),
-		Handler: playerServer,
+	rateLimit, err := NewRateLimit(appConfig.GetRateLimit())
+
+	if err != nil {
+		return nil, &ConfigError{"server.rateLimit", err}
 	}
 
-	return &Application{
-		server,
-		appConfig,
-		dbClose,
+	tournaments := poker.NewTournaments(store, alerter, tournamentOptions)
+	playerServer, err := poker.NewPlayerServerWithGames(store, tournaments.New)
+
+	if err != nil {
+		return nil, &TemplateError{"", err}
+	}
+
+	if err = playerServer.SetAssetsDir(appConfig.GetAssetsDir()); err != nil {
+		return nil, &TemplateError{appConfig.GetAssetsDir(), err}
+	}
+
+	playerServer.SetWebSocketOptions(poker.WebSocketOptions{AllowedOrigins: appConfig.GetAllowedOrigins()})
+
+	if err = LoadInterruptedGames(playerServer, appConfig.GetInterruptedGamesFile()); err != nil {
+		return nil, &StoreError{appConfig.GetInterruptedGamesFile(), err}
+	}
+	playerServer.SetMetrics(serverMetrics)
+
+	snapshotter := poker.NewSnapshotter(store, appConfig.GetSnapshotDir(), appConfig.GetSnapshotRetention())
+
+	router := http.NewServeMux()
+	router.Handle("/", playerServer)
+
+	health := NewHealth()
+	health.AddReadinessCheck("store", store.Ping)
+	health.AddReadinessCheck("templates", playerServer.CheckTemplates)
+	health.Register(router)
+
+	tlsConf := appConfig.GetTLSConfiguration()
+	limiter := poker.NewRateLimiter(rateLimit)
+	handler := poker.LogRequests(logger, tracer, limiter.Limit(router))
+	server, reloader, err := NewHTTPServer(appConfig.GetServerPort(), handler, tlsConf)
+
+	if err != nil {
+		return nil, &ConfigError{"tls", err}
+	}
+
+	adminPort := appConfig.GetAdminPort()
+
+	app = newApplication(appConfig)
+	app.health = health
+	app.configFileName = configFileName
+	app.configFilePath = configFilePath
+	app.flags = flags
+	app.logger = logger
+	app.limiter = limiter
+	app.tournaments = tournaments
+	app.playerServer = playerServer
+
+	app.Register(TracesComponent, closer(closeTraces), ComponentOptions{})
+	app.Register(StoreComponent, closer(closeStore), ComponentOptions{DependsOn: []string{TracesComponent}})
+	app.addServer(HTTPComponent, server, appConfig.GetServerPort(), "Server started",
+		ComponentOptions{DependsOn: []string{StoreComponent}})
+	app.Register(GamesComponent, ComponentFuncs{OnStop: func(ctx context.Context) error {
+		return SaveInterruptedGames(ctx, playerServer, appConfig.GetInterruptedGamesFile())
+	}}, ComponentOptions{DependsOn: []string{StoreComponent, HTTPComponent}})
+
+	if adminPort != "" {
+		admin := NewAdminHandler(poker.NewAdminServer(store, playerServer), poker.NewSnapshotServer(snapshotter),
+			registry, app.ReloadConfig)
+		app.addServer(AdminComponent, &http.Server{Handler: poker.LogRequests(logger, tracer, admin)}, adminPort,
+			"Admin API started", ComponentOptions{DependsOn: []string{StoreComponent}})
 	}
+
+	if configFileName != "" && configFilePath != "" {
+		app.Register(ConfigComponent, newConfigWatcher(configFileName, configFilePath, app.ReloadConfig),
+			ComponentOptions{DependsOn: []string{HTTPComponent}})
+	}
+
+	if reloader != nil {
+		app.Register(CertificatesComponent, &certificatesComponent{reloader: reloader}, ComponentOptions{})
+	}
+
+	if reloader != nil && tlsConf.RedirectPort != "" {
+		redirect := &http.Server{Handler: NewRedirectHandler(appConfig.GetServerPort())}
+		app.addServer(RedirectComponent, redirect, tlsConf.RedirectPort, "Redirecting HTTP to HTTPS",
+			ComponentOptions{DependsOn: []string{HTTPComponent}})
+	}
+
+	return app, nil
 }
 
-//Start executes LisendAndServer for the server and waits for SIGINT to initiate gracefull shutdown
-func (a *Application) Start() {
-	go func() {
-		if err := a.server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			log.Fatalf("Error when starting to listen %v", err)
+//closeOnError calls close when the function returning err failed
+func closeOnError(err *error, close func()) {
+	if *err != nil {
+		close()
+	}
+}
+
+//Health returns the readiness checks of the application
+func (a *Application) Health() *Health {
+	return a.health
+}
+
+//databaseSize returns the size of the database file in bytes or 0 if it can not be read
+func databaseSize(fileName string) func() float64 {
+	return func() float64 {
+		info, err := os.Stat(fileName)
+
+		if err != nil {
+			return 0
 		}
-	}()
 
-	log.Printf("Server started at port %s", a.config.GetServerPort())
+		return float64(info.Size())
+	}
+}
 
+//NewTournamentOptions converts the tournament configuration into poker.TournamentOptions.
+//The default payout table is used when none is configured
+func NewTournamentOptions(conf configuration.TournamentConfiguration) (poker.TournamentOptions, error) {
+	options := poker.TournamentOptions{
+		BuyIn: conf.BuyIn,
+		Rebuy: conf.Rebuy,
+		Clock: poker.ClockOptions{
+			StartingStack: conf.StartingStack,
+			BreakEvery:    conf.BreakEvery,
+			BreakLength:   conf.BreakLength,
+			ColorUps:      conf.ColorUps,
+		},
+	}
+
+	for _, level := range conf.Payouts {
+		options.Payouts = append(options.Payouts, poker.PayoutLevel{
+			MinEntries: level.MinEntries,
+			Shares:     level.Shares,
+		})
+	}
+
+	if err := options.Payouts.Validate(); err != nil {
+		return poker.TournamentOptions{}, err
+	}
+
+	return options, nil
+}
+
+//NewAlerter creates a ClockAlerter that fans alerts out to every configured sink.
+//Alerts are written to the game's writer when no sinks are configured. Terminals print to stderr
+//so their escape codes do not end up between the JSON logs on stdout
+func NewAlerter(confs []configuration.AlerterConfiguration) (poker.ClockAlerter, error) {
+	if len(confs) == 0 {
+		return poker.GenericClockAlerter{}, nil
+	}
+
+	var sinks poker.FanOutSink
+
+	for i, conf := range confs {
+		switch conf.Type {
+		case "writer":
+			sinks = append(sinks, poker.WriterSink{})
+		case "terminal":
+			sinks = append(sinks, poker.NewTerminalSink(os.Stderr, conf.Color))
+		case "webhook":
+			if conf.URL == "" {
+				return nil, fmt.Errorf("alerters[%d]: webhook alerter requires a url", i)
+			}
+
+			sinks = append(sinks, poker.NewWebhookSink(conf.URL, nil, conf.Retries, conf.Backoff, nil))
+		default:
+			return nil, fmt.Errorf("alerters[%d]: unknown alerter type %q", i, conf.Type)
+		}
+	}
+
+	return poker.NewScheduledAlerter(sinks), nil
+}
+
+//Start starts the components and serves until one of the ShutdownSignals initiates gracefull
+//shutdown. One of the RestartSignals hands the listening sockets off to a new process running the
+//same binary and stops once it started. It keeps serving when the new process can not be started.
+//It returns the error of a component that could not start, of a server that stopped
+//serving or of the components that could not stop
+func (a *Application) Start() error {
+	//Listens for the signals before the servers start so a signal sent once they serve is not missed
 	ctx := GenerateContextWithSigint()
 
-	<-ctx.Done()
-	a.gracefullShutdown()
+	err := a.lifecycle.Start(context.Background())
+	CloseInheritedListeners()
+
+	if err != nil {
+		return err
+	}
+
+	restart := make(chan os.Signal, 1)
+
+	if len(RestartSignals) > 0 {
+		signal.Notify(restart, RestartSignals...)
+		defer signal.Stop(restart)
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			a.drain()
+			return a.gracefullShutdown()
+		case err := <-a.failed:
+			logging.Default().Error("Server stopped serving", "error", err)
+			a.health.Drain()
+			a.gracefullShutdown()
+			return fmt.Errorf("Error when serving %v", err)
+		case <-restart:
+			release, err := a.handOff()
+
+			if err != nil {
+				logging.Default().Error("Could not restart, still serving", "error", err)
+				continue
+			}
+
+			a.drain()
+			err = a.gracefullShutdown()
+			release()
+
+			return err
+		}
+	}
 }
 
-//GracefullShutdown gracefully shuts down the http server
-func (a *Application) gracefullShutdown() {
-	timeoutCtx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+//drain makes the application unready and keeps serving for the drain grace period so load
+//balancers see /readyz fail and stop sending requests before the servers stop
+func (a *Application) drain() {
+	a.health.Drain()
 
-	defer func() {
-		a.dbClose()
-		cancel()
-	}()
+	if a.grace > 0 {
+		logging.Default().Info("Draining before shutdown", "gracePeriod", a.grace)
+		time.Sleep(a.grace)
+	}
+}
+
+//GracefullShutdown stops the components in the reverse order they were started in. Every
+//component is stopped even when others fail or take longer than their timeout
+func (a *Application) gracefullShutdown() error {
+	if err := a.lifecycle.Stop(context.Background()); err != nil {
+		logging.Default().Error("Failed to gracefully shutdown server", "error", err)
+		return err
+	}
 
-	if err := a.server.Shutdown(timeoutCtx); err != nil {
-		log.Fatalf("Failed to gracefully shutdown server %v", err)
+	logging.Default().Info("Successful graceful shutdown of server")
+
+	return nil
+}
+
+//handOff starts a new process with the listening sockets before anything is stopped so the
+//application keeps serving when it can not be started. The new process waits for release before
+//it opens the store which the application has to drain and close first. The sockets stay open in
+//between so new connections wait in the backlog instead of being refused
+func (a *Application) handOff() (release func(), err error) {
+	names, listeners := a.listeners()
+	files, err := listenerFiles(listeners)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer closeFiles(files)
+
+	pid, release, err := startProcess(names, files)
+
+	if err != nil {
+		return nil, err
+	}
+
+	logging.Default().Info("Handed the listeners off", "pid", pid)
+
+	return release, nil
+}
+
+//listeners returns the listening sockets of the servers and the names of their components
+func (a *Application) listeners() ([]string, []net.Listener) {
+	var names []string
+	var listeners []net.Listener
+
+	for _, server := range a.servers {
+		if server.listener != nil {
+			names = append(names, server.name)
+			listeners = append(listeners, server.listener)
+		}
 	}
 
-	log.Printf("Successful graceful shutdown of server")
+	return names, listeners
 }
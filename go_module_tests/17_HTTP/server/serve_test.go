@@ -2,32 +2,95 @@ package server_test
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
 	poker "learning/17_HTTP"
 	configuration "learning/17_HTTP/config"
+	repo "learning/17_HTTP/config/viper"
 	server "learning/17_HTTP/server"
+	"net"
+	"path/filepath"
 	"syscall"
 	"testing"
 	"time"
+
+	"github.com/spf13/pflag"
 )
 
+//localAddress makes the applications started by the tests listen on a free port
+const localAddress string = "127.0.0.1:0"
+
+//SpyServer closes served and shutdown when it is called. The application calls it from other
+//goroutines so the tests wait on the channels
 type SpyServer struct {
-	listenAndServeCalled bool
-	shutdownCalled       bool
+	served      chan struct{}
+	shutdown    chan struct{}
+	shutdownErr error
+}
+
+func newSpyServer(shutdownErr error) *SpyServer {
+	return &SpyServer{make(chan struct{}), make(chan struct{}), shutdownErr}
 }
 
 func (s *SpyServer) Shutdown(ctx context.Context) error {
-	s.shutdownCalled = true
+	close(s.shutdown)
+	return s.shutdownErr
+}
+
+func (s *SpyServer) Serve(listener net.Listener) error {
+	close(s.served)
+	return listener.Close()
+}
+
+type SpyConfiguration struct {
+	dbFileName       string
+	serverPort       string
+	drainGracePeriod time.Duration
+}
+
+func (s *SpyConfiguration) GetSnapshotDir() string {
+	return ""
+}
+
+func (s *SpyConfiguration) GetSnapshotRetention() int {
+	return 0
+}
+
+func (s *SpyConfiguration) GetTournamentConfiguration() configuration.TournamentConfiguration {
+	return configuration.TournamentConfiguration{}
+}
+
+func (s *SpyConfiguration) GetAlerters() []configuration.AlerterConfiguration {
 	return nil
 }
 
-func (s *SpyServer) ListenAndServe() error {
-	s.listenAndServeCalled = true
+func (s *SpyConfiguration) GetAllowedOrigins() []string {
 	return nil
 }
 
-type SpyConfiguration struct {
-	dbFileName string
-	serverPort string
+func (s *SpyConfiguration) GetTLSConfiguration() configuration.TLSConfiguration {
+	return configuration.TLSConfiguration{}
+}
+
+func (s *SpyConfiguration) GetAssetsDir() string {
+	return ""
+}
+
+func (s *SpyConfiguration) GetLoggingConfiguration() configuration.LoggingConfiguration {
+	return configuration.LoggingConfiguration{}
+}
+
+func (s *SpyConfiguration) GetShutdownTimeout() time.Duration {
+	return 0
+}
+
+func (s *SpyConfiguration) GetDrainGracePeriod() time.Duration {
+	return s.drainGracePeriod
+}
+
+func (s *SpyConfiguration) GetInterruptedGamesFile() string {
+	return ""
 }
 
 func (s *SpyConfiguration) SetDatabaseFileName(fileName string) {
@@ -46,8 +109,36 @@ func (s *SpyConfiguration) GetServerPort() string {
 	return s.serverPort
 }
 
+func (s *SpyConfiguration) GetAdminPort() string {
+	return ""
+}
+
+func (s *SpyConfiguration) GetRateLimit() configuration.RateLimitConfiguration {
+	return configuration.RateLimitConfiguration{}
+}
+
+func (s *SpyConfiguration) Validate() error {
+	return nil
+}
+
+func (s *SpyConfiguration) BindFlags(set *pflag.FlagSet) error {
+	return nil
+}
+
+func (s *SpyConfiguration) Dump() []configuration.Setting {
+	return nil
+}
+
+func (s *SpyConfiguration) Redact(text string) string {
+	return text
+}
+
+func (s *SpyConfiguration) IsRedacted(key string) bool {
+	return false
+}
+
 func (s *SpyConfiguration) Read(configFileName, configFilePath string,
-	defaultConfig configuration.DefaultConfiguration) error {
+	defaultConfig repo.DefaultConfiguration) error {
 	return nil
 }
 
@@ -69,23 +160,338 @@ func TestCreateContext(t *testing.T) {
 			t.Errorf("Expected context be cancelled afte SIGIN but was not")
 		}
 	})
+
+	for _, sig := range []syscall.Signal{syscall.SIGTERM, syscall.SIGQUIT} {
+		t.Run("Context should be killed after "+sig.String(), func(t *testing.T) {
+			ctx := server.GenerateContextWithSigint()
+
+			syscall.Kill(syscall.Getpid(), sig)
+
+			select {
+			case <-ctx.Done():
+			case <-time.After(50 * time.Millisecond):
+				t.Errorf("Expected context be cancelled after %v but was not", sig)
+			}
+		})
+	}
 }
 
 func TestAppStart(t *testing.T) {
-	conf := &SpyConfiguration{poker.TestDbFileName, poker.TestServerPort}
-	srv := &SpyServer{}
-	var closeDbCalled bool
+	conf := &SpyConfiguration{dbFileName: poker.TestDbFileName, serverPort: localAddress}
+	srv := newSpyServer(nil)
+	closedDb := make(chan struct{})
 
-	app := server.CreateApplication(conf, srv, func() { closeDbCalled = true })
+	app := server.CreateApplication(conf, srv, func() { close(closedDb) })
 	go func() {
 		app.Start()
 	}()
 
-	poker.AssertFalse(t, srv.shutdownCalled)
-	poker.AssertTrueWithRetry(t, &srv.listenAndServeCalled)
+	assertClosed(t, srv.served, "the server to serve")
+
+	select {
+	case <-srv.shutdown:
+		t.Fatalf("Expected the server to keep serving until SIGINT")
+	default:
+	}
 
 	syscall.Kill(syscall.Getpid(), syscall.SIGINT)
 
-	poker.AssertTrueWithRetry(t, &srv.shutdownCalled)
-	poker.AssertTrueWithRetry(t, &closeDbCalled)
+	assertClosed(t, srv.shutdown, "the server to shut down")
+	assertClosed(t, closedDb, "the store to be closed")
+}
+
+func TestAppStartClosesStoreWhenShutdownFails(t *testing.T) {
+	conf := &SpyConfiguration{dbFileName: poker.TestDbFileName, serverPort: localAddress}
+	srv := newSpyServer(context.DeadlineExceeded)
+	closedDb := make(chan struct{})
+
+	app := server.CreateApplication(conf, srv, func() { close(closedDb) })
+	go app.Start()
+
+	assertClosed(t, srv.served, "the server to serve")
+
+	syscall.Kill(syscall.Getpid(), syscall.SIGTERM)
+
+	assertClosed(t, closedDb, "the store to be closed")
+}
+
+func TestAppStartDrains(t *testing.T) {
+	//Registers the SIGTERM handler before the signal is sent so it can not kill the test
+	server.GenerateContextWithSigint()
+
+	conf := &SpyConfiguration{dbFileName: poker.TestDbFileName, serverPort: localAddress}
+	srv := newSpyServer(nil)
+	closedDb := make(chan struct{})
+
+	app := server.CreateApplication(conf, srv, func() { close(closedDb) })
+	go app.Start()
+
+	assertClosed(t, srv.served, "the server to serve")
+
+	if !app.Health().Ready().Ready {
+		t.Fatalf("Expected the application to be ready before SIGTERM")
+	}
+
+	syscall.Kill(syscall.Getpid(), syscall.SIGTERM)
+
+	assertClosed(t, closedDb, "the store to be closed")
+
+	if app.Health().Ready().Ready {
+		t.Errorf("Expected the application to stop being ready after SIGTERM")
+	}
+}
+
+func TestAppStartDrainGracePeriod(t *testing.T) {
+	server.GenerateContextWithSigint()
+
+	grace := 200 * time.Millisecond
+	conf := &SpyConfiguration{dbFileName: poker.TestDbFileName, serverPort: localAddress, drainGracePeriod: grace}
+	closed := make(chan struct{})
+
+	srv := newSpyServer(nil)
+	app := server.CreateApplication(conf, srv, func() { close(closed) })
+	done := make(chan error)
+	go func() { done <- app.Start() }()
+
+	assertClosed(t, srv.served, "the server to serve")
+
+	start := time.Now()
+	syscall.Kill(syscall.Getpid(), syscall.SIGTERM)
+
+	for app.Health().Ready().Ready {
+		time.Sleep(time.Millisecond)
+	}
+
+	select {
+	case <-closed:
+		t.Fatalf("Expected the application to keep running during the grace period")
+	case <-time.After(grace / 2):
+	}
+
+	poker.AssertNoError(t, <-done)
+
+	if elapsed := time.Since(start); elapsed < grace {
+		t.Errorf("Expected the application to stop after %v but it stopped after %v", grace, elapsed)
+	}
+}
+
+//assertClosed waits for ch to be closed
+func assertClosed(t *testing.T, ch <-chan struct{}, what string) {
+	t.Helper()
+
+	select {
+	case <-ch:
+	case <-time.After(500 * time.Millisecond):
+		t.Errorf("Expected %s", what)
+	}
+}
+
+func TestCreateDefaultApplication(t *testing.T) {
+	t.Run("An invalid logging level is a config error", func(t *testing.T) {
+		dir := t.TempDir()
+		_, err := server.CreateDefaultApplication(writeConfig(t, dir, testConfig{
+			DbFileName: filepath.Join(dir, "game.db.json"),
+			LogLevel:   "loud",
+		}))
+
+		assertErrorAs(t, err, new(*server.ConfigError))
+	})
+
+	t.Run("Invalid payouts are a config error", func(t *testing.T) {
+		dir := t.TempDir()
+		_, err := server.CreateDefaultApplication(writeConfig(t, dir, testConfig{
+			DbFileName: filepath.Join(dir, "game.db.json"),
+			Payouts:    "[{minEntries: 2, shares: [60, 60]}]",
+		}))
+
+		assertErrorAs(t, err, new(*server.ConfigError))
+	})
+
+	t.Run("A database that is a directory is a config error", func(t *testing.T) {
+		dir := t.TempDir()
+		_, err := server.CreateDefaultApplication(writeConfig(t, dir, testConfig{DbFileName: dir}))
+
+		assertErrorAs(t, err, new(*server.ConfigError))
+	})
+
+	t.Run("A database that is not JSON is a store error", func(t *testing.T) {
+		dir := t.TempDir()
+		dbFileName := filepath.Join(dir, "game.db.json")
+		poker.AssertNoError(t, ioutil.WriteFile(dbFileName, []byte("not json"), 0600))
+
+		_, err := server.CreateDefaultApplication(writeConfig(t, dir, testConfig{DbFileName: dbFileName}))
+
+		assertErrorAs(t, err, new(*server.StoreError))
+	})
+
+	t.Run("Interrupted games that are not JSON are a store error", func(t *testing.T) {
+		dir := t.TempDir()
+		gamesFile := filepath.Join(dir, "interrupted.games.json")
+		poker.AssertNoError(t, ioutil.WriteFile(gamesFile, []byte("not json"), 0600))
+
+		_, err := server.CreateDefaultApplication(writeConfig(t, dir, testConfig{
+			DbFileName: filepath.Join(dir, "game.db.json"),
+			GamesFile:  gamesFile,
+		}))
+
+		assertErrorAs(t, err, new(*server.StoreError))
+	})
+
+	t.Run("A missing assets directory is a config error", func(t *testing.T) {
+		dir := t.TempDir()
+		_, err := server.CreateDefaultApplication(writeConfig(t, dir, testConfig{
+			DbFileName: filepath.Join(dir, "game.db.json"),
+			AssetsDir:  filepath.Join(dir, "missing"),
+		}))
+
+		assertErrorAs(t, err, new(*server.ConfigError))
+	})
+
+	t.Run("A broken template is a template error", func(t *testing.T) {
+		dir := t.TempDir()
+		poker.AssertNoError(t, ioutil.WriteFile(filepath.Join(dir, "game.html"), []byte("{{ broken"), 0600))
+
+		_, err := server.CreateDefaultApplication(writeConfig(t, dir, testConfig{
+			DbFileName: filepath.Join(dir, "game.db.json"),
+			AssetsDir:  dir,
+		}))
+
+		assertErrorAs(t, err, new(*server.TemplateError))
+	})
+
+	t.Run("A port in use is a bind error when starting", func(t *testing.T) {
+		listener, err := net.Listen("tcp", localAddress)
+		poker.AssertNoError(t, err)
+		defer listener.Close()
+
+		dir := t.TempDir()
+		app, err := server.CreateDefaultApplication(writeConfig(t, dir, testConfig{
+			DbFileName: filepath.Join(dir, "game.db.json"),
+			Port:       listener.Addr().String(),
+		}))
+		poker.AssertNoError(t, err)
+
+		assertErrorAs(t, app.Start(), new(*server.BindError))
+	})
+}
+
+//testConfig holds the values written to the configuration file of TestCreateDefaultApplication
+type testConfig struct {
+	DbFileName  string
+	GamesFile   string
+	SnapshotDir string
+	Port        string
+	AdminPort   string
+	AssetsDir   string
+	LogLevel    string
+	Payouts     string
+	RateLimit   string
+	Alerters    string
+}
+
+//writeConfig writes a configuration file to dir and returns its name and path
+func writeConfig(t *testing.T, dir string, conf testConfig) (string, string) {
+	t.Helper()
+
+	if conf.Port == "" {
+		conf.Port = localAddress
+	}
+
+	if conf.LogLevel == "" {
+		conf.LogLevel = "error"
+	}
+
+	if conf.Payouts == "" {
+		conf.Payouts = "[]"
+	}
+
+	if conf.RateLimit == "" {
+		conf.RateLimit = "{}"
+	}
+
+	if conf.Alerters == "" {
+		conf.Alerters = "[]"
+	}
+
+	if conf.SnapshotDir == "" {
+		conf.SnapshotDir = filepath.Join(dir, "snapshots")
+	}
+
+	content := fmt.Sprintf(`database:
+   fileName: %q
+   interruptedGamesFile: %q
+   snapshotDir: %q
+server:
+   port: %q
+   adminPort: %q
+   assetsDir: %q
+   rateLimit: %s
+logging:
+   level: %q
+tournament:
+   payouts: %s
+alerters: %s
+`, conf.DbFileName, conf.GamesFile, conf.SnapshotDir, conf.Port, conf.AdminPort, conf.AssetsDir, conf.RateLimit,
+		conf.LogLevel, conf.Payouts, conf.Alerters)
+
+	poker.AssertNoError(t, ioutil.WriteFile(filepath.Join(dir, "testConfig.yaml"), []byte(content), 0600))
+
+	return "testConfig", dir
+}
+
+func assertErrorAs(t *testing.T, err error, target interface{}) {
+	t.Helper()
+
+	if !errors.As(err, target) {
+		t.Errorf("Expected %T but got %v", target, err)
+	}
+}
+
+func TestNewTournamentOptions(t *testing.T) {
+	t.Run("Configured payouts are converted to a payout table", func(t *testing.T) {
+		conf := configuration.TournamentConfiguration{
+			BuyIn:   20,
+			Payouts: []configuration.PayoutConfiguration{{MinEntries: 2, Shares: []int{60, 40}}},
+		}
+
+		options, err := server.NewTournamentOptions(conf)
+
+		poker.AssertNoError(t, err)
+
+		if options.BuyIn != 20 || len(options.Payouts) != 1 || options.Payouts[0].Shares[0] != 60 {
+			t.Errorf("Unexpected tournament options %+v", options)
+		}
+	})
+
+	t.Run("Payouts that do not add up to the prize pool are rejected", func(t *testing.T) {
+		conf := configuration.TournamentConfiguration{
+			Payouts: []configuration.PayoutConfiguration{{MinEntries: 2, Shares: []int{60}}},
+		}
+
+		_, err := server.NewTournamentOptions(conf)
+
+		poker.AssertError(t, err)
+	})
+}
+
+func TestNewAlerter(t *testing.T) {
+	t.Run("Every configured sink is created", func(t *testing.T) {
+		confs := []configuration.AlerterConfiguration{
+			{Type: "writer"},
+			{Type: "terminal", Color: "blue"},
+			{Type: "webhook", URL: "http://localhost/alerts", Retries: 3},
+		}
+
+		_, err := server.NewAlerter(confs)
+
+		poker.AssertNoError(t, err)
+	})
+
+	t.Run("Unknown types and webhooks without a url are rejected", func(t *testing.T) {
+		for _, conf := range []configuration.AlerterConfiguration{{Type: "pager"}, {Type: "webhook"}} {
+			_, err := server.NewAlerter([]configuration.AlerterConfiguration{conf})
+
+			poker.AssertError(t, err)
+		}
+	})
 }
@@ -0,0 +1,34 @@
+package server
+
+import (
+	"io"
+	poker "learning/17_HTTP"
+
+	"github.com/spf13/pflag"
+)
+
+//SnapshotCommand runs a snapshot command like "snapshot", "snapshots" or "restore {name}" on the
+//database of the configuration. The snapshots are kept in its snapshot directory. It returns a
+//ConfigError when the configuration can not be read and a StoreError when the database can not be
+//opened. Restore the database of a running server through the admin API instead
+func SnapshotCommand(configFileName, configFilePath string, flags *pflag.FlagSet, args []string,
+	out io.Writer) error {
+
+	conf, err := readConfiguration(configFileName, configFilePath, flags)
+
+	if err != nil {
+		return err
+	}
+
+	store, closeStore, err := poker.GenerateFileSystemPlayerStore(conf.GetDatabaseFileName())
+
+	if err != nil {
+		return &StoreError{conf.GetDatabaseFileName(), err}
+	}
+
+	defer closeStore()
+
+	snapshotter := poker.NewSnapshotter(store, conf.GetSnapshotDir(), conf.GetSnapshotRetention())
+
+	return poker.SnapshotCommand(snapshotter, args, out)
+}
@@ -0,0 +1,59 @@
+package server_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	poker "learning/17_HTTP"
+	server "learning/17_HTTP/server"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSnapshotCommand(t *testing.T) {
+	t.Run("Snapshots the database of the configuration into its snapshot directory", func(t *testing.T) {
+		dir := t.TempDir()
+		dbFileName := filepath.Join(dir, "game.db.json")
+		snapshotDir := filepath.Join(dir, "game-snapshots")
+		poker.AssertNoError(t, ioutil.WriteFile(dbFileName, []byte(`[{"Name": "Cleo", "Wins": 10}]`), 0600))
+
+		name, path := writeConfig(t, dir, testConfig{DbFileName: dbFileName, SnapshotDir: snapshotDir})
+		out := &bytes.Buffer{}
+
+		poker.AssertNoError(t, server.SnapshotCommand(name, path, nil, []string{"snapshot"}, out))
+
+		files, err := ioutil.ReadDir(snapshotDir)
+		poker.AssertNoError(t, err)
+
+		if len(files) != 1 || !strings.Contains(out.String(), files[0].Name()) {
+			t.Fatalf("got output %q and %d snapshots in %s want the created one", out.String(), len(files),
+				snapshotDir)
+		}
+
+		snapshot, err := ioutil.ReadFile(filepath.Join(snapshotDir, files[0].Name()))
+		poker.AssertNoError(t, err)
+
+		if !strings.Contains(string(snapshot), "Cleo") {
+			t.Errorf("got snapshot %s want the league of %s", snapshot, dbFileName)
+		}
+
+		out.Reset()
+		poker.AssertNoError(t, server.SnapshotCommand(name, path, nil, []string{"snapshots"}, out))
+		poker.AssertResponseBody(t, out.String(), "1. "+files[0].Name()+"\n")
+	})
+
+	t.Run("A missing configuration is a config error", func(t *testing.T) {
+		err := server.SnapshotCommand("missing", t.TempDir(), nil, []string{"snapshots"}, &bytes.Buffer{})
+
+		assertErrorAs(t, err, new(*server.ConfigError))
+	})
+
+	t.Run("A database that can not be opened is a store error", func(t *testing.T) {
+		dir := t.TempDir()
+		name, path := writeConfig(t, dir, testConfig{DbFileName: filepath.Join(dir, "missing", "game.db.json")})
+
+		err := server.SnapshotCommand(name, path, nil, []string{"snapshots"}, &bytes.Buffer{})
+
+		assertErrorAs(t, err, new(*server.StoreError))
+	})
+}
@@ -0,0 +1,141 @@
+package server
+
+import (
+	"crypto/tls"
+	"fmt"
+	configuration "learning/17_HTTP/config"
+	"learning/17_HTTP/logging"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+)
+
+//CertificateReloader serves a certificate loaded from disk that can be replaced while the server runs
+type CertificateReloader struct {
+	certFile string
+	keyFile  string
+	cert     *tls.Certificate
+	mx       sync.RWMutex
+}
+
+//NewCertificateReloader loads the certificate and key from the given files
+func NewCertificateReloader(certFile, keyFile string) (*CertificateReloader, error) {
+	reloader := &CertificateReloader{certFile: certFile, keyFile: keyFile}
+
+	if err := reloader.Reload(); err != nil {
+		return nil, err
+	}
+
+	return reloader, nil
+}
+
+//Reload reads the certificate and key again. The old certificate is kept if they can not be loaded
+func (c *CertificateReloader) Reload() error {
+	cert, err := tls.LoadX509KeyPair(c.certFile, c.keyFile)
+
+	if err != nil {
+		return fmt.Errorf("Could not load certificate %s with key %s %v", c.certFile, c.keyFile, err)
+	}
+
+	c.mx.Lock()
+	defer c.mx.Unlock()
+
+	c.cert = &cert
+
+	return nil
+}
+
+//GetCertificate returns the current certificate. It is used as tls.Config.GetCertificate
+func (c *CertificateReloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	c.mx.RLock()
+	defer c.mx.RUnlock()
+
+	return c.cert, nil
+}
+
+//ReloadOnSighup reloads the certificate every time the process gets a SIGHUP until stop is called
+func ReloadOnSighup(reloader *CertificateReloader) (stop func()) {
+	c := make(chan os.Signal, 1)
+	signal.Notify(c, syscall.SIGHUP)
+
+	done := make(chan struct{})
+
+	go func() {
+		for {
+			select {
+			case <-c:
+				if err := reloader.Reload(); err != nil {
+					logging.Default().Error("Keeping the old certificate", "error", err)
+					continue
+				}
+
+				logging.Default().Info("Reloaded certificate", "file", reloader.certFile)
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	return func() {
+		signal.Stop(c)
+		close(done)
+	}
+}
+
+//tlsServer is a http.Server that serves HTTPS on the listeners it is given
+type tlsServer struct {
+	*http.Server
+}
+
+func (s tlsServer) Serve(listener net.Listener) error {
+	return s.ServeTLS(listener, "", "")
+}
+
+//NewHTTPServer creates the server of the application. It serves HTTPS when TLS is enabled and
+//then also returns the reloader of its certificate
+func NewHTTPServer(port string, handler http.Handler, conf configuration.TLSConfiguration) (Server, *CertificateReloader, error) {
+	server := &http.Server{
+		Addr:    port,
+		Handler: handler,
+	}
+
+	if !conf.Enabled() {
+		return server, nil, nil
+	}
+
+	reloader, err := NewCertificateReloader(conf.CertFile, conf.KeyFile)
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	server.TLSConfig = &tls.Config{
+		MinVersion:     tls.VersionTLS12,
+		GetCertificate: reloader.GetCertificate,
+	}
+
+	return tlsServer{server}, reloader, nil
+}
+
+//NewRedirectHandler redirects every request to the same path over HTTPS on the given port
+func NewRedirectHandler(httpsPort string) http.Handler {
+	_, port, _ := net.SplitHostPort(httpsPort)
+
+	return http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
+		host := req.Host
+
+		if hostname, _, err := net.SplitHostPort(req.Host); err == nil {
+			host = hostname
+		}
+
+		if port != "" && port != "443" {
+			host = net.JoinHostPort(host, port)
+		}
+
+		target := "https://" + host + req.URL.RequestURI()
+		http.Redirect(resp, req, target, http.StatusPermanentRedirect)
+	})
+}
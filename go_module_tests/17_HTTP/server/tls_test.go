@@ -0,0 +1,188 @@
+package server_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	poker "learning/17_HTTP"
+	configuration "learning/17_HTTP/config"
+	server "learning/17_HTTP/server"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestCertificateReloader(t *testing.T) {
+	dir, cleanDir := createTempDir(t)
+	defer cleanDir()
+
+	certFile, keyFile := writeCertificate(t, dir, 1)
+
+	reloader, err := server.NewCertificateReloader(certFile, keyFile)
+	poker.AssertNoError(t, err)
+
+	listener, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{GetCertificate: reloader.GetCertificate})
+	poker.AssertNoError(t, err)
+
+	go http.Serve(listener, http.NotFoundHandler())
+	defer listener.Close()
+
+	assertServedSerial(t, listener.Addr().String(), 1)
+
+	t.Run("A new certificate is served after SIGHUP", func(t *testing.T) {
+		stop := server.ReloadOnSighup(reloader)
+		defer stop()
+
+		writeCertificate(t, dir, 2)
+		syscall.Kill(syscall.Getpid(), syscall.SIGHUP)
+
+		deadline := time.Now().Add(time.Second)
+
+		for servedSerial(t, listener.Addr().String()) != 2 && time.Now().Before(deadline) {
+			time.Sleep(5 * time.Millisecond)
+		}
+
+		assertServedSerial(t, listener.Addr().String(), 2)
+	})
+
+	t.Run("A broken certificate does not replace the served one", func(t *testing.T) {
+		ioutil.WriteFile(keyFile, []byte("not a key"), 0600)
+
+		poker.AssertError(t, reloader.Reload())
+		assertServedSerial(t, listener.Addr().String(), 2)
+	})
+}
+
+func TestNewHTTPServer(t *testing.T) {
+	dir, cleanDir := createTempDir(t)
+	defer cleanDir()
+
+	certFile, keyFile := writeCertificate(t, dir, 1)
+
+	t.Run("Plain HTTP is served without certificates", func(t *testing.T) {
+		srv, reloader, err := server.NewHTTPServer(":0", http.NotFoundHandler(), configuration.TLSConfiguration{})
+
+		poker.AssertNoError(t, err)
+
+		if _, ok := srv.(*http.Server); !ok || reloader != nil {
+			t.Errorf("Expected a plain http.Server but got %T", srv)
+		}
+	})
+
+	t.Run("HTTPS is served with certificates", func(t *testing.T) {
+		conf := configuration.TLSConfiguration{CertFile: certFile, KeyFile: keyFile}
+		_, reloader, err := server.NewHTTPServer(":0", http.NotFoundHandler(), conf)
+
+		poker.AssertNoError(t, err)
+
+		if reloader == nil {
+			t.Errorf("Expected a certificate reloader")
+		}
+	})
+
+	t.Run("Missing certificates are reported", func(t *testing.T) {
+		conf := configuration.TLSConfiguration{CertFile: filepath.Join(dir, "missing.pem"), KeyFile: keyFile}
+		_, _, err := server.NewHTTPServer(":0", http.NotFoundHandler(), conf)
+
+		poker.AssertError(t, err)
+	})
+}
+
+func TestRedirectHandler(t *testing.T) {
+	cases := []struct {
+		httpsPort, url, want string
+	}{
+		{":8443", "http://poker.example.com:8000/league?sort=wins", "https://poker.example.com:8443/league?sort=wins"},
+		{":443", "http://poker.example.com/game/", "https://poker.example.com/game/"},
+	}
+
+	for _, test := range cases {
+		response := httptest.NewRecorder()
+		request := httptest.NewRequest(http.MethodGet, test.url, nil)
+
+		server.NewRedirectHandler(test.httpsPort).ServeHTTP(response, request)
+
+		poker.AssertStatusCode(t, response.Code, http.StatusPermanentRedirect)
+
+		if got := response.Header().Get("Location"); got != test.want {
+			t.Errorf("Expected a redirect to %s but got %s", test.want, got)
+		}
+	}
+}
+
+func createTempDir(t *testing.T) (string, func()) {
+	t.Helper()
+
+	dir, err := ioutil.TempDir("", "certs")
+
+	if err != nil {
+		t.Fatalf("Could not create temp dir %v", err)
+	}
+
+	return dir, func() { os.RemoveAll(dir) }
+}
+
+//writeCertificate generates a self signed certificate for localhost with the given serial number
+func writeCertificate(t *testing.T, dir string, serial int64) (certFile, keyFile string) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	poker.AssertNoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{Organization: []string{"Poker test"}},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		DNSNames:     []string{"localhost"},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	poker.AssertNoError(t, err)
+
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	poker.AssertNoError(t, err)
+
+	certFile, keyFile = filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+
+	poker.AssertNoError(t, ioutil.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600))
+	poker.AssertNoError(t, ioutil.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}), 0600))
+
+	return certFile, keyFile
+}
+
+func servedSerial(t *testing.T, addr string) int64 {
+	t.Helper()
+
+	conn, err := tls.Dial("tcp", addr, &tls.Config{InsecureSkipVerify: true})
+
+	if err != nil {
+		t.Fatalf("Could not connect to %s %v", addr, err)
+	}
+
+	defer conn.Close()
+
+	return conn.ConnectionState().PeerCertificates[0].SerialNumber.Int64()
+}
+
+func assertServedSerial(t *testing.T, addr string, want int64) {
+	t.Helper()
+
+	if got := servedSerial(t, addr); got != want {
+		t.Errorf("Expected certificate %d to be served but got %d", want, got)
+	}
+}
@@ -0,0 +1,157 @@
+package poker
+
+import (
+	"bufio"
+	"fmt"
+	"learning/17_HTTP/metrics"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+//unmatchedRoute labels requests that no route handles
+const unmatchedRoute string = "unmatched"
+
+//ServerMetrics are the metrics of the poker server. A nil *ServerMetrics records nothing
+type ServerMetrics struct {
+	requests        *metrics.Counter
+	requestDuration *metrics.Histogram
+	webSockets      *metrics.Gauge
+	wins            *metrics.Counter
+	storeWrites     *metrics.Histogram
+	storeErrors     *metrics.Counter
+	games           []*GameRegistry
+	mx              sync.Mutex
+}
+
+//NewServerMetrics registers the metrics of the poker server in the given registry
+func NewServerMetrics(registry *metrics.Registry) *ServerMetrics {
+	m := &ServerMetrics{
+		requests: registry.NewCounter("poker_http_requests_total",
+			"HTTP requests by route, method and status code.", "route", "method", "code"),
+		requestDuration: registry.NewHistogram("poker_http_request_duration_seconds",
+			"Time spent serving HTTP requests by route.", metrics.DefaultBuckets, "route"),
+		webSockets: registry.NewGauge("poker_websocket_connections",
+			"Open websocket connections."),
+		wins: registry.NewCounter("poker_wins_recorded_total",
+			"Wins recorded in the player store."),
+		storeWrites: registry.NewHistogram("poker_store_write_duration_seconds",
+			"Time spent writing the player store to disk.", metrics.DefaultBuckets),
+		storeErrors: registry.NewCounter("poker_store_write_errors_total",
+			"Failed writes of the player store."),
+	}
+
+	registry.NewGaugeFunc("poker_running_games", "Games that have not finished.", m.runningGames)
+
+	return m
+}
+
+//Instrument counts and times the requests served by the router. Requests are labelled with
+//the pattern of the route that handles them so unknown paths do not create new series
+func (m *ServerMetrics) Instrument(router *http.ServeMux) http.Handler {
+	if m == nil {
+		return router
+	}
+
+	return http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
+		_, route := router.Handler(req)
+
+		if route == "" {
+			route = unmatchedRoute
+		}
+
+		recorder := &statusRecorder{ResponseWriter: resp, status: http.StatusOK}
+		start := time.Now()
+
+		router.ServeHTTP(recorder, req)
+
+		m.requests.Inc(route, req.Method, strconv.Itoa(recorder.status))
+		m.requestDuration.Observe(time.Since(start).Seconds(), route)
+	})
+}
+
+func (m *ServerMetrics) watchGames(games *GameRegistry) {
+	if m == nil {
+		return
+	}
+
+	m.mx.Lock()
+	defer m.mx.Unlock()
+
+	m.games = append(m.games, games)
+}
+
+func (m *ServerMetrics) runningGames() float64 {
+	m.mx.Lock()
+	defer m.mx.Unlock()
+
+	running := 0
+
+	for _, games := range m.games {
+		running += games.Running()
+	}
+
+	return float64(running)
+}
+
+func (m *ServerMetrics) webSocketOpened() {
+	if m != nil {
+		m.webSockets.Inc()
+	}
+}
+
+func (m *ServerMetrics) webSocketClosed() {
+	if m != nil {
+		m.webSockets.Dec()
+	}
+}
+
+func (m *ServerMetrics) winRecorded() {
+	if m != nil {
+		m.wins.Inc()
+	}
+}
+
+func (m *ServerMetrics) storeWritten(duration time.Duration, err error) {
+	if m == nil {
+		return
+	}
+
+	m.storeWrites.Observe(duration.Seconds())
+
+	if err != nil {
+		m.storeErrors.Inc()
+	}
+}
+
+//statusRecorder remembers the status code of a response. It passes flushes through for
+//server-sent events and hijacking for websockets
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (s *statusRecorder) WriteHeader(status int) {
+	s.status = status
+	s.ResponseWriter.WriteHeader(status)
+}
+
+func (s *statusRecorder) Flush() {
+	if flusher, ok := s.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+func (s *statusRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hijacker, ok := s.ResponseWriter.(http.Hijacker)
+
+	if !ok {
+		return nil, nil, fmt.Errorf("Response writer %T can not be hijacked", s.ResponseWriter)
+	}
+
+	s.status = http.StatusSwitchingProtocols
+
+	return hijacker.Hijack()
+}
@@ -0,0 +1,115 @@
+package poker_test
+
+import (
+	"bytes"
+	poker "learning/17_HTTP"
+	"learning/17_HTTP/metrics"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestServerMetrics(t *testing.T) {
+	t.Run("Requests are counted by route", func(t *testing.T) {
+		registry := metrics.NewRegistry()
+		server := poker.CreateNewPlayerServer(t, &poker.StubPlayerStore{}, &poker.SpyGame{})
+		server.SetMetrics(poker.NewServerMetrics(registry))
+
+		for _, path := range []string{"/league/", "/league/", "/missing"} {
+			server.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+		}
+
+		assertMetrics(t, registry,
+			`poker_http_requests_total{route="/league/",method="GET",code="200"} 2`,
+			`poker_http_requests_total{route="unmatched",method="GET",code="404"} 1`,
+			`poker_http_request_duration_seconds_count{route="/league/"} 2`)
+	})
+
+	t.Run("Websockets and running games are tracked", func(t *testing.T) {
+		registry := metrics.NewRegistry()
+		playerServer := poker.CreateNewPlayerServer(t, &poker.StubPlayerStore{}, &poker.SpyGame{})
+		playerServer.SetMetrics(poker.NewServerMetrics(registry))
+		server := httptest.NewServer(playerServer)
+		defer server.Close()
+
+		ws, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http")+"/ws/", nil)
+		poker.AssertNoError(t, err)
+		defer ws.Close()
+
+		ws.WriteMessage(websocket.TextMessage, []byte("3"))
+
+		assertMetricsEventually(t, registry, "poker_websocket_connections 1", "poker_running_games 1")
+
+		ws.WriteMessage(websocket.TextMessage, []byte("Chris wins"))
+
+		assertMetricsEventually(t, registry, "poker_websocket_connections 0", "poker_running_games 0",
+			`poker_http_requests_total{route="/ws/",method="GET",code="101"} 1`)
+	})
+
+	t.Run("Store wins, writes and write errors are counted", func(t *testing.T) {
+		registry := metrics.NewRegistry()
+		database, cleanDb := poker.CreateTempFile(t, "[]", "db")
+		defer cleanDb()
+
+		store, err := poker.NewFileSystemPlayerStore(database)
+		poker.AssertNoError(t, err)
+
+		store.SetMetrics(poker.NewServerMetrics(registry))
+		store.RecordWin("Chris")
+		database.Close()
+		store.RecordGame(poker.GameResult{FieldSize: 2, Positions: []string{"Cleo", "Chris"}})
+
+		assertMetrics(t, registry,
+			"poker_wins_recorded_total 2",
+			"poker_store_write_duration_seconds_count 2",
+			"poker_store_write_errors_total 1")
+	})
+}
+
+func exposeMetrics(registry *metrics.Registry) string {
+	buffer := &bytes.Buffer{}
+	registry.Expose(buffer)
+
+	return buffer.String()
+}
+
+func assertMetrics(t *testing.T, registry *metrics.Registry, lines ...string) {
+	t.Helper()
+
+	exposed := exposeMetrics(registry)
+
+	for _, line := range lines {
+		if !strings.Contains(exposed, line+"\n") {
+			t.Errorf("Expected %q in the metrics\n%s", line, exposed)
+		}
+	}
+}
+
+func assertMetricsEventually(t *testing.T, registry *metrics.Registry, lines ...string) {
+	t.Helper()
+
+	deadline := time.Now().Add(time.Second)
+
+	for time.Now().Before(deadline) {
+		exposed := exposeMetrics(registry)
+		found := 0
+
+		for _, line := range lines {
+			if strings.Contains(exposed, line+"\n") {
+				found++
+			}
+		}
+
+		if found == len(lines) {
+			return
+		}
+
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	assertMetrics(t, registry, lines...)
+}
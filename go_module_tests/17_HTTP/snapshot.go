@@ -0,0 +1,240 @@
+package poker
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	snapshotPrefix     string = "snapshot-"
+	snapshotSuffix     string = ".json"
+	snapshotTimeFormat string = "20060102T150405.000000000"
+)
+
+//ErrSnapshotNotFound is returned when restoring a snapshot that is not in the snapshot directory
+var ErrSnapshotNotFound = errors.New("Snapshot not found")
+
+//ErrInvalidSnapshot is returned when a snapshot fails validation and can not be restored
+var ErrInvalidSnapshot = errors.New("Invalid snapshot")
+
+//SnapshotStore is a store that can be copied while it is being written to and replaced as a whole
+type SnapshotStore interface {
+	Snapshot(to io.Writer) error
+	Restore(data LeagueData) error
+}
+
+//Snapshotter creates, prunes and restores snapshots of a SnapshotStore inside of a directory
+type Snapshotter struct {
+	store  SnapshotStore
+	dir    string
+	retain int
+	now    func() time.Time
+}
+
+//NewSnapshotter is a constructor for Snapshotter. Only the newest retain snapshots are kept in dir.
+//A retain value less than one keeps all snapshots
+func NewSnapshotter(store SnapshotStore, dir string, retain int) *Snapshotter {
+	return &Snapshotter{
+		store:  store,
+		dir:    dir,
+		retain: retain,
+		now:    time.Now,
+	}
+}
+
+//Create writes a new snapshot of the store and removes the ones that are over the retention limit.
+//It returns the name of the created snapshot
+func (s *Snapshotter) Create() (string, error) {
+	if err := os.MkdirAll(s.dir, 0755); err != nil {
+		return "", fmt.Errorf("Could not create snapshot directory %s %v", s.dir, err)
+	}
+
+	name := snapshotPrefix + s.now().UTC().Format(snapshotTimeFormat) + snapshotSuffix
+
+	tmp, err := ioutil.TempFile(s.dir, "."+name)
+
+	if err != nil {
+		return "", fmt.Errorf("Could not create temporary snapshot file %v", err)
+	}
+
+	defer os.Remove(tmp.Name())
+
+	if err := s.store.Snapshot(tmp); err != nil {
+		tmp.Close()
+		return "", fmt.Errorf("Could not write snapshot %s %v", name, err)
+	}
+
+	if err := tmp.Close(); err != nil {
+		return "", fmt.Errorf("Could not close snapshot %s %v", name, err)
+	}
+
+	if err := os.Rename(tmp.Name(), filepath.Join(s.dir, name)); err != nil {
+		return "", fmt.Errorf("Could not save snapshot %s %v", name, err)
+	}
+
+	return name, s.prune()
+}
+
+//List returns the names of all snapshots in the snapshot directory ordered from newest to oldest
+func (s *Snapshotter) List() ([]string, error) {
+	files, err := ioutil.ReadDir(s.dir)
+
+	if os.IsNotExist(err) {
+		return []string{}, nil
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("Could not read snapshot directory %s %v", s.dir, err)
+	}
+
+	names := []string{}
+
+	for _, file := range files {
+		if isSnapshotName(file.Name()) && !file.IsDir() {
+			names = append(names, file.Name())
+		}
+	}
+
+	sort.Sort(sort.Reverse(sort.StringSlice(names)))
+
+	return names, nil
+}
+
+//Restore validates the snapshot with the given name and swaps it in place of the current league.
+//A snapshot of the current state is taken before the swap so a bad restore can be undone
+func (s *Snapshotter) Restore(name string) error {
+	if !isSnapshotName(name) || filepath.Base(name) != name {
+		return fmt.Errorf("%w: %s", ErrSnapshotNotFound, name)
+	}
+
+	file, err := os.Open(filepath.Join(s.dir, name))
+
+	if os.IsNotExist(err) {
+		return fmt.Errorf("%w: %s", ErrSnapshotNotFound, name)
+	}
+
+	if err != nil {
+		return fmt.Errorf("Could not open snapshot %s %v", name, err)
+	}
+
+	defer file.Close()
+
+	data, err := ValidateSnapshot(file)
+
+	if err != nil {
+		return err
+	}
+
+	if _, err := s.Create(); err != nil {
+		return fmt.Errorf("Could not back up current league before restore %v", err)
+	}
+
+	if err := s.store.Restore(data); err != nil {
+		return fmt.Errorf("Could not restore snapshot %s %v", name, err)
+	}
+
+	return nil
+}
+
+func (s *Snapshotter) prune() error {
+	if s.retain < 1 {
+		return nil
+	}
+
+	names, err := s.List()
+
+	if err != nil {
+		return err
+	}
+
+	for len(names) > s.retain {
+		oldest := names[len(names)-1]
+
+		if err := os.Remove(filepath.Join(s.dir, oldest)); err != nil {
+			return fmt.Errorf("Could not remove old snapshot %s %v", oldest, err)
+		}
+
+		names = names[:len(names)-1]
+	}
+
+	return nil
+}
+
+func isSnapshotName(name string) bool {
+	return strings.HasPrefix(name, snapshotPrefix) && strings.HasSuffix(name, snapshotSuffix)
+}
+
+//ValidateSnapshot reads a league from a snapshot and checks that it can be safely restored
+func ValidateSnapshot(read io.Reader) (LeagueData, error) {
+	data, _, err := DecodeLeagueData(read)
+
+	if err != nil {
+		return LeagueData{}, fmt.Errorf("%w: %v", ErrInvalidSnapshot, err)
+	}
+
+	seen := map[string]bool{}
+
+	for _, player := range data.Players {
+		if seen[player.Name] {
+			return LeagueData{}, fmt.Errorf("%w: player %q is present more than once", ErrInvalidSnapshot, player.Name)
+		}
+
+		if player.Wins < 0 {
+			return LeagueData{}, fmt.Errorf("%w: player %q has negative wins", ErrInvalidSnapshot, player.Name)
+		}
+
+		seen[player.Name] = true
+	}
+
+	return data, nil
+}
+
+//SnapshotCommand executes a snapshot subcommand given as command line arguments.
+//Supported commands are "snapshot", "snapshots" and "restore {name}"
+func SnapshotCommand(s *Snapshotter, args []string, out io.Writer) error {
+	if len(args) == 0 {
+		return fmt.Errorf("No snapshot command given")
+	}
+
+	switch args[0] {
+	case "snapshot":
+		name, err := s.Create()
+
+		if err != nil {
+			return err
+		}
+
+		fmt.Fprintf(out, "Created snapshot %s\n", name)
+	case "snapshots":
+		names, err := s.List()
+
+		if err != nil {
+			return err
+		}
+
+		for i, name := range names {
+			fmt.Fprintf(out, "%d. %s\n", i+1, name)
+		}
+	case "restore":
+		if len(args) != 2 {
+			return fmt.Errorf("Usage: restore {snapshot name}")
+		}
+
+		if err := s.Restore(args[1]); err != nil {
+			return err
+		}
+
+		fmt.Fprintf(out, "Restored snapshot %s\n", args[1])
+	default:
+		return fmt.Errorf("Unknown snapshot command %q", args[0])
+	}
+
+	return nil
+}
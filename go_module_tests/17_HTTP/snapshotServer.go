@@ -0,0 +1,79 @@
+package poker
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+)
+
+const snapshotsPath string = "/admin/snapshots/"
+
+//SnapshotServer is the httpHandler for requests to /admin/snapshots/
+type SnapshotServer struct {
+	snapshotter *Snapshotter
+	http.Handler
+}
+
+//NewSnapshotServer is a constructor for SnapshotServer that creates a router for it.
+//GET lists the snapshots, POST creates a new one and POST restore/{name} restores a snapshot
+func NewSnapshotServer(snapshotter *Snapshotter) *SnapshotServer {
+	s := &SnapshotServer{snapshotter: snapshotter}
+
+	router := http.NewServeMux()
+	router.Handle(snapshotsPath, http.HandlerFunc(s.snapshotsHandler))
+	router.Handle(snapshotsPath+"restore/", http.HandlerFunc(s.restoreHandler))
+
+	s.Handler = router
+
+	return s
+}
+
+func (s *SnapshotServer) snapshotsHandler(resp http.ResponseWriter, req *http.Request) {
+	switch req.Method {
+	case http.MethodGet:
+		names, err := s.snapshotter.List()
+
+		if err != nil {
+			http.Error(resp, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		resp.Header().Set("content-type", jsonContentType)
+		json.NewEncoder(resp).Encode(names)
+	case http.MethodPost:
+		name, err := s.snapshotter.Create()
+
+		if err != nil {
+			http.Error(resp, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		resp.Header().Set("content-type", jsonContentType)
+		resp.WriteHeader(http.StatusCreated)
+		json.NewEncoder(resp).Encode(name)
+	default:
+		resp.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func (s *SnapshotServer) restoreHandler(resp http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		resp.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	name := strings.TrimPrefix(req.URL.Path, snapshotsPath+"restore/")
+	err := s.snapshotter.Restore(name)
+
+	switch {
+	case errors.Is(err, ErrSnapshotNotFound):
+		http.Error(resp, err.Error(), http.StatusNotFound)
+	case errors.Is(err, ErrInvalidSnapshot):
+		http.Error(resp, err.Error(), http.StatusUnprocessableEntity)
+	case err != nil:
+		http.Error(resp, err.Error(), http.StatusInternalServerError)
+	default:
+		resp.WriteHeader(http.StatusOK)
+	}
+}
@@ -0,0 +1,207 @@
+package poker
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestSnapshotter(t *testing.T) {
+	t.Run("Create writes the current league and keeps only the newest snapshots", func(t *testing.T) {
+		store, snapshotter, clean := createSnapshotter(t, `[{"Name": "Cleo", "Wins": 10}]`, 2)
+		defer clean()
+
+		var names []string
+		for i := 0; i < 3; i++ {
+			name, err := snapshotter.Create()
+			AssertNoError(t, err)
+			names = append(names, name)
+		}
+
+		got, err := snapshotter.List()
+		AssertNoError(t, err)
+
+		AssertStringSlice(t, got, []string{names[2], names[1]})
+
+		snapshot, err := os.Open(filepath.Join(snapshotter.dir, names[2]))
+		AssertNoError(t, err)
+		defer snapshot.Close()
+
+		data, err := ValidateSnapshot(snapshot)
+		AssertNoError(t, err)
+		AssertLeague(t, data.Players, store.GetLeague())
+	})
+
+	t.Run("Restore swaps in the snapshot and backs up the current league", func(t *testing.T) {
+		store, snapshotter, clean := createSnapshotter(t, `[{"Name": "Cleo", "Wins": 10}]`, 0)
+		defer clean()
+
+		name, err := snapshotter.Create()
+		AssertNoError(t, err)
+
+		store.RecordWin("Chris")
+
+		AssertNoError(t, snapshotter.Restore(name))
+		AssertLeague(t, store.GetLeague(), League{{"Cleo", 10}})
+
+		names, _ := snapshotter.List()
+		if len(names) != 2 {
+			t.Errorf("Expected a backup snapshot to be created before restore but got %v", names)
+		}
+	})
+
+	t.Run("Restore rejects invalid snapshots and leaves the league untouched", func(t *testing.T) {
+		store, snapshotter, clean := createSnapshotter(t, `[{"Name": "Cleo", "Wins": 10}]`, 0)
+		defer clean()
+
+		writeSnapshot(t, snapshotter, "snapshot-bad.json", `[{"Name": "Cleo", "Wins": 1}, {"Name": "Cleo", "Wins": 2}]`)
+		writeSnapshot(t, snapshotter, "snapshot-broken.json", `[{"Name": `)
+
+		for _, name := range []string{"snapshot-bad.json", "snapshot-broken.json"} {
+			err := snapshotter.Restore(name)
+
+			if !errors.Is(err, ErrInvalidSnapshot) {
+				t.Errorf("Expected %v but got %v", ErrInvalidSnapshot, err)
+			}
+		}
+
+		AssertLeague(t, store.GetLeague(), League{{"Cleo", 10}})
+	})
+
+	t.Run("Restore refuses names outside of the snapshot directory", func(t *testing.T) {
+		_, snapshotter, clean := createSnapshotter(t, "[]", 0)
+		defer clean()
+
+		for _, name := range []string{"../snapshot-x.json", "snapshot-missing.json", "game.db.json"} {
+			err := snapshotter.Restore(name)
+
+			if !errors.Is(err, ErrSnapshotNotFound) {
+				t.Errorf("Expected %v for %q but got %v", ErrSnapshotNotFound, name, err)
+			}
+		}
+	})
+
+	t.Run("Snapshots are consistent while wins are being recorded", func(t *testing.T) {
+		_, snapshotter, clean := createSnapshotter(t, "[]", 0)
+		defer clean()
+
+		store := snapshotter.store.(*FileSystemPlayerStore)
+		var wg sync.WaitGroup
+
+		for i := 0; i < 50; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				store.RecordWin("Chris")
+			}()
+		}
+
+		for i := 0; i < 5; i++ {
+			buffer := &bytes.Buffer{}
+			AssertNoError(t, store.Snapshot(buffer))
+
+			_, err := ValidateSnapshot(buffer)
+			AssertNoError(t, err)
+		}
+
+		wg.Wait()
+		AssertPlayerScore(t, store.GetPlayerScore("Chris"), 50)
+	})
+}
+
+func TestSnapshotCommand(t *testing.T) {
+	_, snapshotter, clean := createSnapshotter(t, "[]", 0)
+	defer clean()
+
+	out := &bytes.Buffer{}
+	AssertNoError(t, SnapshotCommand(snapshotter, []string{"snapshot"}, out))
+
+	names, _ := snapshotter.List()
+	out.Reset()
+	AssertNoError(t, SnapshotCommand(snapshotter, []string{"snapshots"}, out))
+	AssertResponseBody(t, out.String(), "1. "+names[0]+"\n")
+
+	AssertError(t, SnapshotCommand(snapshotter, []string{"restore"}, out))
+	AssertError(t, SnapshotCommand(snapshotter, []string{"unknown"}, out))
+}
+
+func TestSnapshotServer(t *testing.T) {
+	_, snapshotter, clean := createSnapshotter(t, `[{"Name": "Cleo", "Wins": 10}]`, 0)
+	defer clean()
+
+	server := NewSnapshotServer(snapshotter)
+
+	response := httptest.NewRecorder()
+	server.ServeHTTP(response, httptest.NewRequest(http.MethodPost, snapshotsPath, nil))
+	AssertStatusCode(t, response.Code, http.StatusCreated)
+
+	response = httptest.NewRecorder()
+	server.ServeHTTP(response, httptest.NewRequest(http.MethodGet, snapshotsPath, nil))
+	AssertStatusCode(t, response.Code, http.StatusOK)
+	AssertJSONContentType(t, response)
+
+	names, _ := snapshotter.List()
+	if !strings.Contains(response.Body.String(), names[0]) {
+		t.Errorf("Expected snapshot %s to be listed but got %s", names[0], response.Body.String())
+	}
+
+	writeSnapshot(t, snapshotter, "snapshot-bad.json", "{")
+
+	cases := []struct {
+		name string
+		code int
+	}{
+		{names[0], http.StatusOK},
+		{"snapshot-bad.json", http.StatusUnprocessableEntity},
+		{"snapshot-missing.json", http.StatusNotFound},
+	}
+
+	for _, test := range cases {
+		t.Run("restore "+test.name, func(t *testing.T) {
+			response := httptest.NewRecorder()
+			request := httptest.NewRequest(http.MethodPost, snapshotsPath+"restore/"+test.name, nil)
+
+			server.ServeHTTP(response, request)
+
+			AssertStatusCode(t, response.Code, test.code)
+		})
+	}
+}
+
+func createSnapshotter(t *testing.T, initialData string, retain int) (*FileSystemPlayerStore, *Snapshotter, func()) {
+	t.Helper()
+
+	database, cleanDb := CreateTempFile(t, initialData, fileName)
+	store, err := NewFileSystemPlayerStore(database)
+	AssertNoError(t, err)
+
+	dir, err := ioutil.TempDir("", "snapshots")
+	AssertNoError(t, err)
+
+	snapshotter := NewSnapshotter(store, dir, retain)
+	tick := time.Date(2020, time.September, 1, 20, 0, 0, 0, time.UTC)
+	snapshotter.now = func() time.Time {
+		tick = tick.Add(time.Second)
+		return tick
+	}
+
+	return store, snapshotter, func() {
+		cleanDb()
+		os.RemoveAll(dir)
+	}
+}
+
+func writeSnapshot(t *testing.T, snapshotter *Snapshotter, name, data string) {
+	t.Helper()
+
+	err := ioutil.WriteFile(filepath.Join(snapshotter.dir, name), []byte(data), 0600)
+	AssertNoError(t, err)
+}
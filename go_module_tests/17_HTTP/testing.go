@@ -7,6 +7,7 @@ import (
 	"net/http/httptest"
 	"os"
 	"reflect"
+	"sync"
 	"testing"
 	"time"
 )
@@ -19,25 +20,65 @@ const (
 	TestServerPort string = "5000"
 )
 
+//SpyGame records how it was started and won. The games of a PlayerServer are played on other
+//goroutines so the calls are read through methods that hold its lock
 type SpyGame struct {
-	StartCalled     bool
-	StartCalledWith int
-	BlindAlert      []byte
+	BlindAlert []byte
 
-	WinCalled     bool
-	WinCalledWith string
+	startCalled     bool
+	startCalledWith int
+	winCalled       bool
+	winCalledWith   string
+	mx              sync.Mutex
 }
 
 func (s *SpyGame) Start(numberOfPlayers int, to io.Writer) {
-	s.StartCalled = true
-	s.StartCalledWith = numberOfPlayers
+	s.mx.Lock()
+	s.startCalled = true
+	s.startCalledWith = numberOfPlayers
+	s.mx.Unlock()
 
 	to.Write(s.BlindAlert)
 }
 
 func (s *SpyGame) Win(winner string) {
-	s.WinCalled = true
-	s.WinCalledWith = winner
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	s.winCalled = true
+	s.winCalledWith = winner
+}
+
+//StartCalled tells if the game was started
+func (s *SpyGame) StartCalled() bool {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	return s.startCalled
+}
+
+//StartCalledWith returns the number of players the game was last started with
+func (s *SpyGame) StartCalledWith() int {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	return s.startCalledWith
+}
+
+//WinCalled tells if a winner was declared
+func (s *SpyGame) WinCalled() bool {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	return s.winCalled
+}
+
+//WinCalledWith returns the last winner that was declared
+func (s *SpyGame) WinCalledWith() string {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	return s.winCalledWith
 }
 
 type StubPlayerStore struct {
@@ -58,18 +99,6 @@ func (s StubPlayerStore) GetLeague() League {
 	return s.league
 }
 
-func AssertTrueWithRetry(t *testing.T, got *bool) {
-	t.Helper()
-
-	passed := retryUntil(500*time.Millisecond, func() bool {
-		return *got
-	})
-
-	if !passed {
-		t.Errorf("expected true but got false")
-	}
-}
-
 func AssertFalse(t *testing.T, got bool) {
 	t.Helper()
 	if got {
@@ -110,11 +139,11 @@ func AssertGameStartedWithXNumberOfPlayers(t *testing.T, game *SpyGame, numberOf
 	t.Helper()
 
 	passed := retryUntil(500*time.Millisecond, func() bool {
-		return game.StartCalled && game.StartCalledWith == numberOfPlayers
+		return game.StartCalled() && game.StartCalledWith() == numberOfPlayers
 	})
 
 	if !passed {
-		t.Errorf("expected start called with %d but got %d", numberOfPlayers, game.StartCalledWith)
+		t.Errorf("expected start called with %d but got %d", numberOfPlayers, game.StartCalledWith())
 	}
 }
 
@@ -130,11 +159,11 @@ func AssertGameWinCalled(t *testing.T, game *SpyGame, player string) {
 	t.Helper()
 
 	passed := retryUntil(500*time.Millisecond, func() bool {
-		return game.WinCalled && game.WinCalledWith == player
+		return game.WinCalled() && game.WinCalledWith() == player
 	})
 
 	if !passed {
-		t.Errorf("expected finish called with %q but got %q", player, game.WinCalledWith)
+		t.Errorf("expected finish called with %q but got %q", player, game.WinCalledWith())
 	}
 }
 
@@ -182,6 +211,14 @@ func NewGetScoreRequest(player string) *http.Request {
 	return request
 }
 
+//NewGetProfileRequest creates a request for the statistics of a player in JSON format
+func NewGetProfileRequest(player string) *http.Request {
+	request := NewGetScoreRequest(player)
+	request.Header.Set("accept", jsonContentType)
+
+	return request
+}
+
 func AssertJSONContentType(t *testing.T, response *httptest.ResponseRecorder) {
 	t.Helper()
 	if response.Result().Header.Get("content-type") != jsonContentType {
@@ -264,3 +301,12 @@ func AssertNoError(t *testing.T, err error) {
 		t.Fatalf("Unexpected error %v", err)
 	}
 }
+
+//AssertStringSlice checks that two string slices hold the same elements in the same order
+func AssertStringSlice(t *testing.T, got, want []string) {
+	t.Helper()
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v want %v", got, want)
+	}
+}
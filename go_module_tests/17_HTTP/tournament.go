@@ -0,0 +1,381 @@
+package poker
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+	"sync"
+)
+
+//Suffixes of the messages players send during a game
+const (
+	winSuffix       string = " wins"
+	eliminateSuffix string = " is out"
+	rebuySuffix     string = " rebuys"
+)
+
+//NotATournament is shown when an elimination or rebuy is sent to a game that does not track them
+const NotATournament string = "This game does not track eliminations and rebuys"
+
+//ErrTournamentNotStarted is returned when a player is eliminated before the tournament starts
+var ErrTournamentNotStarted = errors.New("Tournament has not started")
+
+//ErrPlayerAlreadyOut is returned when a player that is out of the tournament is eliminated again
+var ErrPlayerAlreadyOut = errors.New("Player is already out")
+
+//ErrPlayerNotOut is returned when a player that is still playing tries to rebuy
+var ErrPlayerNotOut = errors.New("Player is not out")
+
+//ErrTooManyEliminations is returned when every player but the winner is already out
+var ErrTooManyEliminations = errors.New("Only the winner is left in the tournament")
+
+//TournamentGame is a game in which players are knocked out one by one and can buy back in
+type TournamentGame interface {
+	AbstractGame
+	Eliminate(player string) error
+	Rebuy(player string) error
+}
+
+//PayoutLevel holds the percentage of the prize pool paid to each finishing position
+//for tournaments with at least MinEntries entries
+type PayoutLevel struct {
+	MinEntries int
+	Shares     []int
+}
+
+//PayoutTable holds the payout levels of a tournament
+type PayoutTable []PayoutLevel
+
+//DefaultPayoutTable is used by tournaments that are not given a payout table
+var DefaultPayoutTable = PayoutTable{
+	{MinEntries: 2, Shares: []int{100}},
+	{MinEntries: 5, Shares: []int{70, 30}},
+	{MinEntries: 8, Shares: []int{50, 30, 20}},
+	{MinEntries: 15, Shares: []int{40, 25, 20, 15}},
+}
+
+//Validate checks that the shares of every level add up to the whole prize pool
+func (p PayoutTable) Validate() error {
+	for _, level := range p {
+		total := 0
+
+		for _, share := range level.Shares {
+			if share < 0 {
+				return fmt.Errorf("Payout level for %d entries has a negative share", level.MinEntries)
+			}
+
+			total += share
+		}
+
+		if total != 100 {
+			return fmt.Errorf("Payout level for %d entries pays %d%% of the prize pool instead of 100%%",
+				level.MinEntries, total)
+		}
+	}
+
+	return nil
+}
+
+//Payouts splits the prize pool between the finishing positions starting from the winner.
+//The level with the most entries that does not exceed the given entries is used
+//and whatever is left after rounding goes to the winner
+func (p PayoutTable) Payouts(entries, prizePool int) []int {
+	var shares []int
+	best := -1
+
+	for _, level := range p {
+		if level.MinEntries <= entries && level.MinEntries > best {
+			shares, best = level.Shares, level.MinEntries
+		}
+	}
+
+	payouts := make([]int, len(shares))
+	paid := 0
+
+	for i, share := range shares {
+		payouts[i] = prizePool * share / 100
+		paid += payouts[i]
+	}
+
+	if len(payouts) > 0 {
+		payouts[0] += prizePool - paid
+	}
+
+	return payouts
+}
+
+//TournamentOptions holds the buy-in and rebuy prices, the payout table and the clock of a tournament.
+//A rebuy adds a starting stack to the chips in play
+type TournamentOptions struct {
+	BuyIn   int
+	Rebuy   int
+	Payouts PayoutTable
+	Clock   ClockOptions
+}
+
+func (o TournamentOptions) withDefaults() TournamentOptions {
+	if o.Payouts == nil {
+		o.Payouts = DefaultPayoutTable
+	}
+
+	return o
+}
+
+//Tournament is a Game that tracks buy-ins, rebuys and the order in which players are knocked out
+type Tournament struct {
+	game    *Game
+	store   PlayerStore
+	options TournamentOptions
+	running TournamentOptions
+
+	started         bool
+	numberOfPlayers int
+	rebuys          int
+	eliminated      []string
+	out             io.Writer
+	mx              sync.Mutex
+}
+
+//NewTournament is a constructor for Tournament. Blinds are scheduled like in a normal Game
+func NewTournament(store PlayerStore, alerter BlindAlerter, options TournamentOptions) *Tournament {
+	options = options.withDefaults()
+
+	return &Tournament{
+		game:    &Game{store: store, alerter: alerter, clock: options.Clock},
+		store:   store,
+		options: options,
+		out:     ioutil.Discard,
+	}
+}
+
+//Tournaments creates a new Tournament for every game so games played at the same time do not
+//share their players, eliminations and rebuys
+type Tournaments struct {
+	store   PlayerStore
+	alerter BlindAlerter
+	options TournamentOptions
+	mx      sync.Mutex
+}
+
+//NewTournaments is a constructor for Tournaments
+func NewTournaments(store PlayerStore, alerter BlindAlerter, options TournamentOptions) *Tournaments {
+	return &Tournaments{store: store, alerter: alerter, options: options}
+}
+
+//SetOptions changes the options of the tournaments created after the call
+func (t *Tournaments) SetOptions(options TournamentOptions) {
+	t.mx.Lock()
+	defer t.mx.Unlock()
+
+	t.options = options
+}
+
+//New creates a Tournament with the options set last
+func (t *Tournaments) New() AbstractGame {
+	t.mx.Lock()
+	defer t.mx.Unlock()
+
+	return NewTournament(t.store, t.alerter, t.options)
+}
+
+//SetOptions changes the prices, payouts and blind structure of the tournaments started after the
+//call. A tournament that is being played keeps the options it was started with
+func (t *Tournament) SetOptions(options TournamentOptions) {
+	t.mx.Lock()
+	defer t.mx.Unlock()
+
+	t.options = options.withDefaults()
+}
+
+//Start begins a new tournament with every player bought in and schedules the blinds
+func (t *Tournament) Start(numberOfPlayers int, to io.Writer) {
+	t.mx.Lock()
+	defer t.mx.Unlock()
+
+	t.started = true
+	t.running = t.options
+	t.game.clock = t.running.Clock
+	t.numberOfPlayers = numberOfPlayers
+	t.rebuys = 0
+	t.eliminated = nil
+	t.out = to
+
+	t.game.Start(numberOfPlayers, to)
+}
+
+//Eliminate knocks a player out of the tournament. Players finish in reverse order of elimination
+func (t *Tournament) Eliminate(player string) error {
+	t.mx.Lock()
+	defer t.mx.Unlock()
+
+	if !t.started {
+		return ErrTournamentNotStarted
+	}
+
+	if t.isOut(player) {
+		return fmt.Errorf("%w: %s", ErrPlayerAlreadyOut, player)
+	}
+
+	if len(t.eliminated) >= t.numberOfPlayers-1 {
+		return ErrTooManyEliminations
+	}
+
+	t.eliminated = append(t.eliminated, player)
+	t.game.Stacks().PlayerOut()
+	fmt.Fprintf(t.out, "%s finishes in position %d\n", player, t.numberOfPlayers-len(t.eliminated)+1)
+
+	return nil
+}
+
+//Rebuy brings a player that is out back into the tournament and adds the rebuy to the prize pool
+func (t *Tournament) Rebuy(player string) error {
+	t.mx.Lock()
+	defer t.mx.Unlock()
+
+	if !t.started {
+		return ErrTournamentNotStarted
+	}
+
+	for i, name := range t.eliminated {
+		if name == player {
+			t.eliminated = append(t.eliminated[:i], t.eliminated[i+1:]...)
+			t.rebuys++
+			t.game.Stacks().PlayerIn(t.running.Clock.StartingStack)
+			fmt.Fprintf(t.out, "%s is back in\n", player)
+
+			return nil
+		}
+	}
+
+	return fmt.Errorf("%w: %s", ErrPlayerNotOut, player)
+}
+
+//Win ends the tournament, writes the payouts and records the finishing positions
+func (t *Tournament) Win(winner string) {
+	t.mx.Lock()
+	defer t.mx.Unlock()
+
+	result := GameResult{
+		PlayedAt:  clockOrSystem(t.running.Clock.Time).Now(),
+		FieldSize: t.numberOfPlayers,
+		Positions: t.positions(winner),
+	}
+
+	prizePool := t.numberOfPlayers*t.running.BuyIn + t.rebuys*t.running.Rebuy
+	payouts := t.running.Payouts.Payouts(t.numberOfPlayers+t.rebuys, prizePool)
+
+	for i, amount := range payouts {
+		if i < len(result.Positions) && result.Positions[i] != "" {
+			fmt.Fprintf(t.out, "%d. %s wins %d\n", i+1, result.Positions[i], amount)
+		}
+	}
+
+	t.started = false
+	t.game.Stop()
+
+	if recorder, ok := t.store.(GameRecorder); ok {
+		recorder.RecordGame(result)
+		return
+	}
+
+	t.store.RecordWin(winner)
+}
+
+//Stop cancels the blinds that are still scheduled
+func (t *Tournament) Stop() {
+	t.game.Stop()
+}
+
+//TournamentState is the standing of a tournament that has not finished. Eliminated lists the
+//players that are out in the order they were knocked out
+type TournamentState struct {
+	Players    int
+	Rebuys     int
+	PrizePool  int
+	Eliminated []string
+}
+
+//State returns the standing of the tournament
+func (t *Tournament) State() TournamentState {
+	t.mx.Lock()
+	defer t.mx.Unlock()
+
+	return TournamentState{
+		Players:    t.numberOfPlayers,
+		Rebuys:     t.rebuys,
+		PrizePool:  t.numberOfPlayers*t.running.BuyIn + t.rebuys*t.running.Rebuy,
+		Eliminated: append([]string{}, t.eliminated...),
+	}
+}
+
+//positions lists the players in finishing order. Positions between the winner and the
+//players that were knocked out are unknown and left empty
+func (t *Tournament) positions(winner string) []string {
+	var out []string
+
+	for _, player := range t.eliminated {
+		if player != winner {
+			out = append(out, player)
+		}
+	}
+
+	size := t.numberOfPlayers
+
+	if size < len(out)+1 {
+		size = len(out) + 1
+	}
+
+	positions := make([]string, size)
+	positions[0] = winner
+
+	for i, player := range out {
+		positions[size-1-i] = player
+	}
+
+	return positions
+}
+
+func (t *Tournament) isOut(player string) bool {
+	for _, name := range t.eliminated {
+		if name == player {
+			return true
+		}
+	}
+
+	return false
+}
+
+//HandleGameMessage applies a "X is out", "X rebuys" or "X wins" message to a game.
+//Messages without a known suffix name the winner. It returns true when the game has finished
+func HandleGameMessage(game AbstractGame, msg string, out io.Writer) bool {
+	var action func(string) error
+
+	switch {
+	case strings.HasSuffix(msg, eliminateSuffix):
+		if tournament, ok := game.(TournamentGame); ok {
+			action = tournament.Eliminate
+		}
+		msg = strings.TrimSuffix(msg, eliminateSuffix)
+	case strings.HasSuffix(msg, rebuySuffix):
+		if tournament, ok := game.(TournamentGame); ok {
+			action = tournament.Rebuy
+		}
+		msg = strings.TrimSuffix(msg, rebuySuffix)
+	default:
+		game.Win(extractWinner(msg))
+		return true
+	}
+
+	if action == nil {
+		fmt.Fprintln(out, NotATournament)
+		return false
+	}
+
+	if err := action(msg); err != nil {
+		fmt.Fprintln(out, err)
+	}
+
+	return false
+}
@@ -0,0 +1,119 @@
+package poker
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+//AnnouncementKind tells what happens on the tournament clock
+type AnnouncementKind string
+
+//Kinds of announcements made by the tournament clock
+const (
+	LevelAnnouncement AnnouncementKind = "level"
+	BreakAnnouncement AnnouncementKind = "break"
+)
+
+//ClockOptions configures the chips and breaks of the tournament clock. Breaks are taken after
+//every BreakEvery levels and the chips in ColorUps are removed during the breaks in order.
+//Time is the Clock games and their alerts are timed with and defaults to the SystemClock
+type ClockOptions struct {
+	StartingStack int
+	BreakEvery    int
+	BreakLength   time.Duration
+	ColorUps      []int
+	Time          Clock
+}
+
+//Announcement is a single event on the tournament clock. Chip counts are read from Stacks
+//when the announcement is made so they are up to date with eliminations and rebuys
+type Announcement struct {
+	Kind      AnnouncementKind
+	Level     int
+	Blind     int
+	Remaining time.Duration
+	ColorUp   int
+	Stacks    *ChipStacks
+}
+
+func (a Announcement) String() string {
+	var parts []string
+
+	switch a.Kind {
+	case BreakAnnouncement:
+		parts = append(parts, fmt.Sprintf("Break after level %d", a.Level), fmt.Sprintf("%v remaining", a.Remaining))
+
+		if a.ColorUp > 0 {
+			parts = append(parts, fmt.Sprintf("Color up the %d chips", a.ColorUp))
+		}
+	default:
+		if a.Level > 0 {
+			parts = append(parts, fmt.Sprintf("Level %d", a.Level))
+		}
+
+		parts = append(parts, fmt.Sprintf("Blind is now %d", a.Blind))
+
+		if a.Remaining > 0 {
+			parts = append(parts, fmt.Sprintf("%v remaining", a.Remaining))
+		}
+	}
+
+	if a.Stacks != nil && a.Stacks.InPlay() > 0 {
+		parts = append(parts, fmt.Sprintf("%d chips in play, average stack %d", a.Stacks.InPlay(), a.Stacks.AverageStack()))
+	}
+
+	return strings.Join(parts, " - ") + "\n"
+}
+
+//ChipStacks tracks the chips in play and the number of players that still have chips
+type ChipStacks struct {
+	inPlay  int
+	players int
+	mx      sync.Mutex
+}
+
+//NewChipStacks creates ChipStacks for a number of players that all start with the same stack
+func NewChipStacks(numberOfPlayers, startingStack int) *ChipStacks {
+	return &ChipStacks{inPlay: numberOfPlayers * startingStack, players: numberOfPlayers}
+}
+
+//PlayerOut removes a player who has lost all of their chips
+func (c *ChipStacks) PlayerOut() {
+	c.mx.Lock()
+	defer c.mx.Unlock()
+
+	if c.players > 0 {
+		c.players--
+	}
+}
+
+//PlayerIn brings a player back with the given chips
+func (c *ChipStacks) PlayerIn(chips int) {
+	c.mx.Lock()
+	defer c.mx.Unlock()
+
+	c.players++
+	c.inPlay += chips
+}
+
+//InPlay returns the total number of chips on the table
+func (c *ChipStacks) InPlay() int {
+	c.mx.Lock()
+	defer c.mx.Unlock()
+
+	return c.inPlay
+}
+
+//AverageStack returns the average stack of the players that are still in
+func (c *ChipStacks) AverageStack() int {
+	c.mx.Lock()
+	defer c.mx.Unlock()
+
+	if c.players == 0 {
+		return 0
+	}
+
+	return c.inPlay / c.players
+}
@@ -0,0 +1,120 @@
+package poker_test
+
+import (
+	"io"
+	"io/ioutil"
+	poker "learning/17_HTTP"
+	"testing"
+	"time"
+)
+
+type SpyClockAlerter struct {
+	SpyBlindAlerter
+	announcements []scheduledAnnouncement
+}
+
+type scheduledAnnouncement struct {
+	at           time.Duration
+	announcement poker.Announcement
+}
+
+func (s *SpyClockAlerter) ScheduledAnnouncementAt(duration time.Duration, announcement poker.Announcement, to io.Writer) {
+	s.announcements = append(s.announcements, scheduledAnnouncement{duration, announcement})
+}
+
+func TestGameClock(t *testing.T) {
+	clock := poker.ClockOptions{
+		StartingStack: 1000,
+		BreakEvery:    2,
+		BreakLength:   5 * time.Minute,
+		ColorUps:      []int{25},
+	}
+
+	t.Run("Breaks and color ups are scheduled between levels", func(t *testing.T) {
+		alerter := &SpyClockAlerter{}
+		game := poker.NewGameWithClock(&poker.StubPlayerStore{}, alerter, clock)
+
+		game.Start(5, ioutil.Discard)
+
+		if len(alerter.alerts) != 0 {
+			t.Errorf("Expected only announcements for a ClockAlerter but got %v", alerter.alerts)
+		}
+
+		want := []struct {
+			at        time.Duration
+			kind      poker.AnnouncementKind
+			level     int
+			colorUp   int
+			remaining time.Duration
+		}{
+			{0, poker.LevelAnnouncement, 1, 0, 10 * time.Minute},
+			{10 * time.Minute, poker.LevelAnnouncement, 2, 0, 10 * time.Minute},
+			{20 * time.Minute, poker.BreakAnnouncement, 2, 25, 5 * time.Minute},
+			{25 * time.Minute, poker.LevelAnnouncement, 3, 0, 10 * time.Minute},
+			{35 * time.Minute, poker.LevelAnnouncement, 4, 0, 10 * time.Minute},
+			{45 * time.Minute, poker.BreakAnnouncement, 4, 0, 5 * time.Minute},
+			{50 * time.Minute, poker.LevelAnnouncement, 5, 0, 10 * time.Minute},
+		}
+
+		for i, test := range want {
+			got := alerter.announcements[i]
+
+			if got.at != test.at || got.announcement.Kind != test.kind || got.announcement.Level != test.level ||
+				got.announcement.ColorUp != test.colorUp || got.announcement.Remaining != test.remaining {
+				t.Errorf("Announcement %d: got %+v at %v want %+v", i, got.announcement, got.at, test)
+			}
+		}
+
+		last := alerter.announcements[len(alerter.announcements)-1]
+		if last.announcement.Kind != poker.LevelAnnouncement || last.announcement.Level != 11 {
+			t.Errorf("Expected no break after the last level but got %+v", last.announcement)
+		}
+	})
+
+	t.Run("Chip counts follow eliminations and rebuys", func(t *testing.T) {
+		alerter := &SpyClockAlerter{}
+		tournament := poker.NewTournament(&SpyGameRecorder{}, alerter, poker.TournamentOptions{Clock: clock})
+
+		tournament.Start(4, ioutil.Discard)
+		stacks := alerter.announcements[0].announcement.Stacks
+
+		assertChips(t, stacks, 4000, 1000)
+
+		poker.AssertNoError(t, tournament.Eliminate("Kiro"))
+		assertChips(t, stacks, 4000, 1333)
+
+		poker.AssertNoError(t, tournament.Rebuy("Kiro"))
+		assertChips(t, stacks, 5000, 1250)
+	})
+}
+
+func TestAnnouncementString(t *testing.T) {
+	stacks := poker.NewChipStacks(4, 1000)
+
+	cases := []struct {
+		announcement poker.Announcement
+		want         string
+	}{
+		{
+			poker.Announcement{Kind: poker.LevelAnnouncement, Level: 3, Blind: 300, Remaining: 12 * time.Minute, Stacks: stacks},
+			"Level 3 - Blind is now 300 - 12m0s remaining - 4000 chips in play, average stack 1000\n",
+		},
+		{
+			poker.Announcement{Kind: poker.BreakAnnouncement, Level: 4, Remaining: 10 * time.Minute, ColorUp: 25},
+			"Break after level 4 - 10m0s remaining - Color up the 25 chips\n",
+		},
+	}
+
+	for _, test := range cases {
+		poker.AssertResponseBody(t, test.announcement.String(), test.want)
+	}
+}
+
+func assertChips(t *testing.T, stacks *poker.ChipStacks, inPlay, average int) {
+	t.Helper()
+
+	if stacks.InPlay() != inPlay || stacks.AverageStack() != average {
+		t.Errorf("got %d chips in play with average %d, want %d with average %d",
+			stacks.InPlay(), stacks.AverageStack(), inPlay, average)
+	}
+}
@@ -0,0 +1,163 @@
+package poker_test
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	poker "learning/17_HTTP"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestPayoutTable(t *testing.T) {
+	table := poker.PayoutTable{
+		{MinEntries: 2, Shares: []int{100}},
+		{MinEntries: 5, Shares: []int{70, 30}},
+	}
+
+	cases := []struct {
+		entries, prizePool int
+		want               []int
+	}{
+		{1, 20, []int{}},
+		{3, 60, []int{60}},
+		{5, 100, []int{70, 30}},
+		{7, 145, []int{102, 43}},
+	}
+
+	for _, test := range cases {
+		got := table.Payouts(test.entries, test.prizePool)
+
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("Payouts for %d entries and %d in the pool: got %v want %v",
+				test.entries, test.prizePool, got, test.want)
+		}
+	}
+
+	t.Run("Shares have to add up to the whole prize pool", func(t *testing.T) {
+		poker.AssertNoError(t, table.Validate())
+		poker.AssertError(t, poker.PayoutTable{{MinEntries: 2, Shares: []int{60, 30}}}.Validate())
+		poker.AssertError(t, poker.PayoutTable{{MinEntries: 2, Shares: []int{110, -10}}}.Validate())
+	})
+}
+
+func TestTournament(t *testing.T) {
+	options := poker.TournamentOptions{BuyIn: 10, Rebuy: 10}
+
+	t.Run("Finishing positions follow the elimination order", func(t *testing.T) {
+		store := &SpyGameRecorder{}
+		out := &bytes.Buffer{}
+		tournament := poker.NewTournament(store, &SpyBlindAlerter{}, options)
+
+		tournament.Start(5, out)
+		poker.AssertNoError(t, tournament.Eliminate("Kiro"))
+		poker.AssertNoError(t, tournament.Eliminate("Joro"))
+		tournament.Win("Chris")
+
+		got := store.results[0]
+		want := []string{"Chris", "", "", "Joro", "Kiro"}
+
+		poker.AssertStringSlice(t, got.Positions, want)
+
+		if got.FieldSize != 5 {
+			t.Errorf("Expected a field of 5 but got %d", got.FieldSize)
+		}
+
+		if !strings.Contains(out.String(), "Kiro finishes in position 5\n") ||
+			!strings.Contains(out.String(), "1. Chris wins 35\n") {
+			t.Errorf("Unexpected tournament output %q", out.String())
+		}
+	})
+
+	t.Run("Rebuys bring players back and grow the prize pool", func(t *testing.T) {
+		store := &SpyGameRecorder{}
+		out := &bytes.Buffer{}
+		tournament := poker.NewTournament(store, &SpyBlindAlerter{}, options)
+
+		tournament.Start(4, out)
+		poker.AssertNoError(t, tournament.Eliminate("Kiro"))
+		poker.AssertNoError(t, tournament.Rebuy("Kiro"))
+		poker.AssertNoError(t, tournament.Eliminate("Joro"))
+		poker.AssertNoError(t, tournament.Eliminate("Kiro"))
+		poker.AssertNoError(t, tournament.Eliminate("Cleo"))
+		tournament.Win("Chris")
+
+		poker.AssertStringSlice(t, store.results[0].Positions, []string{"Chris", "Cleo", "Kiro", "Joro"})
+
+		if !strings.Contains(out.String(), "1. Chris wins 35\n2. Cleo wins 15\n") {
+			t.Errorf("Expected payouts from 5 entries but got %q", out.String())
+		}
+	})
+
+	t.Run("Invalid eliminations and rebuys are rejected", func(t *testing.T) {
+		tournament := poker.NewTournament(&SpyGameRecorder{}, &SpyBlindAlerter{}, options)
+
+		assertErrorIs(t, tournament.Eliminate("Kiro"), poker.ErrTournamentNotStarted)
+
+		tournament.Start(2, ioutil.Discard)
+
+		assertErrorIs(t, tournament.Rebuy("Kiro"), poker.ErrPlayerNotOut)
+		poker.AssertNoError(t, tournament.Eliminate("Kiro"))
+		assertErrorIs(t, tournament.Eliminate("Kiro"), poker.ErrPlayerAlreadyOut)
+		assertErrorIs(t, tournament.Eliminate("Joro"), poker.ErrTooManyEliminations)
+	})
+
+	t.Run("New options apply to the next tournament", func(t *testing.T) {
+		store := &SpyGameRecorder{}
+		out := &bytes.Buffer{}
+		tournament := poker.NewTournament(store, &SpyBlindAlerter{}, options)
+
+		tournament.Start(2, out)
+		tournament.SetOptions(poker.TournamentOptions{BuyIn: 50})
+		tournament.Win("Chris")
+
+		tournament.Start(2, out)
+		tournament.Win("Cleo")
+
+		if !strings.Contains(out.String(), "1. Chris wins 20\n") || !strings.Contains(out.String(), "1. Cleo wins 100\n") {
+			t.Errorf("Expected the running tournament to keep its buy-in but got %q", out.String())
+		}
+	})
+
+	t.Run("Stores without game results only get the winner", func(t *testing.T) {
+		store := &poker.StubPlayerStore{}
+		tournament := poker.NewTournament(store, &SpyBlindAlerter{}, options)
+
+		tournament.Start(3, ioutil.Discard)
+		tournament.Win("Chris")
+
+		poker.AssertUpdateWin(t, *store, "Chris")
+	})
+}
+
+func TestHandleGameMessage(t *testing.T) {
+	t.Run("Eliminations are not accepted by games that are not tournaments", func(t *testing.T) {
+		game := &poker.SpyGame{}
+		out := &bytes.Buffer{}
+
+		finished := poker.HandleGameMessage(game, "Kiro is out", out)
+
+		poker.AssertFalse(t, finished)
+		poker.AssertFalse(t, game.WinCalled())
+		poker.AssertResponseBody(t, out.String(), poker.NotATournament+"\n")
+	})
+
+	t.Run("A winner finishes the game", func(t *testing.T) {
+		game := &poker.SpyGame{}
+
+		if !poker.HandleGameMessage(game, "Chris wins", ioutil.Discard) {
+			t.Errorf("Expected the game to finish")
+		}
+
+		poker.AssertGameWinCalled(t, game, "Chris")
+	})
+}
+
+func assertErrorIs(t *testing.T, got, want error) {
+	t.Helper()
+
+	if !errors.Is(got, want) {
+		t.Errorf("got error %v want %v", got, want)
+	}
+}
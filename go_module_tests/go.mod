@@ -1,8 +1,9 @@
 module learning
 
-go 1.14
+go 1.16
 
 require (
+	github.com/fsnotify/fsnotify v1.4.7
 	github.com/gogo/protobuf v1.3.1
 	github.com/gorilla/websocket v1.4.2
 	github.com/pkg/errors v0.9.1